@@ -10,6 +10,51 @@ import (
 type ProviderConfigSpec struct {
 	// Credentials required to authenticate to this provider.
 	Credentials ProviderCredentials `json:"credentials"`
+
+	// AccountID is the Cloudflare account of managed resources that use this
+	// ProviderConfig and neither set nor reference an account or a zone.
+	// +optional
+	AccountID string `json:"accountId,omitempty"`
+
+	// PlanOnly computes the Terraform plan of every managed resource that
+	// uses this ProviderConfig but never applies it. Pending changes are
+	// reported through the PendingChanges condition and an event instead.
+	// +optional
+	PlanOnly bool `json:"planOnly,omitempty"`
+
+	// Paused stops the provider from observing and applying every managed
+	// resource that uses this ProviderConfig by adding the
+	// crossplane.io/paused annotation to each of them, which is removed again
+	// when the field is unset. The Paused condition of the ProviderConfig
+	// reports how many resources are held.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// ChangeWindows restrict the creation, update and deletion of managed
+	// resources that use this ProviderConfig to recurring maintenance
+	// windows. Outside of them, resources are still observed but changes are
+	// deferred and reported through the WaitingForChangeWindow condition.
+	// Changes may be applied at any time if no windows are configured. The
+	// cloudflare.upbound.io/change-windows annotation of a managed resource,
+	// holding a JSON list of windows, overrides them.
+	// +optional
+	ChangeWindows []ChangeWindow `json:"changeWindows,omitempty"`
+}
+
+// A ChangeWindow is a recurring period in which changes may be applied.
+type ChangeWindow struct {
+	// Schedule is a cron expression such as "0 22 * * 1-5" for the start of
+	// the window.
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Duration of the window, such as 4h.
+	Duration metav1.Duration `json:"duration"`
+
+	// TimeZone of the schedule as an IANA time zone name such as
+	// Europe/Berlin. Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
 // ProviderCredentials required to authenticate.
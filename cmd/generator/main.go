@@ -1,9 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/crossplane/upjet/v2/pkg/pipeline"
 
@@ -19,5 +21,49 @@ func main() {
 	if err != nil {
 		panic(fmt.Sprintf("cannot calculate the absolute path with %s", rootDir))
 	}
+	schemas, err := config.TerraformResourceSchemas()
+	if err != nil {
+		panic(err)
+	}
+	// A configured resource that is not in the schema is silently dropped
+	// from the framework include list, so its kind would vanish.
+	coverage := config.NewExternalNameCoverage(schemas)
+	if len(coverage.Unknown) > 0 {
+		panic(fmt.Sprintf("external name configurations of resources that are not in config/schema.json: %s", strings.Join(coverage.Unknown, ", ")))
+	}
+	if err := writeCoverageReport(filepath.Join(absRootDir, coverageReportPath), coverage); err != nil {
+		panic(err)
+	}
 	pipeline.Run(config.GetProvider(), config.GetProviderNamespaced(), absRootDir)
 }
+
+// coverageReportPath is where the external name coverage report is written,
+// relative to the root directory.
+const coverageReportPath = "docs/EXTERNAL_NAME_COVERAGE.md"
+
+// writeCoverageReport writes a Markdown report listing the resources of the
+// embedded schema that have no external name configuration and are therefore
+// not generated.
+func writeCoverageReport(path string, c *config.ExternalNameCoverage) error {
+	b := &bytes.Buffer{}
+	fmt.Fprintln(b, "# External name coverage")
+	fmt.Fprintln(b)
+	fmt.Fprintln(b, "<!-- Code generated by cmd/generator. DO NOT EDIT. -->")
+	fmt.Fprintln(b)
+	fmt.Fprintf(b, "%d of the %d resources in `config/schema.json` have an external name configuration in `config/external_name.go` and are generated.\n", len(c.Configured), c.Schema)
+	if len(c.Unconfigured) == 0 {
+		fmt.Fprintln(b)
+		fmt.Fprintln(b, "All resources are configured.")
+	} else {
+		fmt.Fprintln(b)
+		fmt.Fprintln(b, "## Resources without an external name configuration")
+		fmt.Fprintln(b)
+		for _, name := range c.Unconfigured {
+			fmt.Fprintf(b, "- `%s`\n", name)
+		}
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("cannot write the external name coverage report: %w", err)
+	}
+	return nil
+}
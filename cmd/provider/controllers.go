@@ -0,0 +1,146 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	xpcontroller "github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	ujconfig "github.com/crossplane/upjet/v2/pkg/config"
+	tjcontroller "github.com/crossplane/upjet/v2/pkg/controller"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	clusterv1beta1 "github.com/prolixalias/provider-cloudflare/apis/cluster/v1beta1"
+	namespacedv1beta1 "github.com/prolixalias/provider-cloudflare/apis/namespaced/v1beta1"
+)
+
+// controllerSelection selects the controllers the provider starts and the
+// poll interval each of them uses.
+type controllerSelection struct {
+	// pollIntervals overrides the poll interval of single kinds.
+	pollIntervals map[schema.GroupKind]time.Duration
+
+	// groups are the enabled API groups. All groups are enabled if it is
+	// empty.
+	groups map[string]bool
+}
+
+// newControllerSelection validates the supplied per-kind poll intervals and
+// enabled groups against the kinds the providers serve.
+func newControllerSelection(providers []*ujconfig.Provider, pollKinds map[string]string, groups []string) (controllerSelection, error) {
+	knownKinds := map[string]bool{}
+	knownGroups := map[string]bool{}
+	for _, p := range providers {
+		for _, r := range p.Resources {
+			g := apiGroup(p, r)
+			knownGroups[g] = true
+			knownKinds[r.Kind+"."+g] = true
+		}
+	}
+
+	s := controllerSelection{pollIntervals: map[schema.GroupKind]time.Duration{}, groups: map[string]bool{}}
+	var unknown []string
+	for gk, p := range pollKinds {
+		if !knownKinds[gk] {
+			unknown = append(unknown, "kind "+gk)
+			continue
+		}
+		d, err := time.ParseDuration(p)
+		if err != nil {
+			return s, errors.Wrapf(err, "invalid poll interval for %s", gk)
+		}
+		if d <= 0 {
+			return s, errors.Errorf("invalid poll interval for %s: duration %s must be positive", gk, p)
+		}
+		s.pollIntervals[schema.ParseGroupKind(gk)] = d
+	}
+	for _, g := range groups {
+		if !knownGroups[g] {
+			unknown = append(unknown, "group "+g)
+			continue
+		}
+		s.groups[g] = true
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return s, errors.Errorf("unknown %s", strings.Join(unknown, ", "))
+	}
+	return s, nil
+}
+
+func (s controllerSelection) isDefault() bool {
+	return len(s.pollIntervals) == 0 && len(s.groups) == 0
+}
+
+func (s controllerSelection) enabled(gvk schema.GroupVersionKind) bool {
+	// The ProviderConfig controllers are always needed.
+	if len(s.groups) == 0 || gvk.Group == clusterv1beta1.Group || gvk.Group == namespacedv1beta1.Group {
+		return true
+	}
+	return s.groups[gvk.Group]
+}
+
+func (s controllerSelection) pollInterval(gvk schema.GroupVersionKind, def time.Duration) time.Duration {
+	if d, ok := s.pollIntervals[gvk.GroupKind()]; ok {
+		return d
+	}
+	return def
+}
+
+// setupControllers sets up the controllers of one scope according to the
+// supplied selection. The controllers of each distinct poll interval are set
+// up with their own options, and a selectionGate makes sure every kind is
+// set up exactly once, with the right options.
+func setupControllers(mgr ctrl.Manager, o tjcontroller.Options, s controllerSelection, setup, setupGated func(ctrl.Manager, tjcontroller.Options) error) error {
+	if s.isDefault() {
+		if o.Gate != nil {
+			return setupGated(mgr, o)
+		}
+		return setup(mgr, o)
+	}
+	intervals := map[time.Duration]bool{o.PollInterval: true}
+	for _, d := range s.pollIntervals {
+		intervals[d] = true
+	}
+	for d := range intervals {
+		so := o
+		so.PollInterval = d
+		so.Gate = &selectionGate{gate: o.Gate, accept: func(gvk schema.GroupVersionKind) bool {
+			return s.enabled(gvk) && s.pollInterval(gvk, o.PollInterval) == d
+		}}
+		if err := setupGated(mgr, so); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectionGate registers only the controllers whose kinds it accepts with
+// the underlying gate. Without an underlying gate accepted controllers are
+// set up right away.
+type selectionGate struct {
+	gate   xpcontroller.Gate
+	accept func(schema.GroupVersionKind) bool
+}
+
+func (g *selectionGate) Register(callback func(), gvks ...schema.GroupVersionKind) {
+	for _, gvk := range gvks {
+		if !g.accept(gvk) {
+			return
+		}
+	}
+	if g.gate == nil {
+		callback()
+		return
+	}
+	g.gate.Register(callback, gvks...)
+}
+
+func (g *selectionGate) Set(gvk schema.GroupVersionKind, ready bool) bool {
+	if g.gate == nil {
+		return false
+	}
+	return g.gate.Set(gvk, ready)
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	ujconfig "github.com/crossplane/upjet/v2/pkg/config"
+	tfjson "github.com/hashicorp/terraform-json"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	authv1 "k8s.io/api/authorization/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1beta1 "github.com/prolixalias/provider-cloudflare/apis/cluster/v1beta1"
+	namespacedv1beta1 "github.com/prolixalias/provider-cloudflare/apis/namespaced/v1beta1"
+	"github.com/prolixalias/provider-cloudflare/config"
+	"github.com/prolixalias/provider-cloudflare/internal/clients"
+	"github.com/prolixalias/provider-cloudflare/internal/version"
+)
+
+type checkStatus string
+
+const (
+	checkPass checkStatus = "pass"
+	checkFail checkStatus = "fail"
+)
+
+// A checkResult is the outcome of a single diagnose check.
+type checkResult struct {
+	Name     string      `json:"name"`
+	Status   checkStatus `json:"status"`
+	Message  string      `json:"message,omitempty"`
+	Problems []string    `json:"problems,omitempty"`
+}
+
+// A diagnosis is the report produced by the diagnose subcommand.
+type diagnosis struct {
+	Version string        `json:"version"`
+	Checks  []checkResult `json:"checks"`
+}
+
+func (d *diagnosis) failed() bool {
+	for _, c := range d.Checks {
+		if c.Status == checkFail {
+			return true
+		}
+	}
+	return false
+}
+
+func newCheckResult(name, message string, problems []string) checkResult {
+	r := checkResult{Name: name, Status: checkPass, Message: message, Problems: problems}
+	if len(problems) > 0 {
+		r.Status = checkFail
+	}
+	return r
+}
+
+func failedCheck(name string, err error) checkResult {
+	return checkResult{Name: name, Status: checkFail, Message: err.Error()}
+}
+
+// diagnose runs all installation checks, writes the report to w in the
+// supplied format and returns the process exit code.
+func diagnose(ctx context.Context, w io.Writer, format string, selection controllerSelection) int {
+	d := &diagnosis{Version: version.Version}
+	d.Checks = append(d.Checks, checkRuntime())
+
+	schemas, err := config.TerraformResourceSchemas()
+	if err != nil {
+		d.Checks = append(d.Checks, failedCheck("schema-consistency", err), failedCheck("external-name-configs", err))
+	} else {
+		d.Checks = append(d.Checks, checkSchemaConsistency(ctx, schemas), checkExternalNameConfigs(schemas))
+	}
+
+	kinds := enabledKinds([]*ujconfig.Provider{config.GetProvider(), config.GetProviderNamespaced()}, selection)
+	kube, err := newDiagnoseClient()
+	if err != nil {
+		for _, name := range []string{"crds", "rbac", "provider-configs"} {
+			d.Checks = append(d.Checks, failedCheck(name, err))
+		}
+	} else {
+		crds := &apiextensionsv1.CustomResourceDefinitionList{}
+		if err := kube.List(ctx, crds); err != nil {
+			err = errors.Wrap(err, "cannot list CustomResourceDefinitions")
+			d.Checks = append(d.Checks, failedCheck("crds", err), failedCheck("rbac", err))
+		} else {
+			served := servedResources(crds.Items)
+			d.Checks = append(d.Checks, checkCRDs(kinds, crds.Items), checkRBAC(ctx, kube, kinds, served))
+		}
+		d.Checks = append(d.Checks, checkProviderConfigs(ctx, kube))
+	}
+
+	if err := writeDiagnosis(w, d, format); err != nil {
+		fmt.Fprintf(os.Stderr, "cannot write diagnosis report: %s\n", err)
+		return 2
+	}
+	if d.failed() {
+		return 1
+	}
+	return 0
+}
+
+func newDiagnoseClient() (client.Client, error) {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get API server rest config")
+	}
+	s := kruntime.NewScheme()
+	for _, add := range []func(*kruntime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		apiextensionsv1.AddToScheme,
+		clusterv1beta1.SchemeBuilder.AddToScheme,
+		namespacedv1beta1.SchemeBuilder.AddToScheme,
+	} {
+		if err := add(s); err != nil {
+			return nil, errors.Wrap(err, "cannot build scheme")
+		}
+	}
+	kube, err := client.New(cfg, client.Options{Scheme: s})
+	return kube, errors.Wrap(err, "cannot create Kubernetes client")
+}
+
+func writeDiagnosis(w io.Writer, d *diagnosis, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	}
+	for _, c := range d.Checks {
+		line := fmt.Sprintf("[%s] %s", strings.ToUpper(string(c.Status)), c.Name)
+		if c.Message != "" {
+			line += ": " + c.Message
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		for _, p := range c.Problems {
+			if _, err := fmt.Fprintf(w, "    - %s\n", p); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "provider-cloudflare %s: %d checks, failed: %t\n", d.Version, len(d.Checks), d.failed())
+	return err
+}
+
+// checkRuntime checks that an external Terraform provider binary, if one is
+// configured, exists and matches the architecture of this process.
+func checkRuntime() checkResult {
+	tfPath := os.Getenv("TERRAFORM_NATIVE_PROVIDER_PATH")
+	if tfPath == "" {
+		return newCheckResult("runtime", "TERRAFORM_NATIVE_PROVIDER_PATH is empty, native in-process mode expected", nil)
+	}
+	if _, err := os.Stat(tfPath); err != nil {
+		return failedCheck("runtime", err)
+	}
+	arch, err := detectELFArch(tfPath)
+	if err != nil {
+		return failedCheck("runtime", errors.Wrapf(err, "cannot detect architecture of %s", tfPath))
+	}
+	var problems []string
+	if arch != runtime.GOARCH {
+		problems = append(problems, fmt.Sprintf("terraform provider binary %s is built for %s but the provider runs on %s", tfPath, arch, runtime.GOARCH))
+	}
+	return newCheckResult("runtime", fmt.Sprintf("terraform provider binary %s (%s)", tfPath, arch), problems)
+}
+
+// checkSchemaConsistency checks that the embedded schema.json describes the
+// same resources and top-level attributes as the framework provider that is
+// compiled into the binary.
+func checkSchemaConsistency(ctx context.Context, schemas map[string]*tfjson.Schema) checkResult {
+	fw := config.GetProvider().TerraformPluginFrameworkProvider
+	if fw == nil {
+		return failedCheck("schema-consistency", errors.New("no terraform framework provider is compiled into this binary"))
+	}
+	resources := frameworkResources(ctx, fw.Resources(ctx))
+	var problems []string
+	for name := range schemas {
+		if _, ok := resources[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s is in schema.json but not in the framework provider", name))
+		}
+	}
+	for name, r := range resources {
+		s, ok := schemas[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is in the framework provider but not in schema.json", name))
+			continue
+		}
+		problems = append(problems, compareAttributes(ctx, name, s, r)...)
+	}
+	sort.Strings(problems)
+	return newCheckResult("schema-consistency", fmt.Sprintf("%d resources in schema.json, %d in the framework provider", len(schemas), len(resources)), problems)
+}
+
+func frameworkResources(ctx context.Context, fns []func() fwresource.Resource) map[string]fwresource.Resource {
+	resources := make(map[string]fwresource.Resource, len(fns))
+	for _, fn := range fns {
+		r := fn()
+		md := &fwresource.MetadataResponse{}
+		r.Metadata(ctx, fwresource.MetadataRequest{ProviderTypeName: "cloudflare"}, md)
+		resources[md.TypeName] = r
+	}
+	return resources
+}
+
+func compareAttributes(ctx context.Context, name string, s *tfjson.Schema, r fwresource.Resource) []string {
+	if s.Block == nil {
+		return []string{fmt.Sprintf("%s has no schema in schema.json", name)}
+	}
+	sch := &fwresource.SchemaResponse{}
+	r.Schema(ctx, fwresource.SchemaRequest{}, sch)
+	if sch.Diagnostics.HasError() {
+		return []string{fmt.Sprintf("%s: cannot get framework schema", name)}
+	}
+	fw := map[string]bool{}
+	for a := range sch.Schema.GetAttributes() {
+		fw[a] = true
+	}
+	for b := range sch.Schema.GetBlocks() {
+		fw[b] = true
+	}
+	embedded := map[string]bool{}
+	for a := range s.Block.Attributes {
+		embedded[a] = true
+	}
+	for b := range s.Block.NestedBlocks {
+		embedded[b] = true
+	}
+	var problems []string
+	for a := range embedded {
+		if !fw[a] {
+			problems = append(problems, fmt.Sprintf("%s.%s is in schema.json but not in the framework provider", name, a))
+		}
+	}
+	for a := range fw {
+		if !embedded[a] {
+			problems = append(problems, fmt.Sprintf("%s.%s is in the framework provider but not in schema.json", name, a))
+		}
+	}
+	return problems
+}
+
+// checkExternalNameConfigs checks that every resource with an external name
+// configuration exists in the embedded schema.
+func checkExternalNameConfigs(schemas map[string]*tfjson.Schema) checkResult {
+	c := config.NewExternalNameCoverage(schemas)
+	var problems []string
+	for _, name := range c.Unknown {
+		problems = append(problems, fmt.Sprintf("%s is configured but not in schema.json", name))
+	}
+	return newCheckResult("external-name-configs", fmt.Sprintf("%d of %d resources in schema.json configured", len(c.Configured), c.Schema), problems)
+}
+
+// apiGroup returns the API group of the supplied resource.
+func apiGroup(p *ujconfig.Provider, r *ujconfig.Resource) string {
+	if r.ShortGroup == "" {
+		return p.RootGroup
+	}
+	return r.ShortGroup + "." + p.RootGroup
+}
+
+// enabledKinds returns the kinds the provider reconciles with the supplied
+// controller selection, including those of the ProviderConfig controllers.
+func enabledKinds(providers []*ujconfig.Provider, selection controllerSelection) []schema.GroupVersionKind {
+	kinds := []schema.GroupVersionKind{
+		clusterv1beta1.ProviderConfigGroupVersionKind, clusterv1beta1.ProviderConfigUsageGroupVersionKind,
+		namespacedv1beta1.ProviderConfigGroupVersionKind, namespacedv1beta1.ProviderConfigUsageGroupVersionKind,
+	}
+	for _, p := range providers {
+		for _, r := range p.Resources {
+			gvk := schema.GroupVersionKind{Group: apiGroup(p, r), Version: r.Version, Kind: r.Kind}
+			if selection.enabled(gvk) {
+				kinds = append(kinds, gvk)
+			}
+		}
+	}
+	return kinds
+}
+
+// servedResources returns the resource, that is the plural name, of every
+// kind and version the supplied CRDs serve.
+func servedResources(crds []apiextensionsv1.CustomResourceDefinition) map[schema.GroupVersionKind]string {
+	served := map[schema.GroupVersionKind]string{}
+	for _, crd := range crds {
+		for _, v := range crd.Spec.Versions {
+			if v.Served {
+				served[schema.GroupVersionKind{Group: crd.Spec.Group, Version: v.Name, Kind: crd.Spec.Names.Kind}] = crd.Spec.Names.Plural
+			}
+		}
+	}
+	return served
+}
+
+// checkCRDs checks that an installed CRD serves every kind the provider
+// reconciles.
+func checkCRDs(kinds []schema.GroupVersionKind, crds []apiextensionsv1.CustomResourceDefinition) checkResult {
+	installed := map[schema.GroupKind]bool{}
+	for _, crd := range crds {
+		installed[schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}] = true
+	}
+	served := servedResources(crds)
+	var problems []string
+	for _, gvk := range kinds {
+		switch {
+		case !installed[gvk.GroupKind()]:
+			problems = append(problems, fmt.Sprintf("no CRD is installed for %s", gvk.GroupKind()))
+		case served[gvk] == "":
+			problems = append(problems, fmt.Sprintf("the CRD of %s does not serve version %s", gvk.GroupKind(), gvk.Version))
+		}
+	}
+	sort.Strings(problems)
+	return newCheckResult("crds", fmt.Sprintf("%d kinds enabled", len(kinds)), problems)
+}
+
+// checkRBAC checks that the provider is allowed to perform the operations its
+// controllers rely on. Access to the kinds it reconciles is checked for the
+// resources their CRDs serve; kinds without one are reported by checkCRDs.
+func checkRBAC(ctx context.Context, kube client.Client, kinds []schema.GroupVersionKind, served map[schema.GroupVersionKind]string) checkResult {
+	attrs := append([]authv1.ResourceAttributes{}, crdWatchAccess...)
+	attrs = append(attrs,
+		authv1.ResourceAttributes{Resource: "secrets", Verb: "get"},
+		// Workers scripts and KV values may read ConfigMaps.
+		authv1.ResourceAttributes{Resource: "configmaps", Verb: "get"},
+		authv1.ResourceAttributes{Resource: "events", Verb: "create"},
+		authv1.ResourceAttributes{Group: "coordination.k8s.io", Resource: "leases", Verb: "update"},
+	)
+	for _, gvk := range kinds {
+		r, ok := served[gvk]
+		if !ok {
+			continue
+		}
+		for _, verb := range []string{"get", "list", "watch", "update", "patch"} {
+			attrs = append(attrs, authv1.ResourceAttributes{Group: gvk.Group, Version: gvk.Version, Resource: r, Verb: verb})
+		}
+		attrs = append(attrs, authv1.ResourceAttributes{Group: gvk.Group, Version: gvk.Version, Resource: r, Subresource: "status", Verb: "update"})
+	}
+	var problems []string
+	for _, a := range attrs {
+		ok, err := canAccess(ctx, kube, a)
+		if err != nil {
+			return failedCheck("rbac", err)
+		}
+		if !ok {
+			problems = append(problems, fmt.Sprintf("not allowed to %s %s", a.Verb, accessResource(a)))
+		}
+	}
+	sort.Strings(problems)
+	return newCheckResult("rbac", fmt.Sprintf("%d permissions checked", len(attrs)), problems)
+}
+
+// checkProviderConfigs checks that the credentials of every ProviderConfig
+// can be extracted and have a shape the provider supports.
+func checkProviderConfigs(ctx context.Context, kube client.Client) checkResult {
+	type pc struct {
+		name string
+		spec namespacedv1beta1.ProviderConfigSpec
+	}
+	var pcs []pc
+	cpcs := &clusterv1beta1.ProviderConfigList{}
+	if err := kube.List(ctx, cpcs); err != nil {
+		return failedCheck("provider-configs", errors.Wrap(err, "cannot list cluster ProviderConfigs"))
+	}
+	for _, c := range cpcs.Items {
+		data, err := json.Marshal(c.Spec)
+		if err != nil {
+			return failedCheck("provider-configs", err)
+		}
+		var spec namespacedv1beta1.ProviderConfigSpec
+		if err := json.Unmarshal(data, &spec); err != nil {
+			return failedCheck("provider-configs", err)
+		}
+		pcs = append(pcs, pc{name: c.Name, spec: spec})
+	}
+	npcs := &namespacedv1beta1.ProviderConfigList{}
+	if err := kube.List(ctx, npcs); err != nil {
+		return failedCheck("provider-configs", errors.Wrap(err, "cannot list namespaced ProviderConfigs"))
+	}
+	for _, n := range npcs.Items {
+		spec := *n.Spec.DeepCopy()
+		if spec.Credentials.SecretRef != nil {
+			spec.Credentials.SecretRef.Namespace = n.Namespace
+		}
+		pcs = append(pcs, pc{name: n.Namespace + "/" + n.Name, spec: spec})
+	}
+
+	var problems []string
+	for _, p := range pcs {
+		if p.spec.Credentials.Source == xpv1.CredentialsSourceNone {
+			continue
+		}
+		data, err := resource.CommonCredentialExtractor(ctx, p.spec.Credentials.Source, kube, p.spec.Credentials.CommonCredentialSelectors)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: cannot extract credentials: %s", p.name, err))
+			continue
+		}
+		if err := clients.ValidateCredentials(data); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", p.name, err))
+		}
+	}
+	return newCheckResult("provider-configs", fmt.Sprintf("%d ProviderConfigs checked", len(pcs)), problems)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	ujconfig "github.com/crossplane/upjet/v2/pkg/config"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	authv1 "k8s.io/api/authorization/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	clusterv1beta1 "github.com/prolixalias/provider-cloudflare/apis/cluster/v1beta1"
+	namespacedv1beta1 "github.com/prolixalias/provider-cloudflare/apis/namespaced/v1beta1"
+)
+
+var (
+	recordGVK = schema.GroupVersionKind{Group: "dns.cloudflare.upbound.io", Version: "v1alpha1", Kind: "Record"}
+	zoneGVK   = schema.GroupVersionKind{Group: "zone.cloudflare.upbound.io", Version: "v1alpha1", Kind: "Zone"}
+)
+
+func testProviders() []*ujconfig.Provider {
+	return []*ujconfig.Provider{{
+		RootGroup: "cloudflare.upbound.io",
+		Resources: map[string]*ujconfig.Resource{
+			"cloudflare_dns_record": {Kind: "Record", ShortGroup: "dns", Version: "v1alpha1"},
+			"cloudflare_zone":       {Kind: "Zone", ShortGroup: "zone", Version: "v1alpha1"},
+		},
+	}}
+}
+
+func crd(gvk schema.GroupVersionKind, plural string, served bool) apiextensionsv1.CustomResourceDefinition {
+	return apiextensionsv1.CustomResourceDefinition{Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+		Group:    gvk.Group,
+		Names:    apiextensionsv1.CustomResourceDefinitionNames{Kind: gvk.Kind, Plural: plural},
+		Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{Name: gvk.Version, Served: served}},
+	}}
+}
+
+func TestEnabledKinds(t *testing.T) {
+	providerConfigs := []schema.GroupVersionKind{
+		clusterv1beta1.ProviderConfigGroupVersionKind, clusterv1beta1.ProviderConfigUsageGroupVersionKind,
+		namespacedv1beta1.ProviderConfigGroupVersionKind, namespacedv1beta1.ProviderConfigUsageGroupVersionKind,
+	}
+	cases := map[string]struct {
+		groups []string
+		want   []schema.GroupVersionKind
+	}{
+		"AllGroups": {
+			want: append(append([]schema.GroupVersionKind{}, providerConfigs...), recordGVK, zoneGVK),
+		},
+		"EnabledGroups": {
+			groups: []string{"dns.cloudflare.upbound.io"},
+			want:   append(append([]schema.GroupVersionKind{}, providerConfigs...), recordGVK),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s, err := newControllerSelection(testProviders(), nil, tc.groups)
+			if err != nil {
+				t.Fatalf("newControllerSelection(...): unexpected error: %v", err)
+			}
+			got := enabledKinds(testProviders(), s)
+			sortGVKs := cmpopts.SortSlices(func(a, b schema.GroupVersionKind) bool { return a.String() < b.String() })
+			if diff := cmp.Diff(tc.want, got, sortGVKs); diff != "" {
+				t.Errorf("enabledKinds(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckCRDs(t *testing.T) {
+	cases := map[string]struct {
+		crds []apiextensionsv1.CustomResourceDefinition
+		want []string
+	}{
+		"Served": {
+			crds: []apiextensionsv1.CustomResourceDefinition{crd(recordGVK, "records", true), crd(zoneGVK, "zones", true)},
+		},
+		"Missing": {
+			crds: []apiextensionsv1.CustomResourceDefinition{crd(recordGVK, "records", true)},
+			want: []string{"no CRD is installed for Zone.zone.cloudflare.upbound.io"},
+		},
+		"NotServed": {
+			crds: []apiextensionsv1.CustomResourceDefinition{crd(recordGVK, "records", false), crd(zoneGVK, "zones", true)},
+			want: []string{"the CRD of Record.dns.cloudflare.upbound.io does not serve version v1alpha1"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := checkCRDs([]schema.GroupVersionKind{recordGVK, zoneGVK}, tc.crds)
+			if diff := cmp.Diff(tc.want, got.Problems); diff != "" {
+				t.Errorf("checkCRDs(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckRBAC(t *testing.T) {
+	// The service account may do anything but update the status of records.
+	kube := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(_ context.Context, _ client.WithWatch, obj client.Object, _ ...client.CreateOption) error {
+			sar := obj.(*authv1.SelfSubjectAccessReview)
+			a := sar.Spec.ResourceAttributes
+			sar.Status.Allowed = a.Resource != "records" || a.Subresource != "status"
+			return nil
+		},
+	}).Build()
+	kinds := []schema.GroupVersionKind{recordGVK, zoneGVK}
+	// The CRD of zones is not installed, which checkCRDs reports.
+	served := map[schema.GroupVersionKind]string{recordGVK: "records"}
+
+	got := checkRBAC(context.Background(), kube, kinds, served)
+	want := []string{"not allowed to update records/status.dns.cloudflare.upbound.io"}
+	if diff := cmp.Diff(want, got.Problems); diff != "" {
+		t.Errorf("checkRBAC(...): -want, +got:\n%s", diff)
+	}
+	// The access of the CRD watch, secrets, configmaps, events and leases,
+	// and six to records.
+	if want := "13 permissions checked"; got.Message != want {
+		t.Errorf("checkRBAC(...): want message %q, got %q", want, got.Message)
+	}
+}
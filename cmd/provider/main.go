@@ -12,6 +12,7 @@ import (
 	changelogsv1alpha1 "github.com/crossplane/crossplane-runtime/v2/apis/changelogs/proto/v1alpha1"
 	xpcontroller "github.com/crossplane/crossplane-runtime/v2/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/feature"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/gate"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
@@ -19,6 +20,7 @@ import (
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/customresourcesgate"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/statemetrics"
+	ujconfig "github.com/crossplane/upjet/v2/pkg/config"
 	tjcontroller "github.com/crossplane/upjet/v2/pkg/controller"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -28,6 +30,7 @@ import (
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
@@ -41,7 +44,9 @@ import (
 	controllerCluster "github.com/prolixalias/provider-cloudflare/internal/controller/cluster"
 	controllerNamespaced "github.com/prolixalias/provider-cloudflare/internal/controller/namespaced"
 	"github.com/prolixalias/provider-cloudflare/internal/features"
+	"github.com/prolixalias/provider-cloudflare/internal/hooks"
 	"github.com/prolixalias/provider-cloudflare/internal/version"
+	"github.com/prolixalias/provider-cloudflare/internal/webhooks"
 )
 
 const (
@@ -51,40 +56,106 @@ const (
 	tlsServerCertDir        = "/tls/server"
 )
 
-func main() {
-	var (
-		app                     = kingpin.New(filepath.Base(os.Args[0]), "Crossplane provider for Cloudflare").DefaultEnvars()
-		debug                   = app.Flag("debug", "Run with debug logging.").Short('d').Bool()
-		syncPeriod              = app.Flag("sync", "Controller manager sync period such as 300ms, 1.5h, or 2h45m").Short('s').Default("1h").Duration()
-		pollInterval            = app.Flag("poll", "Poll interval controls how often an individual resource should be checked for drift.").Default("10m").Duration()
-		pollStateMetricInterval = app.Flag("poll-state-metric", "State metric recording interval").Default("5s").Duration()
-		leaderElection          = app.Flag("leader-election", "Use leader election for the controller manager.").Short('l').Default("false").OverrideDefaultFromEnvar("LEADER_ELECTION").Bool()
-		maxReconcileRate        = app.Flag("max-reconcile-rate", "The global maximum rate per second at which resources may be checked for drift from the desired state.").Default("10").Int()
-
-		webhookPort          = app.Flag("webhook-port", "The port the webhook listens on").Default("9443").Envar("WEBHOOK_PORT").Int()
-		metricsBindAddress   = app.Flag("metrics-bind-address", "The address the metrics server listens on").Default(":8080").Envar("METRICS_BIND_ADDRESS").String()
-		changelogsSocketPath = app.Flag("changelogs-socket-path", "Path for changelogs socket (if enabled)").Default("/var/run/changelogs/changelogs.sock").Envar("CHANGELOGS_SOCKET_PATH").String()
-
-		enableManagementPolicies = app.Flag("enable-management-policies", "Enable support for Management Policies.").Default("true").Envar("ENABLE_MANAGEMENT_POLICIES").Bool()
-		enableChangeLogs         = app.Flag("enable-changelogs", "Enable support for capturing change logs during reconciliation.").Default("false").Envar("ENABLE_CHANGE_LOGS").Bool()
-
-		certsDirSet = false
-		certsDir    = app.Flag("certs-dir", "The directory that contains the server key and certificate.").Default(tlsServerCertDir).Envar(certsDirEnvVar).PreAction(func(_ *kingpin.ParseContext) error {
-			certsDirSet = true
-			return nil
-		}).String()
-	)
+// providerFlags are the command line flags of the provider.
+type providerFlags struct {
+	debug                   *bool
+	syncPeriod              *time.Duration
+	pollInterval            *time.Duration
+	pollStateMetricInterval *time.Duration
+	leaderElection          *bool
+	maxReconcileRate        *int
+
+	webhookPort          *int
+	metricsBindAddress   *string
+	changelogsSocketPath *string
+
+	enableManagementPolicies *bool
+	enableChangeLogs         *bool
+	planOnly                 *bool
+
+	pollKinds     *map[string]string
+	enabledGroups *[]string
+
+	ociRegistries         *[]string
+	insecureOCIRegistries *[]string
+
+	certsDir *string
+	// certsDirSet reports whether the certificate directory was supplied.
+	certsDirSet bool
+
+	diagnose       *kingpin.CmdClause
+	diagnoseOutput *string
+}
+
+// newApp returns the command line application of the provider, named name,
+// and its flags. The configuration file named by the supplied arguments or
+// the environment supplies the defaults of the flags, so it is loaded before
+// the arguments are parsed.
+func newApp(name string, args []string) (*kingpin.Application, *providerFlags, error) {
+	app := kingpin.New(name, "Crossplane provider for Cloudflare").DefaultEnvars()
+	f := &providerFlags{
+		debug:                   app.Flag("debug", "Run with debug logging.").Short('d').Bool(),
+		syncPeriod:              app.Flag("sync", "Controller manager sync period such as 300ms, 1.5h, or 2h45m").Short('s').Default("1h").Duration(),
+		pollInterval:            app.Flag("poll", "Poll interval controls how often an individual resource should be checked for drift.").Default("10m").Duration(),
+		pollStateMetricInterval: app.Flag("poll-state-metric", "State metric recording interval").Default("5s").Duration(),
+		leaderElection:          app.Flag("leader-election", "Use leader election for the controller manager.").Short('l').Default("false").OverrideDefaultFromEnvar("LEADER_ELECTION").Bool(),
+		maxReconcileRate:        app.Flag("max-reconcile-rate", "The global maximum rate per second at which resources may be checked for drift from the desired state.").Default("10").Int(),
 
-	kingpin.MustParse(app.Parse(os.Args[1:]))
+		webhookPort:          app.Flag("webhook-port", "The port the webhook listens on").Default("9443").Envar("WEBHOOK_PORT").Int(),
+		metricsBindAddress:   app.Flag("metrics-bind-address", "The address the metrics server listens on").Default(":8080").Envar("METRICS_BIND_ADDRESS").String(),
+		changelogsSocketPath: app.Flag("changelogs-socket-path", "Path for changelogs socket (if enabled)").Default("/var/run/changelogs/changelogs.sock").Envar("CHANGELOGS_SOCKET_PATH").String(),
 
-	zl := zap.New(zap.UseDevMode(*debug))
+		enableManagementPolicies: app.Flag("enable-management-policies", "Enable support for Management Policies.").Default("true").Envar("ENABLE_MANAGEMENT_POLICIES").Bool(),
+		enableChangeLogs:         app.Flag("enable-changelogs", "Enable support for capturing change logs during reconciliation.").Default("false").Envar("ENABLE_CHANGE_LOGS").Bool(),
+		planOnly:                 app.Flag("plan-only", "Compute and report the changes for every managed resource without applying them.").Default("false").Envar("PLAN_ONLY").Bool(),
+
+		pollKinds:     app.Flag("poll-kind", "Poll interval of a single kind such as Record.dns.cloudflare.upbound.io=1h, overriding --poll. May be repeated.").StringMap(),
+		enabledGroups: app.Flag("enabled-group", "API group whose controllers are started such as dns.cloudflare.upbound.io. May be repeated, all groups are started if omitted.").Strings(),
+
+		ociRegistries:         app.Flag("oci-registry", "OCI registry such as ghcr.io that the contentFrom sources of Workers resources may pull from. May be repeated, no registry is allowed if omitted.").Strings(),
+		insecureOCIRegistries: app.Flag("insecure-oci-registry", "OCI registry such as registry.workers.svc:5000 that the contentFrom sources of Workers resources may pull from, also over plain HTTP. May be repeated.").Strings(),
+	}
+	_ = app.Flag(configFileFlag, "Path to a YAML or JSON provider configuration file. Flags and environment variables take precedence over its settings.").Envar(configFileEnvVar).String()
+	f.certsDir = app.Flag("certs-dir", "The directory that contains the server key and certificate.").Default(tlsServerCertDir).Envar(certsDirEnvVar).PreAction(func(_ *kingpin.ParseContext) error {
+		f.certsDirSet = true
+		return nil
+	}).String()
+
+	if path := configFilePath(args); path != "" {
+		file, err := loadOptionsFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		for name, values := range file.flagDefaults() {
+			app.GetFlag(name).Default(values...)
+		}
+	}
+
+	app.Command("start", "Start the provider controllers.").Default()
+	f.diagnose = app.Command("diagnose", "Check the provider installation and report any problems found.")
+	f.diagnoseOutput = f.diagnose.Flag("output", "Format of the diagnosis report.").Short('o').Default("text").Enum("text", "json")
+	return app, f, nil
+}
+
+func main() {
+	app, f, err := newApp(filepath.Base(os.Args[0]), os.Args[1:])
+	kingpin.FatalIfError(err, "Cannot load configuration file")
+	cmd := kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	zl := zap.New(zap.UseDevMode(*f.debug))
 	log := logging.NewLogrLogger(zl.WithName("provider-cloudflare"))
 	// Always set controller-runtime logger so reconciliation/runtime errors are
 	// surfaced in pod logs (instead of only a one-time "log.SetLogger was never
 	// called" stack trace). We still keep debug mode opt-in for more verbosity.
 	ctrl.SetLogger(zl)
 
-	log.Debug("Starting", "sync-period", syncPeriod.String(), "poll-interval", pollInterval.String(), "max-reconcile-rate", *maxReconcileRate)
+	selection, err := newControllerSelection([]*ujconfig.Provider{config.GetProvider(), config.GetProviderNamespaced()}, *f.pollKinds, *f.enabledGroups)
+	kingpin.FatalIfError(err, "Invalid controller selection")
+	if cmd == f.diagnose.FullCommand() {
+		os.Exit(diagnose(context.Background(), os.Stdout, *f.diagnoseOutput, selection))
+	}
+
+	log.Debug("Starting", "sync-period", f.syncPeriod.String(), "poll-interval", f.pollInterval.String(), "max-reconcile-rate", *f.maxReconcileRate)
 	logProviderRuntimeDiagnostics(log)
 
 	cfg, err := ctrl.GetConfig()
@@ -96,7 +167,7 @@ func main() {
 	// we use TLS_SERVER_CERTS_DIR. If an explicit certs dir is not supplied
 	// via the command-line options, then these environment variables are used
 	// instead.
-	if !certsDirSet {
+	if !f.certsDirSet {
 		// backwards-compatibility concerns
 		xpCertsDir := os.Getenv(certsDirEnvVar)
 		if xpCertsDir == "" {
@@ -108,23 +179,23 @@ func main() {
 		// we probably don't need this condition but just to be on the
 		// safe side, if we are missing any kingpin machinery details...
 		if xpCertsDir != "" {
-			*certsDir = xpCertsDir
+			*f.certsDir = xpCertsDir
 		}
 	}
 
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
-		LeaderElection:   *leaderElection,
+		LeaderElection:   *f.leaderElection,
 		LeaderElectionID: "crossplane-leader-election-provider-cloudflare",
 		Cache: cache.Options{
-			SyncPeriod: syncPeriod,
+			SyncPeriod: f.syncPeriod,
 		},
 		Metrics: metricsserver.Options{
-			BindAddress: *metricsBindAddress,
+			BindAddress: *f.metricsBindAddress,
 		},
 		WebhookServer: webhook.NewServer(
 			webhook.Options{
-				CertDir: *certsDir,
-				Port:    *webhookPort,
+				CertDir: *f.certsDir,
+				Port:    *f.webhookPort,
 			}),
 		LeaderElectionResourceLock: resourcelock.LeasesResourceLock,
 		LeaseDuration:              func() *time.Duration { d := 60 * time.Second; return &d }(),
@@ -142,57 +213,71 @@ func main() {
 	metrics.Registry.MustRegister(metricRecorder)
 	metrics.Registry.MustRegister(stateMetrics)
 
+	if *f.planOnly {
+		log.Info("Plan-only mode enabled, changes to external resources will be reported but not applied")
+	}
+	// The controllers of both scopes share the store of the Terraform state
+	// they keep in memory, so that the setup can clear it.
+	trackers := tjcontroller.NewOperationStore(log)
+	hooks.Register(clients.Hooks())
+	setupFn := clients.TerraformSetupBuilder(
+		clients.WithPlanOnly(*f.planOnly),
+		clients.WithEventRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor("provider-cloudflare"))),
+		clients.WithOperationTrackerStore(trackers),
+		clients.WithOCIRegistries(*f.ociRegistries, *f.insecureOCIRegistries),
+	)
+
 	clusterOpts := tjcontroller.Options{
 		Options: xpcontroller.Options{
 			Logger:                  log,
-			GlobalRateLimiter:       ratelimiter.NewGlobal(*maxReconcileRate),
-			PollInterval:            *pollInterval,
-			MaxConcurrentReconciles: *maxReconcileRate,
+			GlobalRateLimiter:       ratelimiter.NewGlobal(*f.maxReconcileRate),
+			PollInterval:            *f.pollInterval,
+			MaxConcurrentReconciles: *f.maxReconcileRate,
 			Features:                &feature.Flags{},
 			MetricOptions: &xpcontroller.MetricOptions{
-				PollStateMetricInterval: *pollStateMetricInterval,
+				PollStateMetricInterval: *f.pollStateMetricInterval,
 				MRMetrics:               metricRecorder,
 				MRStateMetrics:          stateMetrics,
 			},
 		},
 		Provider:              config.GetProvider(),
-		OperationTrackerStore: tjcontroller.NewOperationStore(log),
-		SetupFn:               clients.TerraformSetupBuilder(),
-		StartWebhooks:         *certsDir != "",
+		OperationTrackerStore: trackers,
+		SetupFn:               setupFn,
+		StartWebhooks:         *f.certsDir != "",
 	}
 
 	namespacedOpts := tjcontroller.Options{
 		Options: xpcontroller.Options{
 			Logger:                  log,
-			GlobalRateLimiter:       ratelimiter.NewGlobal(*maxReconcileRate),
-			PollInterval:            *pollInterval,
-			MaxConcurrentReconciles: *maxReconcileRate,
+			GlobalRateLimiter:       ratelimiter.NewGlobal(*f.maxReconcileRate),
+			PollInterval:            *f.pollInterval,
+			MaxConcurrentReconciles: *f.maxReconcileRate,
 			Features:                &feature.Flags{},
 			MetricOptions: &xpcontroller.MetricOptions{
-				PollStateMetricInterval: *pollStateMetricInterval,
+				PollStateMetricInterval: *f.pollStateMetricInterval,
 				MRMetrics:               metricRecorder,
 				MRStateMetrics:          stateMetrics,
 			},
 		},
 		Provider:              config.GetProviderNamespaced(),
-		OperationTrackerStore: tjcontroller.NewOperationStore(log),
-		SetupFn:               clients.TerraformSetupBuilder(),
-		StartWebhooks:         *certsDir != "",
+		OperationTrackerStore: trackers,
+		SetupFn:               setupFn,
+		StartWebhooks:         *f.certsDir != "",
 	}
 
-	if *enableManagementPolicies {
+	if *f.enableManagementPolicies {
 		clusterOpts.Features.Enable(features.EnableBetaManagementPolicies)
 		namespacedOpts.Features.Enable(features.EnableBetaManagementPolicies)
 		log.Info("Beta feature enabled", "flag", features.EnableBetaManagementPolicies)
 	}
 
-	if *enableChangeLogs {
+	if *f.enableChangeLogs {
 		clusterOpts.Features.Enable(feature.EnableAlphaChangeLogs)
 		namespacedOpts.Features.Enable(feature.EnableAlphaChangeLogs)
 		log.Info("Alpha feature enabled", "flag", feature.EnableAlphaChangeLogs)
 
-		conn, err := grpc.NewClient("unix://"+*changelogsSocketPath, grpc.WithTransportCredentials(insecure.NewCredentials()))
-		kingpin.FatalIfError(err, "failed to create change logs client connection at %s", *changelogsSocketPath)
+		conn, err := grpc.NewClient("unix://"+*f.changelogsSocketPath, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		kingpin.FatalIfError(err, "failed to create change logs client connection at %s", *f.changelogsSocketPath)
 
 		clo := xpcontroller.ChangeLogOptions{
 			ChangeLogger: managed.NewGRPCChangeLogger(
@@ -214,12 +299,14 @@ func main() {
 			Gate:                    crdGate,
 			MaxConcurrentReconciles: 1,
 		}), "Cannot setup CRD gate")
-		kingpin.FatalIfError(controllerCluster.SetupGated(mgr, clusterOpts), "Cannot setup cluster-scoped Template controllers")
-		kingpin.FatalIfError(controllerNamespaced.SetupGated(mgr, namespacedOpts), "Cannot setup namespaced Template controllers")
 	} else {
 		log.Info("Provider has missing RBAC permissions for watching CRDs, controller SafeStart capability will be disabled")
-		kingpin.FatalIfError(controllerCluster.Setup(mgr, clusterOpts), "Cannot setup cluster-scoped Template controllers")
-		kingpin.FatalIfError(controllerNamespaced.Setup(mgr, namespacedOpts), "Cannot setup namespaced Template controllers")
+	}
+	kingpin.FatalIfError(setupControllers(mgr, clusterOpts, selection, controllerCluster.Setup, controllerCluster.SetupGated), "Cannot setup cluster-scoped Template controllers")
+	kingpin.FatalIfError(setupControllers(mgr, namespacedOpts, selection, controllerNamespaced.Setup, controllerNamespaced.SetupGated), "Cannot setup namespaced Template controllers")
+
+	if *f.certsDir != "" {
+		kingpin.FatalIfError(webhooks.Setup(mgr), "Cannot setup admission webhooks")
 	}
 
 	kingpin.FatalIfError(mgr.Start(ctrl.SetupSignalHandler()), "Cannot start controller manager")
@@ -229,19 +316,28 @@ func canWatchCRD(ctx context.Context, mgr manager.Manager) (bool, error) {
 	if err := authv1.AddToScheme(mgr.GetScheme()); err != nil {
 		return false, err
 	}
-	verbs := []string{"get", "list", "watch"}
-	for _, verb := range verbs {
+	return canAccess(ctx, mgr.GetClient(), crdWatchAccess...)
+}
+
+// crdWatchAccess is the access the provider needs to gate its controllers on
+// the availability of their CRDs.
+var crdWatchAccess = []authv1.ResourceAttributes{
+	{Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions", Verb: "get"},
+	{Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions", Verb: "list"},
+	{Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions", Verb: "watch"},
+}
+
+// canAccess reports whether the provider is allowed to perform all of the
+// supplied operations, according to SelfSubjectAccessReviews.
+func canAccess(ctx context.Context, kube client.Client, attrs ...authv1.ResourceAttributes) (bool, error) {
+	for _, a := range attrs {
 		sar := &authv1.SelfSubjectAccessReview{
 			Spec: authv1.SelfSubjectAccessReviewSpec{
-				ResourceAttributes: &authv1.ResourceAttributes{
-					Group:    "apiextensions.k8s.io",
-					Resource: "customresourcedefinitions",
-					Verb:     verb,
-				},
+				ResourceAttributes: &a,
 			},
 		}
-		if err := mgr.GetClient().Create(ctx, sar); err != nil {
-			return false, errors.Wrapf(err, "unable to perform RBAC check for verb %s on CustomResourceDefinitions", verbs)
+		if err := kube.Create(ctx, sar); err != nil {
+			return false, errors.Wrapf(err, "unable to perform RBAC check for verb %s on %s", a.Verb, accessResource(a))
 		}
 		if !sar.Status.Allowed {
 			return false, nil
@@ -250,6 +346,17 @@ func canWatchCRD(ctx context.Context, mgr manager.Manager) (bool, error) {
 	return true, nil
 }
 
+func accessResource(a authv1.ResourceAttributes) string {
+	r := a.Resource
+	if a.Subresource != "" {
+		r += "/" + a.Subresource
+	}
+	if a.Group != "" {
+		r += "." + a.Group
+	}
+	return r
+}
+
 func logProviderRuntimeDiagnostics(log logging.Logger) {
 	tfPath := os.Getenv("TERRAFORM_NATIVE_PROVIDER_PATH")
 	providerDir := os.Getenv("PROVIDER_DIR")
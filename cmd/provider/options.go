@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	optionsFileAPIVersion = "config.cloudflare.upbound.io/v1alpha1"
+	optionsFileKind       = "ProviderOptions"
+
+	configFileFlag   = "config"
+	configFileEnvVar = "PROVIDER_CONFIG_FILE"
+)
+
+// optionsFile is a provider configuration file. Every setting corresponds to
+// the command line flag of the same name and only supplies its default, so
+// flags and environment variables still take precedence over the file.
+type optionsFile struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+
+	Debug                    *bool   `json:"debug,omitempty"`
+	Sync                     *string `json:"sync,omitempty"`
+	Poll                     *string `json:"poll,omitempty"`
+	PollStateMetric          *string `json:"pollStateMetric,omitempty"`
+	LeaderElection           *bool   `json:"leaderElection,omitempty"`
+	MaxReconcileRate         *int    `json:"maxReconcileRate,omitempty"`
+	WebhookPort              *int    `json:"webhookPort,omitempty"`
+	MetricsBindAddress       *string `json:"metricsBindAddress,omitempty"`
+	ChangelogsSocketPath     *string `json:"changelogsSocketPath,omitempty"`
+	EnableManagementPolicies *bool   `json:"enableManagementPolicies,omitempty"`
+	EnableChangeLogs         *bool   `json:"enableChangeLogs,omitempty"`
+	PlanOnly                 *bool   `json:"planOnly,omitempty"`
+	CertsDir                 *string `json:"certsDir,omitempty"`
+
+	// PollKinds overrides the poll interval of single kinds, keyed by kind
+	// and API group such as Record.dns.cloudflare.upbound.io.
+	PollKinds map[string]string `json:"pollKinds,omitempty"`
+
+	// EnabledGroups limits the controllers that are started to the kinds of
+	// the listed API groups. All groups are enabled when it is empty.
+	EnabledGroups []string `json:"enabledGroups,omitempty"`
+
+	// OCIRegistries are the OCI registries the contentFrom sources of
+	// Workers resources may pull from, and InsecureOCIRegistries those
+	// they may also pull from over plain HTTP.
+	OCIRegistries         []string `json:"ociRegistries,omitempty"`
+	InsecureOCIRegistries []string `json:"insecureOCIRegistries,omitempty"`
+}
+
+// configFilePath returns the configuration file supplied on the command line
+// or through the environment. It runs before the flags are parsed so that
+// the file can supply their defaults.
+func configFilePath(args []string) string {
+	for i, a := range args {
+		switch {
+		case a == "--"+configFileFlag && i+1 < len(args):
+			return args[i+1]
+		case strings.HasPrefix(a, "--"+configFileFlag+"="):
+			return strings.TrimPrefix(a, "--"+configFileFlag+"=")
+		}
+	}
+	return os.Getenv(configFileEnvVar)
+}
+
+// loadOptionsFile reads and validates the YAML or JSON configuration file at
+// the supplied path. Unknown and duplicate fields are rejected.
+func loadOptionsFile(path string) (*optionsFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read configuration file")
+	}
+	f := &optionsFile{}
+	if err := yaml.UnmarshalStrict(data, f); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse configuration file %s", path)
+	}
+	return f, errors.Wrapf(f.validate(), "invalid configuration file %s", path)
+}
+
+func (f *optionsFile) validate() error {
+	if f.APIVersion != optionsFileAPIVersion || f.Kind != optionsFileKind {
+		return errors.Errorf("unsupported apiVersion %q and kind %q, expected %s %s", f.APIVersion, f.Kind, optionsFileAPIVersion, optionsFileKind)
+	}
+	for name, d := range map[string]*string{"sync": f.Sync, "poll": f.Poll, "pollStateMetric": f.PollStateMetric} {
+		if d == nil {
+			continue
+		}
+		if err := validatePositiveDuration(*d); err != nil {
+			return errors.Wrapf(err, "invalid %s", name)
+		}
+	}
+	if f.MaxReconcileRate != nil && *f.MaxReconcileRate < 1 {
+		return errors.Errorf("invalid maxReconcileRate %d: must be at least 1", *f.MaxReconcileRate)
+	}
+	if f.WebhookPort != nil && (*f.WebhookPort < 1 || *f.WebhookPort > 65535) {
+		return errors.Errorf("invalid webhookPort %d: must be between 1 and 65535", *f.WebhookPort)
+	}
+	for gk, d := range f.PollKinds {
+		if err := validatePositiveDuration(d); err != nil {
+			return errors.Wrapf(err, "invalid pollKinds entry %s", gk)
+		}
+	}
+	return nil
+}
+
+func validatePositiveDuration(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if d <= 0 {
+		return errors.Errorf("duration %s must be positive", s)
+	}
+	return nil
+}
+
+// flagDefaults returns the default values the file supplies, keyed by flag
+// name.
+func (f *optionsFile) flagDefaults() map[string][]string {
+	d := map[string][]string{}
+	setBool := func(flag string, v *bool) {
+		if v != nil {
+			d[flag] = []string{strconv.FormatBool(*v)}
+		}
+	}
+	setInt := func(flag string, v *int) {
+		if v != nil {
+			d[flag] = []string{strconv.Itoa(*v)}
+		}
+	}
+	setString := func(flag string, v *string) {
+		if v != nil {
+			d[flag] = []string{*v}
+		}
+	}
+	setBool("debug", f.Debug)
+	setString("sync", f.Sync)
+	setString("poll", f.Poll)
+	setString("poll-state-metric", f.PollStateMetric)
+	setBool("leader-election", f.LeaderElection)
+	setInt("max-reconcile-rate", f.MaxReconcileRate)
+	setInt("webhook-port", f.WebhookPort)
+	setString("metrics-bind-address", f.MetricsBindAddress)
+	setString("changelogs-socket-path", f.ChangelogsSocketPath)
+	setBool("enable-management-policies", f.EnableManagementPolicies)
+	setBool("enable-changelogs", f.EnableChangeLogs)
+	setBool("plan-only", f.PlanOnly)
+	setString("certs-dir", f.CertsDir)
+	if len(f.PollKinds) > 0 {
+		kinds := make([]string, 0, len(f.PollKinds))
+		for gk, p := range f.PollKinds {
+			kinds = append(kinds, fmt.Sprintf("%s=%s", gk, p))
+		}
+		sort.Strings(kinds)
+		d["poll-kind"] = kinds
+	}
+	if len(f.EnabledGroups) > 0 {
+		d["enabled-group"] = f.EnabledGroups
+	}
+	if len(f.OCIRegistries) > 0 {
+		d["oci-registry"] = f.OCIRegistries
+	}
+	if len(f.InsecureOCIRegistries) > 0 {
+		d["insecure-oci-registry"] = f.InsecureOCIRegistries
+	}
+	return d
+}
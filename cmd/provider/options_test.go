@@ -0,0 +1,222 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func writeOptionsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "options.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("cannot write configuration file: %v", err)
+	}
+	return path
+}
+
+func TestNewAppPrecedence(t *testing.T) {
+	file := writeOptionsFile(t, `apiVersion: config.cloudflare.upbound.io/v1alpha1
+kind: ProviderOptions
+poll: 5m
+webhookPort: 9000
+enabledGroups: [dns.cloudflare.upbound.io]
+`)
+	type want struct {
+		poll          time.Duration
+		webhookPort   int
+		enabledGroups []string
+	}
+	cases := map[string]struct {
+		args []string
+		env  map[string]string
+		want want
+	}{
+		"Defaults": {
+			want: want{poll: 10 * time.Minute, webhookPort: 9443},
+		},
+		"File": {
+			args: []string{"--config", file},
+			want: want{poll: 5 * time.Minute, webhookPort: 9000, enabledGroups: []string{"dns.cloudflare.upbound.io"}},
+		},
+		"FileFromEnvironment": {
+			env:  map[string]string{configFileEnvVar: file},
+			want: want{poll: 5 * time.Minute, webhookPort: 9000, enabledGroups: []string{"dns.cloudflare.upbound.io"}},
+		},
+		"EnvironmentOverridesFile": {
+			args: []string{"--config=" + file},
+			env:  map[string]string{"WEBHOOK_PORT": "9100", "PROVIDER_POLL": "1m"},
+			want: want{poll: time.Minute, webhookPort: 9100, enabledGroups: []string{"dns.cloudflare.upbound.io"}},
+		},
+		"FlagsOverrideEnvironment": {
+			args: []string{"--config", file, "--webhook-port", "9200", "--enabled-group", "zone.cloudflare.upbound.io"},
+			env:  map[string]string{"WEBHOOK_PORT": "9100"},
+			want: want{poll: 5 * time.Minute, webhookPort: 9200, enabledGroups: []string{"zone.cloudflare.upbound.io"}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			app, f, err := newApp("provider", tc.args)
+			if err != nil {
+				t.Fatalf("newApp(...): unexpected error: %v", err)
+			}
+			if _, err := app.Parse(tc.args); err != nil {
+				t.Fatalf("Parse(...): unexpected error: %v", err)
+			}
+			got := want{poll: *f.pollInterval, webhookPort: *f.webhookPort, enabledGroups: *f.enabledGroups}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("newApp(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewAppFileSettingsNameFlags(t *testing.T) {
+	yes, one, s := true, 1, "1m"
+	f := &optionsFile{
+		Debug: &yes, Sync: &s, Poll: &s, PollStateMetric: &s, LeaderElection: &yes, MaxReconcileRate: &one,
+		WebhookPort: &one, MetricsBindAddress: &s, ChangelogsSocketPath: &s, EnableManagementPolicies: &yes,
+		EnableChangeLogs: &yes, PlanOnly: &yes, CertsDir: &s,
+		PollKinds:     map[string]string{"Record.dns.cloudflare.upbound.io": "1h"},
+		EnabledGroups: []string{"dns.cloudflare.upbound.io"},
+	}
+	app, _, err := newApp("provider", nil)
+	if err != nil {
+		t.Fatalf("newApp(...): unexpected error: %v", err)
+	}
+	for name := range f.flagDefaults() {
+		if app.GetFlag(name) == nil {
+			t.Errorf("flagDefaults(): %s is not a flag", name)
+		}
+	}
+}
+
+func TestNewAppInvalidFile(t *testing.T) {
+	file := writeOptionsFile(t, `apiVersion: config.cloudflare.upbound.io/v1alpha1
+kind: ProviderOptions
+pol: 5m
+`)
+	if _, _, err := newApp("provider", []string{"--config", file}); err == nil {
+		t.Error("newApp(...): want error for unknown field, got none")
+	}
+}
+
+func TestOptionsFileValidate(t *testing.T) {
+	str := func(s string) *string { return &s }
+	num := func(i int) *int { return &i }
+	valid := func(f optionsFile) *optionsFile {
+		f.APIVersion, f.Kind = optionsFileAPIVersion, optionsFileKind
+		return &f
+	}
+	cases := map[string]struct {
+		f       *optionsFile
+		wantErr bool
+	}{
+		"Valid": {
+			f: valid(optionsFile{Poll: str("10m"), MaxReconcileRate: num(5), WebhookPort: num(9443), PollKinds: map[string]string{"Zone.zone.cloudflare.upbound.io": "1h"}}),
+		},
+		"WrongKind": {
+			f:       &optionsFile{APIVersion: optionsFileAPIVersion, Kind: "Provider"},
+			wantErr: true,
+		},
+		"WrongAPIVersion": {
+			f:       &optionsFile{APIVersion: "v1", Kind: optionsFileKind},
+			wantErr: true,
+		},
+		"InvalidDuration": {
+			f:       valid(optionsFile{Sync: str("often")}),
+			wantErr: true,
+		},
+		"NonPositiveDuration": {
+			f:       valid(optionsFile{PollStateMetric: str("0s")}),
+			wantErr: true,
+		},
+		"MaxReconcileRateTooLow": {
+			f:       valid(optionsFile{MaxReconcileRate: num(0)}),
+			wantErr: true,
+		},
+		"WebhookPortOutOfRange": {
+			f:       valid(optionsFile{WebhookPort: num(65536)}),
+			wantErr: true,
+		},
+		"InvalidPollKind": {
+			f:       valid(optionsFile{PollKinds: map[string]string{"Zone.zone.cloudflare.upbound.io": "-1h"}}),
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := tc.f.validate()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("validate(): want error %t, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewControllerSelection(t *testing.T) {
+	cases := map[string]struct {
+		pollKinds map[string]string
+		groups    []string
+		want      controllerSelection
+		wantErr   bool
+	}{
+		"Default": {
+			want: controllerSelection{pollIntervals: map[schema.GroupKind]time.Duration{}, groups: map[string]bool{}},
+		},
+		"Selection": {
+			pollKinds: map[string]string{"Record.dns.cloudflare.upbound.io": "1h"},
+			groups:    []string{"zone.cloudflare.upbound.io"},
+			want: controllerSelection{
+				pollIntervals: map[schema.GroupKind]time.Duration{recordGVK.GroupKind(): time.Hour},
+				groups:        map[string]bool{"zone.cloudflare.upbound.io": true},
+			},
+		},
+		"UnknownKindAndGroup": {
+			pollKinds: map[string]string{"Record.zone.cloudflare.upbound.io": "1h"},
+			groups:    []string{"ssl.cloudflare.upbound.io"},
+			wantErr:   true,
+		},
+		"InvalidPollInterval": {
+			pollKinds: map[string]string{"Record.dns.cloudflare.upbound.io": "hourly"},
+			wantErr:   true,
+		},
+		"NonPositivePollInterval": {
+			pollKinds: map[string]string{"Record.dns.cloudflare.upbound.io": "0s"},
+			wantErr:   true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := newControllerSelection(testProviders(), tc.pollKinds, tc.groups)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("newControllerSelection(...): want error %t, got %v", tc.wantErr, err)
+			}
+			if tc.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(controllerSelection{})); diff != "" {
+				t.Errorf("newControllerSelection(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestControllerSelectionEnabled(t *testing.T) {
+	s, err := newControllerSelection(testProviders(), nil, []string{"dns.cloudflare.upbound.io"})
+	if err != nil {
+		t.Fatalf("newControllerSelection(...): unexpected error: %v", err)
+	}
+	for gvk, want := range map[schema.GroupVersionKind]bool{recordGVK: true, zoneGVK: false} {
+		if got := s.enabled(gvk); got != want {
+			t.Errorf("enabled(%s): want %t, got %t", gvk, want, got)
+		}
+	}
+}
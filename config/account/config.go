@@ -0,0 +1,23 @@
+package account
+
+import (
+	"github.com/crossplane/upjet/v2/pkg/config"
+
+	"github.com/prolixalias/provider-cloudflare/config/common"
+	"github.com/prolixalias/provider-cloudflare/internal/hooks"
+)
+
+// Configure configures individual resources by adding custom ResourceConfigurators.
+func Configure(p *config.Provider) {
+	// Account-level resources take the ID of their account, which is the
+	// external name of the Account.
+	common.AddTopLevelReferences(p, "account_id", "cloudflare_account")
+
+	// Without an account ID, reference or zone, resources default to the
+	// account of their ProviderConfig.
+	for _, name := range common.ResourcesWithArgument(p, "account_id") {
+		p.AddResourceConfigurator(name, func(r *config.Resource) {
+			r.InitializerFns = append(r.InitializerFns, hooks.AccountIDInitializer)
+		})
+	}
+}
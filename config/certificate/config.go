@@ -0,0 +1,121 @@
+package certificate
+
+import (
+	"github.com/crossplane/upjet/v2/pkg/config"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// A Resource is a resource that uploads a certificate, which may be read
+// from a Secret.
+type Resource struct {
+	// Field is the argument holding the PEM encoded certificate.
+	Field string
+
+	// DeletePath returns the API path that deletes the uploaded certificate
+	// with the supplied ID, given the parameters of the resource, for
+	// certificates that cannot be changed in place. It is nil for those
+	// that can.
+	DeletePath func(parameter func(name string) string, id string) string
+}
+
+// Resources are the resources that may read their certificate from a
+// certificateSecretRef, by Terraform resource type.
+var Resources = map[string]Resource{
+	"cloudflare_custom_ssl": {Field: "certificate"},
+	"cloudflare_mtls_certificate": {Field: "certificates", DeletePath: func(parameter func(string) string, id string) string {
+		return "/accounts/" + parameter("accountId") + "/mtls_certificates/" + id
+	}},
+	"cloudflare_zero_trust_access_mtls_certificate": {Field: "certificate", DeletePath: func(parameter func(string) string, id string) string {
+		if zoneID := parameter("zoneId"); zoneID != "" {
+			return "/zones/" + zoneID + "/access/certificates/" + id
+		}
+		return "/accounts/" + parameter("accountId") + "/access/certificates/" + id
+	}},
+	"cloudflare_authenticated_origin_pulls_certificate": {Field: "certificate", DeletePath: func(parameter func(string) string, id string) string {
+		return "/zones/" + parameter("zoneId") + "/origin_tls_client_auth/" + id
+	}},
+	"cloudflare_keyless_certificate": {Field: "certificate", DeletePath: func(parameter func(string) string, id string) string {
+		return "/zones/" + parameter("zoneId") + "/keyless_certificates/" + id
+	}},
+}
+
+// Configure configures individual resources by adding custom ResourceConfigurators.
+func Configure(p *config.Provider) {
+	for name, cr := range Resources {
+		p.AddResourceConfigurator(name, func(r *config.Resource) {
+			// The certificate may be read from a TLS Secret instead, like
+			// the private key through privateKeySecretRef.
+			cert := r.TerraformResource.Schema[cr.Field]
+			cert.Required = false
+			cert.Optional = true
+			r.TerraformResource.Schema["certificate_secret_ref"] = certificateSecretRefSchema()
+			r.SchemaElementOptions.SetEmbeddedObject("certificate_secret_ref")
+			r.TerraformConfigurationInjector = func(_ map[string]any, tfMap map[string]any) error {
+				delete(tfMap, "certificate_secret_ref")
+				return nil
+			}
+		})
+	}
+	p.AddResourceConfigurator("cloudflare_origin_ca_certificate", func(r *config.Resource) {
+		// The provider can generate the key and CSR of the certificate
+		// and keep them, with the certificate, in a TLS Secret.
+		csr := r.TerraformResource.Schema["csr"]
+		csr.Required = false
+		csr.Optional = true
+		r.TerraformResource.Schema["tls_secret_ref"] = &schema.Schema{
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			Description: "A kubernetes.io/tls Secret the provider keeps the private key, CSR and certificate in, instead of csr. The key and CSR are generated for the hostnames and request type, which must be origin-rsa or origin-ecc, unless the Secret holds a key already. The certificate is reissued with a new key when its hostnames or validity change and before it expires, and the previous certificate is revoked.",
+			Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+				"name": {
+					Type:        schema.TypeString,
+					Required:    true,
+					Description: "Name of the Secret.",
+				},
+				"namespace": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "Namespace of the Secret. Defaults to, and for namespaced resources must be, the namespace of the resource.",
+				},
+			}},
+		}
+		r.TerraformResource.Schema["renew_before_days"] = &schema.Schema{
+			Type:        schema.TypeInt,
+			Optional:    true,
+			Description: "Days before it expires that a certificate with a tlsSecretRef is reissued. Defaults to a third of its validity.",
+		}
+		r.SchemaElementOptions.SetEmbeddedObject("tls_secret_ref")
+		r.TerraformConfigurationInjector = func(_ map[string]any, tfMap map[string]any) error {
+			delete(tfMap, "tls_secret_ref")
+			delete(tfMap, "renew_before_days")
+			return nil
+		}
+	})
+}
+
+func certificateSecretRefSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Description: "A key of a Secret holding the PEM encoded certificate, such as a kubernetes.io/tls Secret issued by cert-manager, instead of the certificate in the resource. The certificate is uploaded again when the Secret changes.",
+		Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Name of the Secret.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Namespace of the Secret. Defaults to, and for namespaced resources must be, the namespace of the resource.",
+			},
+			"key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Key of the certificate in the Secret. Defaults to tls.crt.",
+			},
+		}},
+	}
+}
@@ -0,0 +1,56 @@
+// Package common contains configuration shared by the resources of the
+// provider.
+package common
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/crossplane/upjet/v2/pkg/config"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// AddTopLevelReferences adds a reference to the supplied Terraform resource
+// for the top-level field of every other resource whose schema has it as an
+// argument.
+func AddTopLevelReferences(p *config.Provider, field, terraformName string) {
+	for _, name := range ResourcesWithArgument(p, field) {
+		if name == terraformName {
+			continue
+		}
+		p.AddResourceConfigurator(name, func(r *config.Resource) {
+			if _, ok := r.References[field]; ok {
+				return
+			}
+			r.References[field] = config.Reference{TerraformName: terraformName}
+		})
+	}
+}
+
+// ResourcesWithArgument returns the names of the resources whose schema has
+// the supplied top-level string field as an argument that can be set in
+// their spec.
+func ResourcesWithArgument(p *config.Provider, field string) []string {
+	var names []string
+	for name, r := range p.Resources {
+		if isArgument(r.TerraformResource, field) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isArgument(res *schema.Resource, field string) bool {
+	if res == nil {
+		return false
+	}
+	s, ok := res.Schema[field]
+	return ok && s.Type == schema.TypeString && (s.Required || s.Optional)
+}
+
+// ExtractParamPath extracts a parameter of the referenced resource, given
+// its Terraform field path.
+func ExtractParamPath(field string) string {
+	return fmt.Sprintf("github.com/crossplane/upjet/v2/pkg/resource.ExtractParamPath(%q,false)", field)
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"sort"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// ExternalNameCoverage relates the resources in ExternalNameConfigs to the
+// resources of the embedded Terraform provider schema.
+type ExternalNameCoverage struct {
+	// Schema is the number of resources in the embedded schema.
+	Schema int
+
+	// Configured are the resources with an external name configuration
+	// that are in the embedded schema. Only these resources are generated.
+	Configured []string
+
+	// Unknown are the resources with an external name configuration that
+	// are not in the embedded schema, typically typos or resources removed
+	// from the Terraform provider.
+	Unknown []string
+
+	// Unconfigured are the resources of the embedded schema without an
+	// external name configuration.
+	Unconfigured []string
+}
+
+// NewExternalNameCoverage returns the coverage of the supplied Terraform
+// resource schemas by ExternalNameConfigs.
+func NewExternalNameCoverage(schemas map[string]*tfjson.Schema) *ExternalNameCoverage {
+	c := &ExternalNameCoverage{Schema: len(schemas)}
+	for name := range ExternalNameConfigs {
+		if _, ok := schemas[name]; ok {
+			c.Configured = append(c.Configured, name)
+		} else {
+			c.Unknown = append(c.Unknown, name)
+		}
+	}
+	for name := range schemas {
+		if _, ok := ExternalNameConfigs[name]; !ok {
+			c.Unconfigured = append(c.Unconfigured, name)
+		}
+	}
+	sort.Strings(c.Configured)
+	sort.Strings(c.Unknown)
+	sort.Strings(c.Unconfigured)
+	return c
+}
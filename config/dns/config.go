@@ -0,0 +1,16 @@
+package dns
+
+import (
+	"github.com/crossplane/upjet/v2/pkg/config"
+
+	"github.com/prolixalias/provider-cloudflare/internal/hooks"
+)
+
+// Configure configures individual resources by adding custom ResourceConfigurators.
+func Configure(p *config.Provider) {
+	p.AddResourceConfigurator("cloudflare_dns_record", func(r *config.Resource) {
+		// Records annotated for adoption take over an existing record
+		// with the same zone, name and type instead of creating one.
+		r.InitializerFns = append(r.InitializerFns, hooks.DNSRecordAdoptionInitializer)
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	"github.com/crossplane/upjet/v2/pkg/config"
+
+	"github.com/prolixalias/provider-cloudflare/internal/hooks"
 )
 
 // ExternalNameConfigs contains all external name configurations for this
@@ -180,8 +182,7 @@ var ExternalNameConfigs = map[string]config.ExternalName{
 	"cloudflare_workers_script_subdomain": config.IdentifierFromProvider,
 	"cloudflare_worker_version": config.IdentifierFromProvider,
 	"cloudflare_workflow": config.IdentifierFromProvider,
-	"cloudflare_zone": config.IdentifierFromProvider,
-	"cloudflare_zone_settings_override": config.IdentifierFromProvider,
+	"cloudflare_zone": zoneExternalName(),
 	"cloudflare_zone_cache_reserve": config.IdentifierFromProvider,
 	"cloudflare_zone_cache_variants": config.IdentifierFromProvider,
 	"cloudflare_zone_dnssec": config.IdentifierFromProvider,
@@ -266,3 +267,27 @@ func ExternalNameConfigured() []string {
 	}
 	return l
 }
+
+// zoneExternalName configures zones like IdentifierFromProvider, except that
+// their external names may be the domain name of an existing zone, which is
+// looked up to its zone ID. The external name is then set to the ID observed
+// in the state.
+func zoneExternalName() config.ExternalName {
+	en := config.IdentifierFromProvider
+	en.GetIDFn = zoneIDFromName
+	return en
+}
+
+// zoneIDFromName returns the ID of the zone with the supplied external name,
+// looking it up if it is a domain name.
+func zoneIDFromName(ctx context.Context, externalName string, parameters map[string]any, terraformProviderConfig map[string]any) (string, error) {
+	id, err := config.IdentifierFromProvider.GetIDFn(ctx, externalName, parameters, terraformProviderConfig)
+	if err != nil || !strings.Contains(id, ".") {
+		return id, err
+	}
+	var accountID string
+	if account, ok := parameters["account"].(map[string]any); ok {
+		accountID, _ = account["id"].(string)
+	}
+	return hooks.ZoneIDByName(ctx, terraformProviderConfig, id, accountID)
+}
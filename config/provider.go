@@ -6,7 +6,14 @@ import (
 
 	ujconfig "github.com/crossplane/upjet/v2/pkg/config"
 
+	"github.com/prolixalias/provider-cloudflare/config/account"
 	"github.com/prolixalias/provider-cloudflare/config/address"
+	"github.com/prolixalias/provider-cloudflare/config/certificate"
+	"github.com/prolixalias/provider-cloudflare/config/dns"
+	"github.com/prolixalias/provider-cloudflare/config/token"
+	"github.com/prolixalias/provider-cloudflare/config/tunnel"
+	"github.com/prolixalias/provider-cloudflare/config/workers"
+	"github.com/prolixalias/provider-cloudflare/config/zone"
 )
 
 const (
@@ -34,7 +41,14 @@ func GetProvider() *ujconfig.Provider {
 		))
 
 	for _, configure := range []func(provider *ujconfig.Provider){
+		account.Configure,
 		address.Configure,
+		certificate.Configure,
+		dns.Configure,
+		token.Configure,
+		tunnel.Configure,
+		workers.Configure,
+		zone.Configure,
 	} {
 		configure(pc)
 	}
@@ -60,7 +74,14 @@ func GetProviderNamespaced() *ujconfig.Provider {
 		}))
 
 	for _, configure := range []func(provider *ujconfig.Provider){
+		account.Configure,
 		address.Configure,
+		certificate.Configure,
+		dns.Configure,
+		token.Configure,
+		tunnel.Configure,
+		workers.Configure,
+		zone.Configure,
 	} {
 		configure(pc)
 	}
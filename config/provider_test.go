@@ -0,0 +1,194 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	ujconfig "github.com/crossplane/upjet/v2/pkg/config"
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// Run `go test -tags ci ./config -update` (or `make golden.update`) after
+// changing the resource configuration, and review the diff of the golden
+// files like any other change.
+var update = flag.Bool("update", false, "update the golden resource configuration files")
+
+// resourceDump is the effective configuration of a resource, as far as it
+// shapes the generated API and controller.
+type resourceDump struct {
+	Kind            string                   `json:"kind"`
+	Group           string                   `json:"group"`
+	Version         string                   `json:"version"`
+	Client          string                   `json:"client"`
+	ExternalName    externalNameDump         `json:"externalName"`
+	References      map[string]referenceDump `json:"references,omitempty"`
+	SensitiveFields []string                 `json:"sensitiveFields,omitempty"`
+
+	// AdditionalConnectionDetails reports whether the resource publishes
+	// connection details besides its sensitive fields.
+	AdditionalConnectionDetails bool `json:"additionalConnectionDetails,omitempty"`
+}
+
+type externalNameDump struct {
+	Type                   string   `json:"type"`
+	IdentifierFields       []string `json:"identifierFields,omitempty"`
+	ComputedIdentifiers    []string `json:"computedIdentifiers,omitempty"`
+	OmittedFields          []string `json:"omittedFields,omitempty"`
+	DisableNameInitializer bool     `json:"disableNameInitializer,omitempty"`
+}
+
+type referenceDump struct {
+	Type              string `json:"type,omitempty"`
+	TerraformName     string `json:"terraformName,omitempty"`
+	Extractor         string `json:"extractor,omitempty"`
+	RefFieldName      string `json:"refFieldName,omitempty"`
+	SelectorFieldName string `json:"selectorFieldName,omitempty"`
+}
+
+// externalNameTypes are the external name configurations of upjet that
+// resources are based on. Configurations are told apart by their functions.
+var externalNameTypes = []struct {
+	name string
+	en   ujconfig.ExternalName
+}{
+	{name: "IdentifierFromProvider", en: ujconfig.IdentifierFromProvider},
+	{name: "NameAsIdentifier", en: ujconfig.NameAsIdentifier},
+	{name: "ParameterAsIdentifier", en: ujconfig.ParameterAsIdentifier("name")},
+	{name: "TemplatedStringAsIdentifier", en: ujconfig.TemplatedStringAsIdentifier("", "{{ .external_name }}")},
+	{name: "FrameworkResourceWithComputedIdentifier", en: ujconfig.FrameworkResourceWithComputedIdentifier("id", "id")},
+	{name: "ZoneExternalName", en: zoneExternalName()},
+}
+
+func funcPointer(fn any) uintptr {
+	v := reflect.ValueOf(fn)
+	if v.IsNil() {
+		return 0
+	}
+	return v.Pointer()
+}
+
+// externalNameType names the upjet external name configuration the supplied
+// one is based on, along with the functions it overrides. The configuration
+// it overrides the fewest functions of is chosen.
+func externalNameType(en ujconfig.ExternalName) string {
+	name, fewest := "Custom", 3
+	for _, t := range externalNameTypes {
+		var custom []string
+		if funcPointer(en.GetExternalNameFn) != funcPointer(t.en.GetExternalNameFn) {
+			custom = append(custom, "GetExternalNameFn")
+		}
+		if funcPointer(en.GetIDFn) != funcPointer(t.en.GetIDFn) {
+			custom = append(custom, "GetIDFn")
+		}
+		if funcPointer(en.SetIdentifierArgumentFn) != funcPointer(t.en.SetIdentifierArgumentFn) {
+			custom = append(custom, "SetIdentifierArgumentFn")
+		}
+		switch {
+		case len(custom) >= fewest:
+		case len(custom) == 0:
+			return t.name
+		default:
+			name, fewest = t.name+" with custom "+strings.Join(custom, ", "), len(custom)
+		}
+	}
+	return name
+}
+
+// sensitiveFields returns the paths of the sensitive attributes of a
+// Terraform resource schema.
+func sensitiveFields(prefix string, s map[string]*schema.Schema) []string {
+	var paths []string
+	for name, attr := range s {
+		p := prefix + name
+		if attr.Sensitive {
+			paths = append(paths, p)
+		}
+		if r, ok := attr.Elem.(*schema.Resource); ok {
+			paths = append(paths, sensitiveFields(p+".", r.Schema)...)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func dumpResources(p *ujconfig.Provider) map[string]resourceDump {
+	dump := make(map[string]resourceDump, len(p.Resources))
+	for name, r := range p.Resources {
+		client := "cli"
+		switch {
+		case r.ShouldUseTerraformPluginFrameworkClient():
+			client = "framework"
+		case r.ShouldUseTerraformPluginSDKClient():
+			client = "sdk"
+		}
+		d := resourceDump{
+			Kind:    r.Kind,
+			Group:   r.ShortGroup + "." + p.RootGroup,
+			Version: r.Version,
+			Client:  client,
+			ExternalName: externalNameDump{
+				Type:                   externalNameType(r.ExternalName),
+				IdentifierFields:       r.ExternalName.IdentifierFields,
+				ComputedIdentifiers:    r.ExternalName.TFPluginFrameworkOptions.ComputedIdentifierAttributes,
+				OmittedFields:          r.ExternalName.OmittedFields,
+				DisableNameInitializer: r.ExternalName.DisableNameInitializer,
+			},
+			SensitiveFields:             sensitiveFields("", r.TerraformResource.Schema),
+			AdditionalConnectionDetails: funcPointer(r.Sensitive.AdditionalConnectionDetailsFn) != funcPointer(ujconfig.NopAdditionalConnectionDetails),
+		}
+		if len(r.References) > 0 {
+			d.References = make(map[string]referenceDump, len(r.References))
+			for field, ref := range r.References {
+				d.References[field] = referenceDump(ref)
+			}
+		}
+		dump[name] = d
+	}
+	return dump
+}
+
+func TestGoldenResourceConfiguration(t *testing.T) {
+	cases := map[string]func() *ujconfig.Provider{
+		"cluster":    GetProvider,
+		"namespaced": GetProviderNamespaced,
+	}
+	for name, getProvider := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := yaml.Marshal(dumpResources(getProvider()))
+			if err != nil {
+				t.Fatalf("cannot marshal resource configuration: %v", err)
+			}
+			golden := filepath.Join("testdata", name+".golden.yaml")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o600); err != nil {
+					t.Fatalf("cannot update %s: %v", golden, err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("cannot read %s, run with -update to create it: %v", golden, err)
+			}
+			if diff := cmp.Diff(string(want), string(got)); diff != "" {
+				t.Errorf("resource configuration differs from %s, run with -update and review the changes: -want, +got:\n%s", golden, diff)
+			}
+		})
+	}
+}
+
+func TestExternalNameConfigsInSchema(t *testing.T) {
+	schemas, err := TerraformResourceSchemas()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unknown := NewExternalNameCoverage(schemas).Unknown; len(unknown) > 0 {
+		t.Errorf("resources in ExternalNameConfigs but not in schema.json: %s", strings.Join(unknown, ", "))
+	}
+}
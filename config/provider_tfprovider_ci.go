@@ -3,13 +3,77 @@
 package config
 
 import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
+	pschema "github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	rschema "github.com/hashicorp/terraform-plugin-framework/resource/schema"
 )
 
-// getTerraformProvider returns nil during CI builds.
+// getTerraformProvider returns a stand-in for the Cloudflare terraform
+// provider during CI builds.
 // This avoids compiling the massive terraform-provider-cloudflare
 // dependency which requires 10GB+ of disk space.
-// The actual provider is only needed at runtime, not for linting or testing.
+// The stand-in only serves the names of the resources in the embedded
+// schema document, which is all GetProvider needs to assemble the resource
+// configuration for linting and testing. It cannot apply anything.
 func getTerraformProvider() provider.Provider {
+	return schemaOnlyProvider{}
+}
+
+type schemaOnlyProvider struct{}
+
+func (schemaOnlyProvider) Metadata(_ context.Context, _ provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = resourcePrefix
+}
+
+func (schemaOnlyProvider) Schema(_ context.Context, _ provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = pschema.Schema{}
+}
+
+func (schemaOnlyProvider) Configure(context.Context, provider.ConfigureRequest, *provider.ConfigureResponse) {
+}
+
+func (schemaOnlyProvider) DataSources(context.Context) []func() datasource.DataSource {
 	return nil
 }
+
+func (schemaOnlyProvider) Resources(context.Context) []func() resource.Resource {
+	schemas, err := TerraformResourceSchemas()
+	if err != nil {
+		panic(err)
+	}
+	fns := make([]func() resource.Resource, 0, len(schemas))
+	for name := range schemas {
+		fns = append(fns, func() resource.Resource { return schemaOnlyResource{typeName: name} })
+	}
+	return fns
+}
+
+type schemaOnlyResource struct {
+	typeName string
+}
+
+func (r schemaOnlyResource) Metadata(_ context.Context, _ resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = r.typeName
+}
+
+func (schemaOnlyResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = rschema.Schema{}
+}
+
+func (r schemaOnlyResource) Create(_ context.Context, _ resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.AddError("Unsupported in CI builds", r.typeName+" cannot be created by a CI build of the provider")
+}
+
+func (schemaOnlyResource) Read(context.Context, resource.ReadRequest, *resource.ReadResponse) {}
+
+func (r schemaOnlyResource) Update(_ context.Context, _ resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.AddError("Unsupported in CI builds", r.typeName+" cannot be updated by a CI build of the provider")
+}
+
+func (r schemaOnlyResource) Delete(_ context.Context, _ resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.AddError("Unsupported in CI builds", r.typeName+" cannot be deleted by a CI build of the provider")
+}
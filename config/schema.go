@@ -0,0 +1,22 @@
+package config
+
+import (
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/pkg/errors"
+)
+
+// TerraformResourceSchemas returns the resource schemas of the embedded
+// Terraform provider schema document, keyed by Terraform resource name.
+func TerraformResourceSchemas() (map[string]*tfjson.Schema, error) {
+	ps := tfjson.ProviderSchemas{}
+	if err := ps.UnmarshalJSON([]byte(providerSchema)); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal the embedded Terraform provider schema")
+	}
+	if len(ps.Schemas) != 1 {
+		return nil, errors.Errorf("embedded Terraform schema should contain exactly 1 provider but contains %d", len(ps.Schemas))
+	}
+	for _, s := range ps.Schemas {
+		return s.ResourceSchemas, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,35 @@
+package token
+
+import (
+	"github.com/crossplane/upjet/v2/pkg/config"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/prolixalias/provider-cloudflare/internal/hooks"
+)
+
+// Configure configures individual resources by adding custom ResourceConfigurators.
+func Configure(p *config.Provider) {
+	// Token values are only returned when the token is created. They are
+	// published as credentials a ProviderConfig can use.
+	for _, name := range []string{"cloudflare_api_token", "cloudflare_account_token"} {
+		p.AddResourceConfigurator(name, func(r *config.Resource) {
+			r.TerraformResource.Schema["value"].Sensitive = true
+			r.Sensitive.AdditionalConnectionDetailsFn = hooks.APITokenConnectionDetails
+		})
+	}
+	p.AddResourceConfigurator("cloudflare_zero_trust_access_service_token", func(r *config.Resource) {
+		// The client secret is only returned when the token is created or
+		// its secret rotated. The client ID and secret are published as
+		// connection details.
+		r.Sensitive.AdditionalConnectionDetailsFn = hooks.AccessServiceTokenConnectionDetails
+		r.TerraformResource.Schema["rotate_before_days"] = &schema.Schema{
+			Type:        schema.TypeInt,
+			Optional:    true,
+			Description: "Days before it expires that the client secret of the token is rotated and its expiry extended by its duration. The token is not rotated if unset.",
+		}
+		r.TerraformConfigurationInjector = func(_ map[string]any, tfMap map[string]any) error {
+			delete(tfMap, "rotate_before_days")
+			return nil
+		}
+	})
+}
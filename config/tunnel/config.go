@@ -0,0 +1,37 @@
+package tunnel
+
+import (
+	"github.com/crossplane/upjet/v2/pkg/config"
+
+	"github.com/prolixalias/provider-cloudflare/internal/hooks"
+)
+
+// Configure configures individual resources by adding custom ResourceConfigurators.
+func Configure(p *config.Provider) {
+	p.AddResourceConfigurator("cloudflare_zero_trust_tunnel_cloudflared", func(r *config.Resource) {
+		// The tunnel secret is sensitive, so it is read from
+		// tunnelSecretSecretRef. The ID, token and credentials file
+		// cloudflared runs the tunnel with are published as connection
+		// details.
+		r.Sensitive.AdditionalConnectionDetailsFn = hooks.TunnelConnectionDetails
+	})
+	p.AddResourceConfigurator("cloudflare_zero_trust_tunnel_cloudflared_config", func(r *config.Resource) {
+		r.References["tunnel_id"] = config.Reference{
+			TerraformName: "cloudflare_zero_trust_tunnel_cloudflared",
+		}
+		r.InitializerFns = append(r.InitializerFns, hooks.TunnelConfigInitializer)
+	})
+	p.AddResourceConfigurator("cloudflare_zero_trust_tunnel_cloudflared_route", func(r *config.Resource) {
+		r.References["tunnel_id"] = config.Reference{
+			TerraformName: "cloudflare_zero_trust_tunnel_cloudflared",
+		}
+		r.References["virtual_network_id"] = config.Reference{
+			TerraformName: "cloudflare_zero_trust_tunnel_cloudflared_virtual_network",
+		}
+	})
+	p.AddResourceConfigurator("cloudflare_zero_trust_network_hostname_route", func(r *config.Resource) {
+		r.References["tunnel_id"] = config.Reference{
+			TerraformName: "cloudflare_zero_trust_tunnel_cloudflared",
+		}
+	})
+}
@@ -0,0 +1,242 @@
+package workers
+
+import (
+	"github.com/crossplane/upjet/v2/pkg/config"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/prolixalias/provider-cloudflare/config/common"
+	"github.com/prolixalias/provider-cloudflare/internal/hooks"
+)
+
+// Configure configures individual resources by adding custom ResourceConfigurators.
+func Configure(p *config.Provider) {
+	p.AddResourceConfigurator("cloudflare_workers_script", func(r *config.Resource) {
+		addContentFrom(r, "")
+		addBindingReferences(r)
+		r.TerraformConfigurationInjector = injectConfiguration
+		// Scripts are read from their source, keep them out of the spec.
+		r.LateInitializer.IgnoredFields = append(r.LateInitializer.IgnoredFields, "content", "content_file", "content_sha256")
+	})
+	p.AddResourceConfigurator("cloudflare_worker_version", func(r *config.Resource) {
+		addContentFrom(r, "modules")
+		addBindingReferences(r)
+		r.TerraformConfigurationInjector = injectConfiguration
+		r.LateInitializer.IgnoredFields = append(r.LateInitializer.IgnoredFields, "modules")
+	})
+	p.AddResourceConfigurator("cloudflare_workers_kv", func(r *config.Resource) {
+		// Values are read from a Secret through valueSecretRef, or from a
+		// ConfigMap through value_from, instead of inline.
+		value := r.TerraformResource.Schema["value"]
+		value.Sensitive = true
+		value.Required = false
+		value.Optional = true
+		r.TerraformResource.Schema["value_from"] = valueFromSchema()
+		r.SchemaElementOptions.SetEmbeddedObject("value_from")
+		r.SchemaElementOptions.SetEmbeddedObject("value_from.config_map_key_ref")
+		r.TerraformConfigurationInjector = hooks.InjectKVValue
+		r.References["namespace_id"] = config.Reference{
+			TerraformName: "cloudflare_workers_kv_namespace",
+		}
+	})
+	p.AddResourceConfigurator("cloudflare_workers_kv_namespace", func(r *config.Resource) {
+		r.TerraformResource.Schema["values_from"] = valuesFromSchema()
+		r.SchemaElementOptions.SetEmbeddedObject("values_from")
+		r.SchemaElementOptions.SetEmbeddedObject("values_from.config_map_ref")
+		r.TerraformConfigurationInjector = func(_ map[string]any, tfMap map[string]any) error {
+			delete(tfMap, "values_from")
+			return nil
+		}
+	})
+}
+
+// injectConfiguration removes the arguments the provider adds to the
+// Terraform schema of Workers resources from their Terraform configuration,
+// and injects the content read from their contentFrom sources.
+func injectConfiguration(jsonMap map[string]any, tfMap map[string]any) error {
+	if err := hooks.InjectContentSources(jsonMap, tfMap); err != nil {
+		return err
+	}
+	delete(tfMap, "content_from")
+	if modules, ok := tfMap["modules"].([]any); ok {
+		for _, m := range modules {
+			if m, ok := m.(map[string]any); ok {
+				delete(m, "content_from")
+			}
+		}
+	}
+	bindings, _ := tfMap["bindings"].([]any)
+	for _, b := range bindings {
+		b, ok := b.(map[string]any)
+		if !ok {
+			continue
+		}
+		// Hyperdrive configs are bound by the same id argument as D1
+		// databases.
+		if id, ok := b["hyperdrive_id"]; ok {
+			if id != nil {
+				b["id"] = id
+			}
+			delete(b, "hyperdrive_id")
+		}
+	}
+	return nil
+}
+
+// addBindingReferences adds references to the resources Workers bind to,
+// inside each entry of the bindings argument.
+func addBindingReferences(r *config.Resource) {
+	bindings := r.TerraformResource.Schema["bindings"].Elem.(*schema.Resource).Schema
+	// Both D1 databases and Hyperdrive configs are bound by id, which can
+	// only reference one kind. Hyperdrive configs are referenced by an
+	// argument of their own instead, which is copied to id.
+	bindings["hyperdrive_id"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Description: "Identifier of the Hyperdrive config to bind to. Used as id of hyperdrive bindings.",
+	}
+	r.References["bindings.namespace_id"] = config.Reference{
+		TerraformName: "cloudflare_workers_kv_namespace",
+	}
+	r.References["bindings.bucket_name"] = config.Reference{
+		TerraformName: "cloudflare_r2_bucket",
+		Extractor:     common.ExtractParamPath("name"),
+	}
+	r.References["bindings.id"] = config.Reference{
+		TerraformName:     "cloudflare_d1_database",
+		RefFieldName:      "D1DatabaseRef",
+		SelectorFieldName: "D1DatabaseSelector",
+	}
+	r.References["bindings.queue_name"] = config.Reference{
+		TerraformName: "cloudflare_queue",
+		Extractor:     common.ExtractParamPath("queue_name"),
+	}
+	r.References["bindings.hyperdrive_id"] = config.Reference{
+		TerraformName: "cloudflare_hyperdrive_config",
+	}
+	r.References["bindings.service"] = config.Reference{
+		TerraformName: "cloudflare_workers_script",
+		Extractor:     common.ExtractParamPath("script_name"),
+	}
+}
+
+// addContentFrom adds a content_from argument to the Terraform schema of a
+// Workers resource, or of its supplied nested block. The provider resolves it
+// to the content_file argument in the configuration passed to Terraform,
+// from which injectConfiguration removes it.
+func addContentFrom(r *config.Resource, block string) {
+	s := r.TerraformResource.Schema
+	path := "content_from"
+	if block != "" {
+		s = r.TerraformResource.Schema[block].Elem.(*schema.Resource).Schema
+		path = block + "." + path
+	}
+	s["content_from"] = contentFromSchema()
+	for _, el := range []string{path, path + ".config_map_key_ref", path + ".secret_key_ref", path + ".oci"} {
+		r.SchemaElementOptions.SetEmbeddedObject(el)
+	}
+}
+
+// keyRefSchema returns the schema of a reference to a key of a ConfigMap or
+// Secret holding the described content.
+func keyRefSchema(kind, content string) *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Description: "A key of a " + kind + " holding the " + content + ".",
+		Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Name of the " + kind + ".",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Namespace of the " + kind + ". Defaults to, and for namespaced resources must be, the namespace of the resource.",
+			},
+			"key": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Key of the " + content + " in the " + kind + ".",
+			},
+		}},
+	}
+}
+
+func contentFromSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Description: "Source the content is read from when the resource is reconciled, instead of content in the resource. Changes to the source content are deployed. Exactly one source must be set.",
+		Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+			"config_map_key_ref": keyRefSchema("ConfigMap", "content"),
+			"secret_key_ref":     keyRefSchema("Secret", "content"),
+			"oci": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "An OCI artifact holding the content, such as one pushed with oras to a registry in the cluster. Only anonymous pulls are supported, with an anonymous token where the registry requires one.",
+				Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+					"reference": {
+						Type:        schema.TypeString,
+						Required:    true,
+						Description: "Reference of the artifact, such as registry.example:5000/workers/app:v1 or a digest reference.",
+					},
+					"path": {
+						Type:        schema.TypeString,
+						Optional:    true,
+						Description: "Title of the layer holding the content, as set in its org.opencontainers.image.title annotation. May be omitted if the artifact has a single layer.",
+					},
+					"insecure": {
+						Type:        schema.TypeBool,
+						Optional:    true,
+						Description: "Pull the artifact over plain HTTP.",
+					},
+				}},
+			},
+		}},
+	}
+}
+
+func valueFromSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Description: "Source the value is read from when the resource is reconciled, instead of value or valueSecretRef. Changes to the source value are written.",
+		Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+			"config_map_key_ref": keyRefSchema("ConfigMap", "value"),
+		}},
+	}
+}
+
+func valuesFromSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Description: "Source the values of the namespace are synced from once it exists. Every key of the source is written to the namespace when the source changes, and keys removed from the source are deleted from the namespace.",
+		Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+			"config_map_ref": {
+				Type:        schema.TypeList,
+				Required:    true,
+				MaxItems:    1,
+				Description: "A ConfigMap holding the values by key.",
+				Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+					"name": {
+						Type:        schema.TypeString,
+						Required:    true,
+						Description: "Name of the ConfigMap.",
+					},
+					"namespace": {
+						Type:        schema.TypeString,
+						Optional:    true,
+						Description: "Namespace of the ConfigMap. Defaults to, and for namespaced resources must be, the namespace of the resource.",
+					},
+				}},
+			},
+		}},
+	}
+}
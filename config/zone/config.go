@@ -0,0 +1,14 @@
+package zone
+
+import (
+	"github.com/crossplane/upjet/v2/pkg/config"
+
+	"github.com/prolixalias/provider-cloudflare/config/common"
+)
+
+// Configure configures individual resources by adding custom ResourceConfigurators.
+func Configure(p *config.Provider) {
+	// Zone-level resources take the ID of their zone, which is the external
+	// name of the Zone.
+	common.AddTopLevelReferences(p, "zone_id", "cloudflare_zone")
+}
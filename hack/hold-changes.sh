@@ -0,0 +1,48 @@
+#!/bin/bash
+# Post-generation fix: wrap the external connectors of the generated managed
+# resource controllers with clients.HoldChanges, so that creations and
+# deletions held by plan-only mode or change windows wait for the hold to end
+# instead of failing, and with clients.ConnectInTurn, so that the values
+# resolved when connecting reach the right resource. upjet offers no option to
+# wrap the connectors it generates. Run after make generate so the fix
+# persists.
+
+set -e
+
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+ROOT_DIR="$(cd "$SCRIPT_DIR/.." && pwd)"
+cd "$ROOT_DIR"
+
+python3 << 'PY'
+import glob
+
+CALL = "managed.WithExternalConnector("
+IMPORT = '"github.com/prolixalias/provider-cloudflare/internal/clients"'
+
+fixed = 0
+for path in sorted(glob.glob("internal/controller/*/*/*/zz_controller.go")):
+    with open(path) as f:
+        content = f.read()
+    if "clients.HoldChanges(" in content:
+        continue
+    start = content.find(CALL)
+    if start < 0:
+        raise SystemExit("External connector not found in " + path)
+    arg = start + len(CALL)
+    depth, end = 1, arg
+    while depth:
+        if content[end] == "(":
+            depth += 1
+        elif content[end] == ")":
+            depth -= 1
+        end += 1
+    end -= 1
+    content = content[:arg] + "clients.HoldChanges(clients.ConnectInTurn(" + content[arg:end] + "))" + content[end:]
+    content = content.replace("import (\n", "import (\n\t" + IMPORT + "\n", 1)
+    with open(path, "w") as f:
+        f.write(content)
+    fixed += 1
+print("Wrapped the external connectors of", fixed, "controllers")
+PY
+
+gofmt -w internal/controller
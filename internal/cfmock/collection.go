@@ -0,0 +1,463 @@
+package cfmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const defaultPerPage = 20
+
+// A collection is a kind of object served with the usual list, create, get,
+// replace, patch and delete endpoints.
+type collection struct {
+	// pattern is the path of the collection relative to the base URL, with
+	// {name} wildcards for the identifiers of the objects it belongs to.
+	pattern string
+
+	// key is the field objects are identified by in their path.
+	key string
+
+	// parent is the pattern of the path of the object the collection
+	// belongs to, which must exist. The collection may belong to anything
+	// if it is empty.
+	parent string
+
+	// notFound is returned for objects that do not exist.
+	notFound Error
+
+	// conflict is returned when an object would duplicate another one, as
+	// decided by duplicate.
+	conflict  Error
+	duplicate func(existing, obj Object) bool
+
+	// create validates a new object and fills in the fields the API
+	// computes. params holds the values of the wildcards of the pattern and
+	// parent is the object the collection belongs to, if any.
+	create func(s *Server, params map[string]string, parent, obj Object) *Error
+
+	// update validates a replaced or patched object and fills in the
+	// fields the API computes.
+	update func(s *Server, params map[string]string, parent, obj Object) *Error
+
+	// readOnly fields are kept when an object is replaced or patched.
+	readOnly []string
+
+	// hidden fields are stored but never returned.
+	hidden []string
+
+	// listKey wraps the list result in an object under the supplied key
+	// instead of returning a paginated array.
+	listKey string
+
+	// deleted returns the result of a deletion. The result holds the key of
+	// the object if it is nil.
+	deleted func(s *Server, obj Object) any
+}
+
+// wildcards returns the names of the wildcards of the supplied pattern.
+func wildcards(pattern string) []string {
+	var names []string
+	for _, seg := range strings.Split(pattern, "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			names = append(names, strings.Trim(seg, "{}"))
+		}
+	}
+	return names
+}
+
+// expand replaces the wildcards of the supplied pattern with their values.
+func expand(pattern string, params map[string]string) string {
+	for k, v := range params {
+		pattern = strings.ReplaceAll(pattern, "{"+k+"}", v)
+	}
+	return pattern
+}
+
+// match reports whether the supplied path matches the pattern, returning the
+// values of its wildcards if it does.
+func match(pattern, p string) (map[string]string, bool) {
+	ps, segs := strings.Split(pattern, "/"), strings.Split(p, "/")
+	if len(ps) != len(segs) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, seg := range ps {
+		switch {
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}"):
+			if segs[i] == "" {
+				return nil, false
+			}
+			params[strings.Trim(seg, "{}")] = segs[i]
+		case seg != segs[i]:
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (s *Server) handleCollection(c *collection) {
+	item := c.pattern + "/{" + c.key + "}"
+	s.mux.HandleFunc("GET "+APIPrefix+c.pattern, s.withParams(c, c.list))
+	s.mux.HandleFunc("POST "+APIPrefix+c.pattern, s.withParams(c, c.post))
+	s.mux.HandleFunc("GET "+APIPrefix+item, s.withParams(c, c.get))
+	s.mux.HandleFunc("PUT "+APIPrefix+item, s.withParams(c, c.put))
+	s.mux.HandleFunc("PATCH "+APIPrefix+item, s.withParams(c, c.patch))
+	s.mux.HandleFunc("DELETE "+APIPrefix+item, s.withParams(c, c.delete))
+	s.collections = append(s.collections, c)
+}
+
+type collectionHandler func(s *Server, w http.ResponseWriter, r *http.Request, params map[string]string)
+
+// withParams serves a collection endpoint with the values of the wildcards
+// of the request path while holding the state lock.
+func (s *Server) withParams(c *collection, h collectionHandler) http.HandlerFunc {
+	names := append(wildcards(c.pattern), c.key)
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := map[string]string{}
+		for _, n := range names {
+			if v := r.PathValue(n); v != "" {
+				params[n] = v
+			}
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		h(s, w, r, params)
+	}
+}
+
+// parentOf returns the object the collection belongs to, or the not found
+// error of its collection if it does not exist.
+func (c *collection) parentOf(s *Server, params map[string]string) (Object, *Error) {
+	if c.parent == "" {
+		return nil, nil
+	}
+	p := expand(c.parent, params)
+	pc := s.collectionOf(path.Dir(p))
+	obj, ok := s.find(path.Dir(p), pc.key, path.Base(p))
+	if !ok {
+		return nil, &pc.notFound
+	}
+	return obj, nil
+}
+
+func (c *collection) list(s *Server, w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if _, err := c.parentOf(s, params); err != nil {
+		writeErrors(w, http.StatusNotFound, *err)
+		return
+	}
+	q := r.URL.Query()
+	items := []Object{}
+	for _, obj := range s.objects[expand(c.pattern, params)] {
+		if filtered(obj, q) {
+			items = append(items, c.visible(obj))
+		}
+	}
+	if c.listKey != "" {
+		writeResult(w, http.StatusOK, Object{c.listKey: items})
+		return
+	}
+	page, perPage := intParam(q, "page", 1), intParam(q, "per_page", defaultPerPage)
+	total := len(items)
+	start, end := min((page-1)*perPage, total), min(page*perPage, total)
+	writeList(w, items[start:end], resultInfo{
+		Page:       page,
+		PerPage:    perPage,
+		Count:      end - start,
+		TotalCount: total,
+		TotalPages: (total + perPage - 1) / perPage,
+	})
+}
+
+func (c *collection) post(s *Server, w http.ResponseWriter, r *http.Request, params map[string]string) {
+	obj := Object{}
+	if err := readJSON(r, &obj); err != nil {
+		writeErrors(w, http.StatusBadRequest, *err)
+		return
+	}
+	created, status, err := s.insert(c, params, obj)
+	if err != nil {
+		writeErrors(w, status, *err)
+		return
+	}
+	writeResult(w, http.StatusOK, c.visible(created))
+}
+
+// insert validates and stores a new object of the collection.
+func (s *Server) insert(c *collection, params map[string]string, obj Object) (Object, int, *Error) {
+	parent, err := c.parentOf(s, params)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	if c.create != nil {
+		if err := c.create(s, params, parent, obj); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	}
+	p := expand(c.pattern, params)
+	for _, existing := range s.objects[p] {
+		if (obj[c.key] != nil && existing[c.key] == obj[c.key]) || (c.duplicate != nil && c.duplicate(existing, obj)) {
+			return nil, http.StatusConflict, &c.conflict
+		}
+	}
+	s.objects[p] = append(s.objects[p], clone(obj))
+	return obj, http.StatusOK, nil
+}
+
+func (c *collection) get(s *Server, w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	obj, ok := s.find(expand(c.pattern, params), c.key, params[c.key])
+	if !ok {
+		writeErrors(w, http.StatusNotFound, c.notFound)
+		return
+	}
+	writeResult(w, http.StatusOK, c.visible(obj))
+}
+
+func (c *collection) put(s *Server, w http.ResponseWriter, r *http.Request, params map[string]string) {
+	c.modify(s, w, r, params, func(_, body Object) Object { return body })
+}
+
+func (c *collection) patch(s *Server, w http.ResponseWriter, r *http.Request, params map[string]string) {
+	c.modify(s, w, r, params, func(cur, body Object) Object {
+		for k, v := range body {
+			cur[k] = v
+		}
+		return cur
+	})
+}
+
+// modify replaces an object with the result of merging the request body into
+// it, keeping its read-only fields.
+func (c *collection) modify(s *Server, w http.ResponseWriter, r *http.Request, params map[string]string, merge func(cur, body Object) Object) {
+	p := expand(c.pattern, params)
+	cur, ok := s.find(p, c.key, params[c.key])
+	if !ok {
+		writeErrors(w, http.StatusNotFound, c.notFound)
+		return
+	}
+	body := Object{}
+	if err := readJSON(r, &body); err != nil {
+		writeErrors(w, http.StatusBadRequest, *err)
+		return
+	}
+	obj := merge(clone(cur), body)
+	for _, k := range append([]string{c.key}, c.readOnly...) {
+		if v, ok := cur[k]; ok {
+			obj[k] = v
+		}
+	}
+	if c.update != nil {
+		parent, _ := c.parentOf(s, params)
+		if err := c.update(s, params, parent, obj); err != nil {
+			writeErrors(w, http.StatusBadRequest, *err)
+			return
+		}
+	}
+	for i, existing := range s.objects[p] {
+		if existing[c.key] == params[c.key] {
+			s.objects[p][i] = clone(obj)
+		}
+	}
+	writeResult(w, http.StatusOK, c.visible(obj))
+}
+
+func (c *collection) delete(s *Server, w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	p := expand(c.pattern, params)
+	obj, ok := s.find(p, c.key, params[c.key])
+	if !ok {
+		writeErrors(w, http.StatusNotFound, c.notFound)
+		return
+	}
+	s.remove(p + "/" + params[c.key])
+	var result any = Object{c.key: obj[c.key]}
+	if c.deleted != nil {
+		result = c.deleted(s, obj)
+	}
+	writeResult(w, http.StatusOK, result)
+}
+
+// remove deletes the object at the supplied path and everything that belongs
+// to it.
+func (s *Server) remove(p string) {
+	dir, id := path.Dir(p), path.Base(p)
+	key := s.collectionOf(dir).key
+	kept := s.objects[dir][:0]
+	for _, obj := range s.objects[dir] {
+		if obj[key] != id {
+			kept = append(kept, obj)
+		}
+	}
+	s.objects[dir] = kept
+	for k := range s.objects {
+		if strings.HasPrefix(k, p+"/") {
+			delete(s.objects, k)
+		}
+	}
+	for k := range s.kv {
+		if k == p || strings.HasPrefix(k, p+"/") {
+			delete(s.kv, k)
+		}
+	}
+	for k := range s.tunnelCfgs {
+		if k == p || strings.HasPrefix(k, p+"/") {
+			delete(s.tunnelCfgs, k)
+		}
+	}
+}
+
+// find returns the object of the collection at the supplied path whose key
+// has the supplied value.
+func (s *Server) find(p, key, id string) (Object, bool) {
+	for _, obj := range s.objects[p] {
+		if obj[key] == id {
+			return clone(obj), true
+		}
+	}
+	return nil, false
+}
+
+// collectionOf returns the collection the supplied collection path belongs
+// to. It returns an empty collection keyed by ID if there is none.
+func (s *Server) collectionOf(p string) *collection {
+	for _, c := range s.collections {
+		if _, ok := match(c.pattern, p); ok {
+			return c
+		}
+	}
+	return &collection{key: "id", notFound: Error{Code: 7003, Message: "Could not route to " + p + ", perhaps your object identifier is invalid?"}}
+}
+
+func (c *collection) visible(obj Object) Object {
+	out := clone(obj)
+	for _, k := range c.hidden {
+		delete(out, k)
+	}
+	return out
+}
+
+// Seed stores an object in the collection at the supplied path, relative to
+// the base URL, as if it had been created through the API. It returns the
+// object including the fields the API computes.
+func (s *Server) Seed(collectionPath string, obj Object) (Object, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.collections {
+		params, ok := match(c.pattern, collectionPath)
+		if !ok {
+			continue
+		}
+		created, _, err := s.insert(c, params, clone(obj))
+		if err != nil {
+			return nil, errors.Errorf("cannot seed %s: error %d: %s", collectionPath, err.Code, err.Message)
+		}
+		return created, nil
+	}
+	return nil, errors.Errorf("no collection at %s", collectionPath)
+}
+
+// Object returns the object at the supplied path, relative to the base URL,
+// for example "/zones/<zone id>". Hidden fields like tunnel secrets are
+// included.
+func (s *Server) Object(p string) (Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.find(path.Dir(p), s.collectionOf(path.Dir(p)).key, path.Base(p))
+}
+
+// Objects returns the objects of the collection at the supplied path,
+// relative to the base URL, in the order they were created.
+func (s *Server) Objects(collectionPath string) []Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	objs := make([]Object, 0, len(s.objects[collectionPath]))
+	for _, obj := range s.objects[collectionPath] {
+		objs = append(objs, clone(obj))
+	}
+	return objs
+}
+
+// filterParams are query parameters that never filter a list.
+var filterParams = map[string]bool{
+	"page": true, "per_page": true, "order": true, "direction": true, "match": true, "cursor": true, "limit": true,
+}
+
+// filtered reports whether an object passes the filters of a list request.
+// A filter applies to the field of its name, for example name or account.id,
+// and may use the .exact, .contains, .startswith and .endswith operators.
+// Filters on fields the object does not have are ignored.
+func filtered(obj Object, q map[string][]string) bool {
+	for k, vs := range q {
+		if filterParams[k] || len(vs) == 0 {
+			continue
+		}
+		field, op := k, "exact"
+		if i := strings.LastIndex(k, "."); i > 0 {
+			switch k[i+1:] {
+			case "exact", "contains", "startswith", "endswith":
+				field, op = k[:i], k[i+1:]
+			}
+		}
+		v, ok := lookup(obj, field)
+		if !ok {
+			continue
+		}
+		got, want := fmt.Sprint(v), vs[0]
+		var pass bool
+		switch op {
+		case "contains":
+			pass = strings.Contains(got, want)
+		case "startswith":
+			pass = strings.HasPrefix(got, want)
+		case "endswith":
+			pass = strings.HasSuffix(got, want)
+		default:
+			pass = got == want
+		}
+		if !pass {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the value of a dotted field path of an object.
+func lookup(obj Object, field string) (any, bool) {
+	var cur any = map[string]any(obj)
+	for _, part := range strings.Split(field, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func intParam(q map[string][]string, name string, def int) int {
+	if vs := q[name]; len(vs) > 0 {
+		if n, err := strconv.Atoi(vs[0]); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
+}
+
+// clone deep copies an object.
+func clone(obj Object) Object {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		panic(err)
+	}
+	out := Object{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		panic(err)
+	}
+	return out
+}
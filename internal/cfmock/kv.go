@@ -0,0 +1,244 @@
+package cfmock
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const kvNamespacePath = "/accounts/{account_id}/storage/kv/namespaces/{id}"
+
+// A kvPair is a value stored in a Workers KV namespace.
+type kvPair struct {
+	value      []byte
+	metadata   json.RawMessage
+	expiration int64
+}
+
+var errKeyNotFound = Error{Code: 10009, Message: "get: 'key not found'"}
+
+// kvRoutes registers the endpoints of the values of Workers KV namespaces.
+// Key names may contain slashes, escaped or not.
+func (s *Server) kvRoutes() {
+	s.mux.HandleFunc("GET "+APIPrefix+kvNamespacePath+"/keys", s.withNamespace(s.listKVKeys))
+	s.mux.HandleFunc("GET "+APIPrefix+kvNamespacePath+"/values/{key...}", s.withNamespace(s.getKVValue))
+	s.mux.HandleFunc("PUT "+APIPrefix+kvNamespacePath+"/values/{key...}", s.withNamespace(s.putKVValue))
+	s.mux.HandleFunc("DELETE "+APIPrefix+kvNamespacePath+"/values/{key...}", s.withNamespace(s.deleteKVValue))
+	s.mux.HandleFunc("GET "+APIPrefix+kvNamespacePath+"/metadata/{key...}", s.withNamespace(s.getKVMetadata))
+	s.mux.HandleFunc("PUT "+APIPrefix+kvNamespacePath+"/bulk", s.withNamespace(s.putKVBulk))
+	s.mux.HandleFunc("POST "+APIPrefix+kvNamespacePath+"/bulk/delete", s.withNamespace(s.deleteKVBulk))
+	s.mux.HandleFunc("DELETE "+APIPrefix+kvNamespacePath+"/bulk", s.withNamespace(s.deleteKVBulk))
+	s.mux.HandleFunc("POST "+APIPrefix+kvNamespacePath+"/bulk/get", s.withNamespace(s.getKVBulk))
+}
+
+type kvHandler func(w http.ResponseWriter, r *http.Request, pairs map[string]kvPair)
+
+// withNamespace serves a request for the values of a KV namespace while
+// holding the state lock, writing a not found error if the namespace does
+// not exist.
+func (s *Server) withNamespace(h kvHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		collPath := "/accounts/" + r.PathValue("account_id") + "/storage/kv/namespaces"
+		c := s.collectionOf(collPath)
+		if _, ok := s.find(collPath, c.key, r.PathValue("id")); !ok {
+			writeErrors(w, http.StatusNotFound, c.notFound)
+			return
+		}
+		p := collPath + "/" + r.PathValue("id")
+		if s.kv[p] == nil {
+			s.kv[p] = map[string]kvPair{}
+		}
+		h(w, r, s.kv[p])
+	}
+}
+
+func (s *Server) listKVKeys(w http.ResponseWriter, r *http.Request, pairs map[string]kvPair) {
+	q := r.URL.Query()
+	names := make([]string, 0, len(pairs))
+	for k := range pairs {
+		if strings.HasPrefix(k, q.Get("prefix")) {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	start := intParam(q, "cursor", 0)
+	end := min(start+intParam(q, "limit", 1000), len(names))
+	start = min(start, end)
+	keys := make([]Object, 0, end-start)
+	for _, k := range names[start:end] {
+		key := Object{"name": k}
+		if p := pairs[k]; p.expiration > 0 {
+			key["expiration"] = p.expiration
+		}
+		if p := pairs[k]; p.metadata != nil {
+			key["metadata"] = p.metadata
+		}
+		keys = append(keys, key)
+	}
+	info := resultInfo{Count: len(keys), TotalCount: len(names)}
+	if end < len(names) {
+		info.Cursor = strconv.Itoa(end)
+	}
+	writeList(w, keys, info)
+}
+
+// getKVValue serves the raw value of a key rather than a JSON envelope.
+func (s *Server) getKVValue(w http.ResponseWriter, r *http.Request, pairs map[string]kvPair) {
+	p, ok := pairs[r.PathValue("key")]
+	if !ok {
+		writeErrors(w, http.StatusNotFound, errKeyNotFound)
+		return
+	}
+	if p.expiration > 0 {
+		w.Header().Set("Expiration", strconv.FormatInt(p.expiration, 10))
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(p.value)
+}
+
+// putKVValue stores the value of a key, which is either sent as the value
+// field of a multipart form along with its metadata, or as the raw body.
+func (s *Server) putKVValue(w http.ResponseWriter, r *http.Request, pairs map[string]kvPair) {
+	var p kvPair
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mt == "multipart/form-data" {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			writeErrors(w, http.StatusBadRequest, Error{Code: 10026, Message: "could not parse request body: " + err.Error()})
+			return
+		}
+		if f, _, err := r.FormFile("value"); err == nil {
+			p.value, _ = io.ReadAll(f)
+			_ = f.Close()
+		} else {
+			p.value = []byte(r.FormValue("value"))
+		}
+		if m := r.FormValue("metadata"); m != "" {
+			if !json.Valid([]byte(m)) {
+				writeErrors(w, http.StatusBadRequest, Error{Code: 10024, Message: "metadata must be valid JSON"})
+				return
+			}
+			p.metadata = json.RawMessage(m)
+		}
+	} else {
+		p.value, _ = io.ReadAll(r.Body)
+	}
+	p.expiration = s.kvExpiration(r.URL.Query().Get("expiration"), r.URL.Query().Get("expiration_ttl"))
+	pairs[r.PathValue("key")] = p
+	writeResult(w, http.StatusOK, Object{})
+}
+
+func (s *Server) deleteKVValue(w http.ResponseWriter, r *http.Request, pairs map[string]kvPair) {
+	delete(pairs, r.PathValue("key"))
+	writeResult(w, http.StatusOK, Object{})
+}
+
+func (s *Server) getKVMetadata(w http.ResponseWriter, r *http.Request, pairs map[string]kvPair) {
+	p, ok := pairs[r.PathValue("key")]
+	if !ok {
+		writeErrors(w, http.StatusNotFound, errKeyNotFound)
+		return
+	}
+	writeResult(w, http.StatusOK, p.metadata)
+}
+
+// putKVBulk stores up to 10,000 key-value pairs.
+func (s *Server) putKVBulk(w http.ResponseWriter, r *http.Request, pairs map[string]kvPair) {
+	var items []struct {
+		Key           string          `json:"key"`
+		Value         string          `json:"value"`
+		Base64        bool            `json:"base64"`
+		Metadata      json.RawMessage `json:"metadata"`
+		Expiration    json.Number     `json:"expiration"`
+		ExpirationTTL json.Number     `json:"expiration_ttl"`
+	}
+	if err := readJSON(r, &items); err != nil {
+		writeErrors(w, http.StatusBadRequest, *err)
+		return
+	}
+	if len(items) > 10000 {
+		writeErrors(w, http.StatusBadRequest, Error{Code: 10020, Message: "too many keys in bulk request"})
+		return
+	}
+	for _, it := range items {
+		p := kvPair{value: []byte(it.Value), metadata: it.Metadata, expiration: s.kvExpiration(it.Expiration.String(), it.ExpirationTTL.String())}
+		if it.Base64 {
+			v, err := base64.StdEncoding.DecodeString(it.Value)
+			if err != nil {
+				writeErrors(w, http.StatusBadRequest, Error{Code: 10021, Message: "value of key " + it.Key + " is not valid base64"})
+				return
+			}
+			p.value = v
+		}
+		pairs[it.Key] = p
+	}
+	writeResult(w, http.StatusOK, Object{"successful_key_count": len(items), "unsuccessful_keys": []string{}})
+}
+
+// deleteKVBulk deletes up to 10,000 keys.
+func (s *Server) deleteKVBulk(w http.ResponseWriter, r *http.Request, pairs map[string]kvPair) {
+	var keys []string
+	if err := readJSON(r, &keys); err != nil {
+		writeErrors(w, http.StatusBadRequest, *err)
+		return
+	}
+	for _, k := range keys {
+		delete(pairs, k)
+	}
+	writeResult(w, http.StatusOK, Object{"successful_key_count": len(keys), "unsuccessful_keys": []string{}})
+}
+
+func (s *Server) getKVBulk(w http.ResponseWriter, r *http.Request, pairs map[string]kvPair) {
+	var body struct {
+		Keys         []string `json:"keys"`
+		Type         string   `json:"type"`
+		WithMetadata bool     `json:"withMetadata"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		writeErrors(w, http.StatusBadRequest, *err)
+		return
+	}
+	values := Object{}
+	for _, k := range body.Keys {
+		p, ok := pairs[k]
+		if !ok {
+			values[k] = nil
+			continue
+		}
+		var v any = string(p.value)
+		if body.Type == "json" && json.Valid(p.value) {
+			v = json.RawMessage(p.value)
+		}
+		if body.WithMetadata {
+			v = Object{"value": v, "metadata": p.metadata}
+		}
+		values[k] = v
+	}
+	writeResult(w, http.StatusOK, Object{"values": values})
+}
+
+// kvExpiration returns the absolute expiration of a value, in seconds since
+// the epoch, from either an absolute expiration or a TTL in seconds.
+func (s *Server) kvExpiration(expiration, ttl string) int64 {
+	if n, err := strconv.ParseInt(expiration, 10, 64); err == nil && n > 0 {
+		return n
+	}
+	if n, err := strconv.ParseInt(ttl, 10, 64); err == nil && n > 0 {
+		return s.now().Unix() + n
+	}
+	return 0
+}
+
+// KVValue returns the value of a key of a Workers KV namespace.
+func (s *Server) KVValue(accountID, namespaceID, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.kv["/accounts/"+accountID+"/storage/kv/namespaces/"+namespaceID][key]
+	return p.value, ok
+}
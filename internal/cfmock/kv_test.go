@@ -0,0 +1,99 @@
+package cfmock
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestKV(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	const account = "0123456789abcdef0123456789abcdef"
+	ns, err := s.Seed("/accounts/"+account+"/storage/kv/namespaces", Object{"title": "config"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := "/accounts/" + account + "/storage/kv/namespaces/" + ns["id"].(string)
+
+	if got := do(t, s, http.MethodPut, base+"/values/feature/flags", []byte("on")).Status; got != http.StatusOK {
+		t.Fatalf("PUT value: want status 200, got %d", got)
+	}
+	bulk := []Object{
+		{"key": "greeting", "value": "aGVsbG8=", "base64": true},
+		{"key": "limit", "value": "10", "metadata": Object{"owner": "team"}},
+	}
+	if got := do(t, s, http.MethodPut, base+"/bulk", bulk).Status; got != http.StatusOK {
+		t.Fatalf("PUT bulk: want status 200, got %d", got)
+	}
+
+	t.Run("Value", func(t *testing.T) {
+		resp := do(t, s, http.MethodGet, base+"/values/feature/flags", nil)
+		if resp.Status != http.StatusOK || string(resp.Result) != "on" {
+			t.Errorf("GET value: want 200 on, got %d %s", resp.Status, resp.Result)
+		}
+		if v, ok := s.KVValue(account, ns["id"].(string), "greeting"); !ok || string(v) != "hello" {
+			t.Errorf("KVValue(greeting): want decoded base64 value hello, got %q", v)
+		}
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		resp := do(t, s, http.MethodGet, base+"/metadata/limit", nil)
+		if diff := cmp.Diff(`{"owner":"team"}`, string(resp.Result)); diff != "" {
+			t.Errorf("GET metadata: -want, +got:\n%s", diff)
+		}
+	})
+
+	t.Run("ListKeysWithCursor", func(t *testing.T) {
+		var names []string
+		cursor := ""
+		for range 5 {
+			resp := do(t, s, http.MethodGet, base+"/keys?limit=2&cursor="+cursor, nil)
+			var keys []Object
+			if err := json.Unmarshal(resp.Result, &keys); err != nil {
+				t.Fatalf("cannot decode keys: %v", err)
+			}
+			for _, k := range keys {
+				names = append(names, k["name"].(string))
+			}
+			if cursor = resp.ResultInfo.Cursor; cursor == "" {
+				break
+			}
+		}
+		if diff := cmp.Diff([]string{"feature/flags", "greeting", "limit"}, names); diff != "" {
+			t.Errorf("keys: -want, +got:\n%s", diff)
+		}
+	})
+
+	t.Run("BulkDelete", func(t *testing.T) {
+		if got := do(t, s, http.MethodPost, base+"/bulk/delete", []string{"greeting", "missing"}).Status; got != http.StatusOK {
+			t.Fatalf("POST bulk/delete: want status 200, got %d", got)
+		}
+		if _, ok := s.KVValue(account, ns["id"].(string), "greeting"); ok {
+			t.Error("KVValue(greeting): want deleted key to be gone")
+		}
+		resp := do(t, s, http.MethodGet, base+"/values/greeting", nil)
+		if resp.Status != http.StatusNotFound || len(resp.Errors) != 1 || resp.Errors[0].Code != errKeyNotFound.Code {
+			t.Errorf("GET deleted value: want 404 with error %d, got %d %v", errKeyNotFound.Code, resp.Status, resp.Errors)
+		}
+	})
+
+	t.Run("UnknownNamespace", func(t *testing.T) {
+		p := strings.Replace(base, ns["id"].(string), "ffffffffffffffffffffffffffffffff", 1)
+		if got := do(t, s, http.MethodGet, p+"/keys", nil).Status; got != http.StatusNotFound {
+			t.Errorf("GET keys of unknown namespace: want 404, got %d", got)
+		}
+	})
+
+	t.Run("DeletingNamespaceDeletesValues", func(t *testing.T) {
+		if got := do(t, s, http.MethodDelete, base, nil).Status; got != http.StatusOK {
+			t.Fatalf("DELETE namespace: want status 200, got %d", got)
+		}
+		if _, ok := s.KVValue(account, ns["id"].(string), "limit"); ok {
+			t.Error("KVValue(limit): want values of deleted namespace to be gone")
+		}
+	})
+}
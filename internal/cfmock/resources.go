@@ -0,0 +1,319 @@
+package cfmock
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// routes registers the endpoints of every emulated resource.
+func (s *Server) routes() {
+	s.handleCollection(zones())
+	s.handleCollection(dnsRecords())
+	s.handleCollection(tunnels())
+	s.handleCollection(accessApplications("/accounts/{account_id}/access/apps", ""))
+	s.handleCollection(accessApplications("/zones/{zone_id}/access/apps", "/zones/{zone_id}"))
+	s.handleCollection(kvNamespaces())
+	s.handleCollection(r2Buckets())
+
+	s.mux.HandleFunc("GET "+APIPrefix+"/accounts/{account_id}/cfd_tunnel/{id}/token", s.tunnelToken)
+	s.mux.HandleFunc("GET "+APIPrefix+"/accounts/{account_id}/cfd_tunnel/{id}/configurations", s.getTunnelConfiguration)
+	s.mux.HandleFunc("PUT "+APIPrefix+"/accounts/{account_id}/cfd_tunnel/{id}/configurations", s.putTunnelConfiguration)
+	s.kvRoutes()
+
+	s.mux.HandleFunc("GET "+APIPrefix+"/user/tokens/verify", func(w http.ResponseWriter, _ *http.Request) {
+		writeResult(w, http.StatusOK, Object{"id": "00000000000000000000000000000000", "status": "active"})
+	})
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		writeErrors(w, http.StatusNotFound, Error{Code: 7000, Message: "No route for that URI"})
+	})
+}
+
+func zones() *collection {
+	return &collection{
+		pattern:  "/zones",
+		key:      "id",
+		notFound: Error{Code: 1001, Message: "Invalid zone identifier"},
+		conflict: Error{Code: 1061, Message: "Zone already exists"},
+		duplicate: func(existing, obj Object) bool {
+			return existing["name"] == obj["name"]
+		},
+		create: func(s *Server, _ map[string]string, _, obj Object) *Error {
+			name, _ := obj["name"].(string)
+			if name == "" {
+				return &Error{Code: 1097, Message: "name is required"}
+			}
+			obj["id"] = s.newID()
+			obj["name"] = strings.ToLower(strings.TrimSuffix(name, "."))
+			setDefault(obj, "type", "full")
+			setDefault(obj, "account", Object{"id": ""})
+			setDefault(obj, "paused", false)
+			setDefault(obj, "development_mode", 0)
+			obj["status"] = "pending"
+			obj["name_servers"] = []string{"ada.ns.cloudflare.com", "bob.ns.cloudflare.com"}
+			obj["original_name_servers"] = []string{}
+			obj["activated_on"] = nil
+			obj["meta"] = Object{}
+			obj["owner"] = Object{}
+			now := s.timestamp()
+			obj["created_on"], obj["modified_on"] = now, now
+			return nil
+		},
+		update: func(s *Server, _ map[string]string, _, obj Object) *Error {
+			obj["modified_on"] = s.timestamp()
+			return nil
+		},
+		readOnly: []string{"name", "account", "status", "name_servers", "original_name_servers", "activated_on", "meta", "owner", "created_on"},
+	}
+}
+
+// proxiableTypes are the DNS record types that may be proxied.
+var proxiableTypes = map[string]bool{"A": true, "AAAA": true, "CNAME": true}
+
+func dnsRecords() *collection {
+	normalize := func(s *Server, zone, obj Object) *Error {
+		typ, _ := obj["type"].(string)
+		name, _ := obj["name"].(string)
+		if typ == "" || name == "" {
+			return &Error{Code: 9000, Message: "DNS record type and name are required"}
+		}
+		if _, ok := obj["content"]; !ok {
+			if _, ok := obj["data"]; !ok {
+				return &Error{Code: 9005, Message: "Content for " + typ + " record is invalid"}
+			}
+		}
+		// Like the API, qualify names relative to the zone.
+		zoneName, _ := zone["name"].(string)
+		switch name = strings.ToLower(strings.TrimSuffix(name, ".")); {
+		case name == "@":
+			name = zoneName
+		case name != zoneName && !strings.HasSuffix(name, "."+zoneName):
+			name += "." + zoneName
+		}
+		obj["name"] = name
+		setDefault(obj, "ttl", 1)
+		setDefault(obj, "proxied", false)
+		setDefault(obj, "comment", nil)
+		setDefault(obj, "tags", []string{})
+		setDefault(obj, "settings", Object{})
+		obj["proxiable"] = proxiableTypes[typ]
+		if proxied, _ := obj["proxied"].(bool); proxied && !proxiableTypes[typ] {
+			return &Error{Code: 9004, Message: "This record type cannot be proxied."}
+		}
+		obj["modified_on"] = s.timestamp()
+		return nil
+	}
+	return &collection{
+		pattern:  "/zones/{zone_id}/dns_records",
+		key:      "id",
+		parent:   "/zones/{zone_id}",
+		notFound: Error{Code: 81044, Message: "Record does not exist."},
+		conflict: Error{Code: 81058, Message: "An identical record already exists."},
+		duplicate: func(existing, obj Object) bool {
+			return existing["type"] == obj["type"] && existing["name"] == obj["name"] && fmt.Sprint(existing["content"]) == fmt.Sprint(obj["content"])
+		},
+		create: func(s *Server, _ map[string]string, zone, obj Object) *Error {
+			obj["id"] = s.newID()
+			obj["created_on"] = s.timestamp()
+			obj["meta"] = Object{}
+			return normalize(s, zone, obj)
+		},
+		update: func(s *Server, _ map[string]string, zone, obj Object) *Error {
+			return normalize(s, zone, obj)
+		},
+		readOnly: []string{"created_on", "meta"},
+	}
+}
+
+func tunnels() *collection {
+	return &collection{
+		pattern:  "/accounts/{account_id}/cfd_tunnel",
+		key:      "id",
+		notFound: Error{Code: 1003, Message: "Tunnel not found"},
+		conflict: Error{Code: 1013, Message: "You already have a tunnel with this name"},
+		duplicate: func(existing, obj Object) bool {
+			return existing["name"] == obj["name"]
+		},
+		create: func(s *Server, params map[string]string, _, obj Object) *Error {
+			if name, _ := obj["name"].(string); name == "" {
+				return &Error{Code: 1001, Message: "Tunnel name is required"}
+			}
+			obj["id"] = s.newUUID()
+			if secret, _ := obj["tunnel_secret"].(string); secret == "" {
+				obj["tunnel_secret"] = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%032x", s.seq)))
+			}
+			setDefault(obj, "config_src", "local")
+			obj["remote_config"] = obj["config_src"] == "cloudflare"
+			obj["account_tag"] = params["account_id"]
+			obj["tun_type"] = "cfd_tunnel"
+			obj["status"] = "inactive"
+			obj["connections"] = []Object{}
+			obj["conns_active_at"] = nil
+			obj["conns_inactive_at"] = nil
+			obj["created_at"] = s.timestamp()
+			obj["deleted_at"] = nil
+			return nil
+		},
+		readOnly: []string{"account_tag", "tun_type", "status", "connections", "conns_active_at", "conns_inactive_at", "created_at", "deleted_at", "config_src", "remote_config", "tunnel_secret"},
+		hidden:   []string{"tunnel_secret"},
+		deleted: func(s *Server, obj Object) any {
+			out := clone(obj)
+			delete(out, "tunnel_secret")
+			out["deleted_at"] = s.timestamp()
+			return out
+		},
+	}
+}
+
+func accessApplications(pattern, parent string) *collection {
+	return &collection{
+		pattern:  pattern,
+		key:      "id",
+		parent:   parent,
+		notFound: Error{Code: 12130, Message: "access.api.error.not_found: application not found"},
+		create: func(s *Server, _ map[string]string, _, obj Object) *Error {
+			setDefault(obj, "type", "self_hosted")
+			if obj["type"] == "self_hosted" {
+				if domain, _ := obj["domain"].(string); domain == "" {
+					return &Error{Code: 12130, Message: "access.api.error.invalid_request: domain is required"}
+				}
+			}
+			obj["id"] = s.newUUID()
+			obj["aud"] = fmt.Sprintf("%064x", s.seq)
+			setDefault(obj, "session_duration", "24h")
+			now := s.timestamp()
+			obj["created_at"], obj["updated_at"] = now, now
+			return nil
+		},
+		update: func(s *Server, _ map[string]string, _, obj Object) *Error {
+			obj["updated_at"] = s.timestamp()
+			return nil
+		},
+		readOnly: []string{"aud", "created_at"},
+	}
+}
+
+func kvNamespaces() *collection {
+	return &collection{
+		pattern:  "/accounts/{account_id}/storage/kv/namespaces",
+		key:      "id",
+		notFound: Error{Code: 10013, Message: "namespace not found"},
+		conflict: Error{Code: 10014, Message: "a namespace with this account ID and title already exists"},
+		duplicate: func(existing, obj Object) bool {
+			return existing["title"] == obj["title"]
+		},
+		create: func(s *Server, _ map[string]string, _, obj Object) *Error {
+			if title, _ := obj["title"].(string); title == "" {
+				return &Error{Code: 10019, Message: "title is required"}
+			}
+			obj["id"] = s.newID()
+			obj["supports_url_encoding"] = true
+			return nil
+		},
+		readOnly: []string{"supports_url_encoding"},
+	}
+}
+
+func r2Buckets() *collection {
+	return &collection{
+		pattern:  "/accounts/{account_id}/r2/buckets",
+		key:      "name",
+		notFound: Error{Code: 10006, Message: "The specified bucket does not exist."},
+		conflict: Error{Code: 10004, Message: "The bucket you tried to create already exists, and you own it."},
+		create: func(s *Server, _ map[string]string, _, obj Object) *Error {
+			if name, _ := obj["name"].(string); name == "" {
+				return &Error{Code: 10005, Message: "The specified bucket name is not valid."}
+			}
+			location := "ENAM"
+			if hint, _ := obj["locationHint"].(string); hint != "" {
+				location = strings.ToUpper(hint)
+			}
+			delete(obj, "locationHint")
+			obj["location"] = location
+			setDefault(obj, "storage_class", "Standard")
+			obj["creation_date"] = s.timestamp()
+			return nil
+		},
+		readOnly: []string{"location", "creation_date"},
+		listKey:  "buckets",
+		deleted: func(*Server, Object) any {
+			return Object{}
+		},
+	}
+}
+
+// tunnelPath returns the path of the tunnel a request is about, writing a not
+// found error if it does not exist.
+func (s *Server) tunnelPath(w http.ResponseWriter, r *http.Request) (Object, string, bool) {
+	collPath := "/accounts/" + r.PathValue("account_id") + "/cfd_tunnel"
+	c := s.collectionOf(collPath)
+	obj, ok := s.find(collPath, c.key, r.PathValue("id"))
+	if !ok {
+		writeErrors(w, http.StatusNotFound, c.notFound)
+		return nil, "", false
+	}
+	return obj, collPath + "/" + r.PathValue("id"), true
+}
+
+// tunnelToken serves the token cloudflared runs a tunnel with, which encodes
+// the account tag, tunnel ID and tunnel secret.
+func (s *Server) tunnelToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, _, ok := s.tunnelPath(w, r)
+	if !ok {
+		return
+	}
+	data, _ := json.Marshal(map[string]any{"a": obj["account_tag"], "t": obj["id"], "s": obj["tunnel_secret"]})
+	writeResult(w, http.StatusOK, base64.StdEncoding.EncodeToString(data))
+}
+
+func (s *Server) getTunnelConfiguration(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, p, ok := s.tunnelPath(w, r)
+	if !ok {
+		return
+	}
+	cfg, ok := s.tunnelCfgs[p]
+	if !ok {
+		cfg = Object{"account_id": r.PathValue("account_id"), "tunnel_id": obj["id"], "config": nil, "source": "cloudflare", "version": 0}
+	}
+	writeResult(w, http.StatusOK, cfg)
+}
+
+func (s *Server) putTunnelConfiguration(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, p, ok := s.tunnelPath(w, r)
+	if !ok {
+		return
+	}
+	body := Object{}
+	if err := readJSON(r, &body); err != nil {
+		writeErrors(w, http.StatusBadRequest, *err)
+		return
+	}
+	version := 1
+	if prev, ok := s.tunnelCfgs[p]; ok {
+		version = prev["version"].(int) + 1
+	}
+	cfg := Object{
+		"account_id": r.PathValue("account_id"),
+		"tunnel_id":  obj["id"],
+		"config":     body["config"],
+		"source":     "cloudflare",
+		"version":    version,
+		"created_at": s.timestamp(),
+	}
+	s.tunnelCfgs[p] = cfg
+	writeResult(w, http.StatusOK, cfg)
+}
+
+func setDefault(obj Object, key string, v any) {
+	if _, ok := obj[key]; !ok {
+		obj[key] = v
+	}
+}
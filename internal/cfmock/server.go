@@ -0,0 +1,344 @@
+// Package cfmock implements an in-memory stand-in for the subset of the
+// Cloudflare v4 API used by the most common managed resources: zones, DNS
+// records, Cloudflare Tunnels and their configurations, Access applications,
+// Workers KV namespaces and values, and R2 buckets.
+//
+// A Server is meant to be used from Go tests. Point the Terraform provider or
+// a cloudflare-go client at its BaseURL, for example through the base_url key
+// of the ProviderConfig credentials. The Server keeps the state of every
+// object it is asked to create, can be told to fail requests, and records
+// every request it receives.
+package cfmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIPrefix is the path prefix under which the Cloudflare v4 API is served.
+const APIPrefix = "/client/v4"
+
+const (
+	headerRayID      = "Cf-Ray"
+	headerRetryAfter = "Retry-After"
+)
+
+// An Object is a Cloudflare API object as it is returned in the result of an
+// API response.
+type Object map[string]any
+
+// An Error is an entry of the errors array of a Cloudflare API response.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// A Fault makes the Server fail matching requests instead of serving them.
+type Fault struct {
+	// Method is the HTTP method of the requests to fail. All methods match
+	// if it is empty.
+	Method string
+
+	// Path is a path.Match pattern for the path of the requests to fail,
+	// relative to the base URL, for example "/zones/*/dns_records". All
+	// paths match if it is empty.
+	Path string
+
+	// Status is the HTTP status of the error response. It defaults to 500.
+	Status int
+
+	// Errors are returned in the errors array of the error response.
+	Errors []Error
+
+	// RetryAfter is returned in the Retry-After header of the error
+	// response, if set.
+	RetryAfter time.Duration
+
+	// Times is how many requests are failed. Every matching request is
+	// failed if it is zero.
+	Times int
+}
+
+func (f *Fault) matches(method, p string) bool {
+	if f.Method != "" && !strings.EqualFold(f.Method, method) {
+		return false
+	}
+	if f.Path == "" {
+		return true
+	}
+	ok, err := path.Match(f.Path, p)
+	return err == nil && ok
+}
+
+// A Request is a request received by the Server.
+type Request struct {
+	Method string
+
+	// Path is relative to the base URL, for example "/zones".
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+
+	// Status is the HTTP status the Server responded with.
+	Status int
+
+	// RayID is the request ID the Server returned in the Cf-Ray header.
+	RayID string
+}
+
+// An Option configures a Server.
+type Option func(*Server)
+
+// WithAPIToken makes the Server reject requests that do not authenticate
+// with the supplied API token. Any credentials are accepted by default.
+func WithAPIToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithClock sets the clock used for the timestamps of objects.
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+// A Server is an in-memory Cloudflare API server.
+type Server struct {
+	srv   *httptest.Server
+	mux   *http.ServeMux
+	token string
+	now   func() time.Time
+
+	mu          sync.Mutex
+	seq         int
+	requests    []Request
+	faults      []*Fault
+	collections []*collection
+	objects     map[string][]Object
+	kv          map[string]map[string]kvPair
+	tunnelCfgs  map[string]Object
+}
+
+// NewServer starts a Server. Callers must Close it when done.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		mux:        http.NewServeMux(),
+		now:        time.Now,
+		objects:    map[string][]Object{},
+		kv:         map[string]map[string]kvPair{},
+		tunnelCfgs: map[string]Object{},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	s.routes()
+	s.srv = httptest.NewServer(s)
+	return s
+}
+
+// BaseURL returns the base URL of the Cloudflare API served by the Server.
+func (s *Server) BaseURL() string {
+	return s.srv.URL + APIPrefix
+}
+
+// Client returns an HTTP client that talks to the Server.
+func (s *Server) Client() *http.Client {
+	return s.srv.Client()
+}
+
+// Close shuts the Server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// InjectFault makes the Server fail requests matching the supplied fault.
+// Faults are consulted in the order they were injected.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns the requests received so far, oldest first.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// ResetRequests forgets the requests received so far.
+func (s *Server) ResetRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+// ServeHTTP authenticates, records and serves a request, unless an injected
+// fault fails it.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	s.mu.Lock()
+	s.seq++
+	ray := fmt.Sprintf("%016x-MOCK", s.seq)
+	s.mu.Unlock()
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	rec.Header().Set(headerRayID, ray)
+	p := strings.TrimPrefix(r.URL.Path, APIPrefix)
+	switch {
+	case !strings.HasPrefix(r.URL.Path, APIPrefix+"/"):
+		writeErrors(rec, http.StatusNotFound, Error{Code: 7000, Message: "No route for that URI"})
+	case !s.authenticated(r):
+		writeErrors(rec, http.StatusForbidden, Error{Code: 9109, Message: "Invalid access token"})
+	default:
+		if f := s.fault(r.Method, p); f != nil {
+			if f.RetryAfter > 0 {
+				rec.Header().Set(headerRetryAfter, fmt.Sprintf("%d", int(f.RetryAfter.Seconds())))
+			}
+			status := f.Status
+			if status == 0 {
+				status = http.StatusInternalServerError
+			}
+			writeErrors(rec, status, f.Errors...)
+			break
+		}
+		s.mux.ServeHTTP(rec, r)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   p,
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+		Body:   body,
+		Status: rec.status,
+		RayID:  ray,
+	})
+}
+
+func (s *Server) authenticated(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	return r.Header.Get("Authorization") == "Bearer "+s.token
+}
+
+// fault returns the first fault matching the request and consumes one of its
+// times, or nil if no fault matches.
+func (s *Server) fault(method, p string) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.faults {
+		if !f.matches(method, p) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// newID returns a new object ID in the 32 hex digit format Cloudflare uses
+// for most objects. IDs are deterministic so that tests can predict them.
+func (s *Server) newID() string {
+	s.seq++
+	return fmt.Sprintf("%032x", s.seq)
+}
+
+// newUUID returns a new object ID in the UUID format Cloudflare uses for
+// tunnels and Access applications.
+func (s *Server) newUUID() string {
+	s.seq++
+	return fmt.Sprintf("00000000-0000-4000-8000-%012x", s.seq)
+}
+
+func (s *Server) timestamp() string {
+	return s.now().UTC().Format(time.RFC3339Nano)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+type envelope struct {
+	Success    bool        `json:"success"`
+	Errors     []Error     `json:"errors"`
+	Messages   []Error     `json:"messages"`
+	Result     any         `json:"result"`
+	ResultInfo *resultInfo `json:"result_info,omitempty"`
+}
+
+type resultInfo struct {
+	Page       int    `json:"page,omitempty"`
+	PerPage    int    `json:"per_page,omitempty"`
+	Count      int    `json:"count"`
+	TotalCount int    `json:"total_count"`
+	TotalPages int    `json:"total_pages,omitempty"`
+	Cursor     string `json:"cursor,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeResult(w http.ResponseWriter, status int, result any) {
+	writeJSON(w, status, envelope{Success: true, Errors: []Error{}, Messages: []Error{}, Result: result})
+}
+
+func writeList(w http.ResponseWriter, result any, info resultInfo) {
+	writeJSON(w, http.StatusOK, envelope{Success: true, Errors: []Error{}, Messages: []Error{}, Result: result, ResultInfo: &info})
+}
+
+func writeErrors(w http.ResponseWriter, status int, errs ...Error) {
+	if errs == nil {
+		errs = []Error{}
+	}
+	writeJSON(w, status, envelope{Errors: errs, Messages: []Error{}})
+}
+
+func readJSON(r *http.Request, v any) *Error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return &Error{Code: 9207, Message: "Request body is invalid: " + err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package cfmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// response is a decoded response of the Server.
+type response struct {
+	Status     int
+	Header     http.Header
+	Errors     []Error
+	Result     json.RawMessage
+	ResultInfo *resultInfo
+}
+
+// do sends a request to the Server, encoding body as JSON unless it is nil
+// or a byte slice.
+func do(t *testing.T, s *Server, method, p string, body any) response {
+	t.Helper()
+	var r io.Reader
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		r = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("cannot encode request body: %v", err)
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, s.BaseURL()+p, r)
+	if err != nil {
+		t.Fatalf("cannot build request: %v", err)
+	}
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, p, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("cannot read response: %v", err)
+	}
+	out := response{Status: resp.StatusCode, Header: resp.Header}
+	var env struct {
+		Errors     []Error         `json:"errors"`
+		Result     json.RawMessage `json:"result"`
+		ResultInfo *resultInfo     `json:"result_info"`
+	}
+	if json.Unmarshal(data, &env) == nil {
+		out.Errors, out.Result, out.ResultInfo = env.Errors, env.Result, env.ResultInfo
+	} else {
+		out.Result = data
+	}
+	return out
+}
+
+func TestFault(t *testing.T) {
+	cases := map[string]struct {
+		fault      Fault
+		method     string
+		path       string
+		wantStatus []int
+	}{
+		"MatchesEverything": {
+			fault:      Fault{},
+			method:     http.MethodGet,
+			path:       "/zones",
+			wantStatus: []int{http.StatusInternalServerError, http.StatusInternalServerError},
+		},
+		"MatchesPathPattern": {
+			fault:      Fault{Path: "/zones/*/dns_records", Status: http.StatusBadRequest},
+			method:     http.MethodGet,
+			path:       "/zones/abc/dns_records",
+			wantStatus: []int{http.StatusBadRequest, http.StatusBadRequest},
+		},
+		"OtherPath": {
+			fault:      Fault{Path: "/zones/*/dns_records"},
+			method:     http.MethodGet,
+			path:       "/zones",
+			wantStatus: []int{http.StatusOK, http.StatusOK},
+		},
+		"MethodIsCaseInsensitive": {
+			fault:      Fault{Method: "get", Status: http.StatusConflict},
+			method:     http.MethodGet,
+			path:       "/zones",
+			wantStatus: []int{http.StatusConflict, http.StatusConflict},
+		},
+		"OtherMethod": {
+			fault:      Fault{Method: http.MethodPost},
+			method:     http.MethodGet,
+			path:       "/zones",
+			wantStatus: []int{http.StatusOK, http.StatusOK},
+		},
+		"Times": {
+			fault:      Fault{Status: http.StatusTooManyRequests, Times: 1},
+			method:     http.MethodGet,
+			path:       "/zones",
+			wantStatus: []int{http.StatusTooManyRequests, http.StatusOK},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := NewServer()
+			defer s.Close()
+			s.InjectFault(tc.fault)
+			var got []int
+			for range tc.wantStatus {
+				got = append(got, do(t, s, tc.method, tc.path, nil).Status)
+			}
+			if diff := cmp.Diff(tc.wantStatus, got); diff != "" {
+				t.Errorf("statuses: -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFaultResponse(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.InjectFault(Fault{Status: http.StatusTooManyRequests, Errors: []Error{{Code: 971, Message: "Please wait"}}, RetryAfter: 30 * time.Second})
+
+	resp := do(t, s, http.MethodGet, "/zones", nil)
+	if got := resp.Header.Get(headerRetryAfter); got != "30" {
+		t.Errorf("Retry-After: want 30, got %q", got)
+	}
+	if diff := cmp.Diff([]Error{{Code: 971, Message: "Please wait"}}, resp.Errors); diff != "" {
+		t.Errorf("errors: -want, +got:\n%s", diff)
+	}
+	reqs := s.Requests()
+	if len(reqs) != 1 || reqs[0].Status != http.StatusTooManyRequests || reqs[0].RayID != resp.Header.Get(headerRayID) {
+		t.Errorf("Requests(): want the failed request with its ray ID, got %+v", reqs)
+	}
+
+	s.ClearFaults()
+	if got := do(t, s, http.MethodGet, "/zones", nil).Status; got != http.StatusOK {
+		t.Errorf("after ClearFaults(): want status 200, got %d", got)
+	}
+}
+
+func TestAPIToken(t *testing.T) {
+	s := NewServer(WithAPIToken("secret"))
+	defer s.Close()
+	if got := do(t, s, http.MethodGet, "/zones", nil).Status; got != http.StatusForbidden {
+		t.Errorf("without token: want status 403, got %d", got)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	for i := range 5 {
+		if _, err := s.Seed("/zones", Object{"name": fmt.Sprintf("example%d.com", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := map[string]struct {
+		query     string
+		wantNames []string
+		wantInfo  resultInfo
+	}{
+		"FirstPage": {
+			query:     "?per_page=2",
+			wantNames: []string{"example0.com", "example1.com"},
+			wantInfo:  resultInfo{Page: 1, PerPage: 2, Count: 2, TotalCount: 5, TotalPages: 3},
+		},
+		"LastPage": {
+			query:     "?per_page=2&page=3",
+			wantNames: []string{"example4.com"},
+			wantInfo:  resultInfo{Page: 3, PerPage: 2, Count: 1, TotalCount: 5, TotalPages: 3},
+		},
+		"PastLastPage": {
+			query:     "?per_page=2&page=4",
+			wantNames: []string{},
+			wantInfo:  resultInfo{Page: 4, PerPage: 2, Count: 0, TotalCount: 5, TotalPages: 3},
+		},
+		"Filtered": {
+			query:     "?name=example3.com",
+			wantNames: []string{"example3.com"},
+			wantInfo:  resultInfo{Page: 1, PerPage: defaultPerPage, Count: 1, TotalCount: 1, TotalPages: 1},
+		},
+		"FilterOperator": {
+			query:     "?name.startswith=example1",
+			wantNames: []string{"example1.com"},
+			wantInfo:  resultInfo{Page: 1, PerPage: defaultPerPage, Count: 1, TotalCount: 1, TotalPages: 1},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := do(t, s, http.MethodGet, "/zones"+tc.query, nil)
+			var items []Object
+			if err := json.Unmarshal(resp.Result, &items); err != nil {
+				t.Fatalf("cannot decode result: %v", err)
+			}
+			names := []string{}
+			for _, it := range items {
+				names = append(names, it["name"].(string))
+			}
+			if diff := cmp.Diff(tc.wantNames, names); diff != "" {
+				t.Errorf("names: -want, +got:\n%s", diff)
+			}
+			if diff := cmp.Diff(&tc.wantInfo, resp.ResultInfo); diff != "" {
+				t.Errorf("result_info: -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
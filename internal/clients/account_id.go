@@ -0,0 +1,51 @@
+package clients
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	errDefaultAccountID = "cannot set the default account ID of the ProviderConfig"
+)
+
+// NewAccountIDInitializer returns an initializer that sets the accountId of
+// a managed resource to the AccountID of its ProviderConfig, unless the
+// resource sets or references an account or a zone.
+func NewAccountIDInitializer(kube client.Client) managed.Initializer {
+	return managed.InitializerFn(func(ctx context.Context, mg resource.Managed) error {
+		paved, err := fieldpath.PaveObject(mg)
+		if err != nil {
+			return errors.Wrap(err, errDefaultAccountID)
+		}
+		// Resources such as Access applications or rulesets belong to
+		// either an account or a zone.
+		for _, p := range []string{"forProvider", "initProvider"} {
+			for _, f := range []string{"accountId", "accountIdRef", "accountIdSelector", "zoneId", "zoneIdRef", "zoneIdSelector"} {
+				if v, err := paved.GetValue("spec." + p + "." + f); err == nil && v != nil && v != "" {
+					return nil
+				}
+			}
+		}
+		pcSpec, err := resolveProviderConfig(ctx, kube, mg)
+		if err != nil {
+			return errors.Wrap(err, errDefaultAccountID)
+		}
+		if pcSpec.AccountID == "" {
+			return nil
+		}
+		if err := paved.SetValue("spec.forProvider.accountId", pcSpec.AccountID); err != nil {
+			return errors.Wrap(err, errDefaultAccountID)
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(paved.UnstructuredContent(), mg); err != nil {
+			return errors.Wrap(err, errDefaultAccountID)
+		}
+		return errors.Wrap(kube.Update(ctx, mg), errDefaultAccountID)
+	})
+}
@@ -0,0 +1,145 @@
+package clients
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AnnotationKeyAdopt requests that a managed resource without an external
+// name takes ownership of a matching existing Cloudflare resource instead of
+// creating a new one, when set to "true".
+const AnnotationKeyAdopt = "cloudflare.upbound.io/adopt"
+
+const (
+	errAdopt = "cannot adopt an existing Cloudflare resource"
+)
+
+// adoptionRequested reports whether a managed resource without an external
+// name asks to adopt an existing resource.
+func adoptionRequested(mg resource.Managed) bool {
+	return mg.GetAnnotations()[AnnotationKeyAdopt] == "true" && meta.GetExternalName(mg) == ""
+}
+
+// adopt sets the external name of a managed resource to the ID of the
+// existing Cloudflare resource it adopts.
+func adopt(ctx context.Context, kube client.Client, mg resource.Managed, id string) error {
+	meta.SetExternalName(mg, id)
+	return errors.Wrap(kube.Update(ctx, mg), errAdopt)
+}
+
+// parameter returns a string parameter of a managed resource, preferring
+// spec.forProvider over spec.initProvider.
+func parameter(paved *fieldpath.Paved, name string) string {
+	for _, p := range []string{"forProvider", "initProvider"} {
+		if v, err := paved.GetString("spec." + p + "." + name); err == nil && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// NewDNSRecordAdoptionInitializer returns an initializer that adopts the
+// existing DNS record with the zone, name and type of a record that requests
+// adoption, and also its content if set, which tells apart the records of a
+// multi-value set. Nothing is adopted if no record matches, so the record is
+// created.
+func NewDNSRecordAdoptionInitializer(kube client.Client) managed.Initializer {
+	return managed.InitializerFn(func(ctx context.Context, mg resource.Managed) error {
+		if !adoptionRequested(mg) {
+			return nil
+		}
+		// The zone is usually referenced, and references are resolved
+		// after initialization.
+		if err := managed.NewAPISimpleReferenceResolver(kube).ResolveReferences(ctx, mg); err != nil {
+			return errors.Wrap(err, errAdopt)
+		}
+		paved, err := fieldpath.PaveObject(mg)
+		if err != nil {
+			return errors.Wrap(err, errAdopt)
+		}
+		zoneID, name, typ := parameter(paved, "zoneId"), parameter(paved, "name"), parameter(paved, "type")
+		if zoneID == "" || name == "" || typ == "" {
+			return errors.New(errAdopt + ": adopting a DNS record requires its zoneId, name and type")
+		}
+		api, err := newManagedAPIClient(ctx, kube, mg)
+		if err != nil {
+			return errors.Wrap(err, errAdopt)
+		}
+		zone := struct {
+			Name string `json:"name"`
+		}{}
+		if err := api.get(ctx, "/zones/"+zoneID, nil, &zone); err != nil {
+			return errors.Wrap(err, errAdopt)
+		}
+		q := url.Values{"name.exact": {qualifiedRecordName(name, zone.Name)}, "type": {typ}}
+		if content := parameter(paved, "content"); content != "" {
+			q.Set("content.exact", content)
+		}
+		var records []struct {
+			ID string `json:"id"`
+		}
+		if err := api.get(ctx, "/zones/"+zoneID+"/dns_records", q, &records); err != nil {
+			return errors.Wrap(err, errAdopt)
+		}
+		switch len(records) {
+		case 0:
+			return nil
+		case 1:
+			return adopt(ctx, kube, mg, records[0].ID)
+		default:
+			return errors.Errorf("%s: %d %s records named %s exist, set the content of the record to select one", errAdopt, len(records), typ, q.Get("name.exact"))
+		}
+	})
+}
+
+// qualifiedRecordName returns the fully qualified name of a DNS record, which
+// may be given relative to its zone or as @ for the zone apex.
+func qualifiedRecordName(name, zone string) string {
+	name = strings.TrimSuffix(name, ".")
+	switch {
+	case name == "@" || name == zone:
+		return zone
+	case strings.HasSuffix(name, "."+zone):
+		return name
+	default:
+		return name + "." + zone
+	}
+}
+
+// ZoneIDByName looks up the ID of the zone with the supplied domain name with
+// the supplied Terraform provider configuration, optionally restricted to an
+// account.
+func ZoneIDByName(ctx context.Context, providerConfig map[string]any, name, accountID string) (string, error) {
+	creds := make(map[string]string, len(providerConfig))
+	for k, v := range providerConfig {
+		if s, ok := v.(string); ok {
+			creds[k] = s
+		}
+	}
+	q := url.Values{"name": {name}}
+	if accountID != "" {
+		q.Set("account.id", accountID)
+	}
+	var zones []struct {
+		ID string `json:"id"`
+	}
+	if err := newAPIClient(creds).get(ctx, "/zones", q, &zones); err != nil {
+		return "", errors.Wrapf(err, "cannot look up zone %s", name)
+	}
+	switch len(zones) {
+	case 0:
+		return "", errors.Errorf("zone %s does not exist or is not accessible with the credentials of the ProviderConfig", name)
+	case 1:
+		return zones[0].ID, nil
+	default:
+		return "", errors.Errorf("%d zones named %s are accessible with the credentials of the ProviderConfig, set account.id to select one", len(zones), name)
+	}
+}
@@ -0,0 +1,135 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultBaseURL = "https://api.cloudflare.com/client/v4"
+
+	errAPIRequest = "cannot query the Cloudflare API"
+)
+
+// An apiClient makes requests to the Cloudflare API outside of the Terraform
+// provider, for example to look up existing resources.
+type apiClient struct {
+	baseURL string
+	creds   map[string]string
+	http    *http.Client
+}
+
+// newAPIClient returns a client of the Cloudflare API authenticated with the
+// supplied ProviderConfig credentials.
+func newAPIClient(creds map[string]string) *apiClient {
+	base := defaultBaseURL
+	if v := creds["base_url"]; v != "" {
+		base = v
+	}
+	return &apiClient{baseURL: strings.TrimSuffix(base, "/"), creds: creds, http: apiHTTPClient}
+}
+
+// An apiFailure is the error of a failed Cloudflare API request.
+type apiFailure struct {
+	path     string
+	response apiResponse
+}
+
+func (e *apiFailure) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.response.Method, e.path, describeAPIFailure(e.response, classifyAPIResponse(e.response)))
+}
+
+// isAPINotFound reports whether an error is that of a Cloudflare API request
+// for something that does not exist.
+func isAPINotFound(err error) bool {
+	var f *apiFailure
+	return errors.As(err, &f) && f.response.StatusCode == http.StatusNotFound
+}
+
+// newManagedAPIClient returns a client of the Cloudflare API authenticated
+// with the credentials of the ProviderConfig of a managed resource.
+func newManagedAPIClient(ctx context.Context, kube client.Client, mg resource.Managed) (*apiClient, error) {
+	pcSpec, err := resolveProviderConfig(ctx, kube, mg)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot resolve provider config")
+	}
+	creds, err := providerCredentials(ctx, kube, pcSpec.Credentials.Source, pcSpec.Credentials.CommonCredentialSelectors)
+	if err != nil {
+		return nil, err
+	}
+	return newAPIClient(creds), nil
+}
+
+// get requests the supplied path, relative to the API base URL, and decodes
+// the result of the response into result.
+func (c *apiClient) get(ctx context.Context, path string, query url.Values, result any) error {
+	return c.do(ctx, http.MethodGet, path, query, nil, result)
+}
+
+// do sends a request with the supplied method and path, relative to the API
+// base URL, and body, which is encoded as JSON unless it is nil, and decodes
+// the result of the response into result unless it is nil.
+func (c *apiClient) do(ctx context.Context, method, path string, query url.Values, body, result any) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return errors.Wrap(err, errAPIRequest)
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return errors.Wrap(err, errAPIRequest)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token := c.creds["api_token"]; token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else {
+		req.Header.Set("X-Auth-Email", c.creds["email"])
+		req.Header.Set("X-Auth-Key", c.creds["api_key"])
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return errors.Wrap(err, errAPIRequest)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, errAPIRequest)
+	}
+	envelope := struct {
+		Errors []apiError      `json:"errors"`
+		Result json.RawMessage `json:"result"`
+	}{}
+	// Failed responses are reported by status; their body is best effort.
+	decodeErr := json.Unmarshal(respBody, &envelope)
+	r := apiResponse{Method: req.Method, StatusCode: resp.StatusCode, RayID: resp.Header.Get(headerRayID), Errors: envelope.Errors}
+	if r.failed() {
+		return &apiFailure{path: path, response: r}
+	}
+	if decodeErr != nil {
+		return errors.Wrap(decodeErr, errAPIRequest)
+	}
+	if result == nil {
+		return nil
+	}
+	return errors.Wrap(json.Unmarshal(envelope.Result, result), errAPIRequest)
+}
@@ -0,0 +1,43 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsAPINotFound(t *testing.T) {
+	cases := map[string]struct {
+		status int
+		want   bool
+	}{
+		"NotFound": {
+			status: http.StatusNotFound,
+			want:   true,
+		},
+		"Forbidden": {
+			status: http.StatusForbidden,
+		},
+		"ServerError": {
+			status: http.StatusInternalServerError,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(`{"success":false,"errors":[{"code":1000,"message":"failed"}]}`))
+			}))
+			defer srv.Close()
+			var token string
+			err := newAPIClient(map[string]string{"api_token": "token", "base_url": srv.URL}).get(context.Background(), "/accounts/a/cfd_tunnel/t/token", nil, &token)
+			if err == nil {
+				t.Fatalf("get(...): want error")
+			}
+			if got := isAPINotFound(err); got != tc.want {
+				t.Errorf("isAPINotFound(%v): want %t, got %t", err, tc.want, got)
+			}
+		})
+	}
+}
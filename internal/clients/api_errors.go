@@ -0,0 +1,239 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// errorClass tells whether retrying a failed Cloudflare API call may help.
+type errorClass string
+
+const (
+	errorClassRetryable   errorClass = "retryable"
+	errorClassRateLimited errorClass = "rate limited"
+	errorClassTerminal    errorClass = "terminal"
+)
+
+const (
+	// terminalBackoff is how long a change that failed with a terminal
+	// error is held back, unless the resource changes in the meantime.
+	terminalBackoff = time.Hour
+
+	// terminalReadBackoff is how long reads are held back after a read
+	// failed with a terminal error. It is shorter than terminalBackoff
+	// because such errors, such as missing permissions, are usually fixed
+	// outside of the resource.
+	terminalReadBackoff = 5 * time.Minute
+
+	// defaultRateLimitBackoff is how long changes are held back after a
+	// rate limited call that did not say when to retry.
+	defaultRateLimitBackoff = time.Minute
+)
+
+// errorCodes classify the Cloudflare error codes whose class does not follow
+// from the HTTP status they are returned with. Responses without any of them
+// are classified by their status.
+var errorCodes = map[int]errorClass{
+	// Returned with 400 rather than 429.
+	971: errorClassRateLimited, // Please wait and consider throttling your request speed.
+
+	// Returned while a new or rolled API token propagates, or during an
+	// outage of Cloudflare's authentication service.
+	10000: errorClassRetryable, // Authentication error.
+	10013: errorClassRetryable, // An unknown error has occurred.
+
+	// Returned with a status that retrying may help with, but caused by
+	// the request itself.
+	1004:  errorClassTerminal, // DNS Validation Error.
+	1061:  errorClassTerminal, // The zone already exists.
+	7003:  errorClassTerminal, // Could not route to the path, the object identifier is invalid.
+	9103:  errorClassTerminal, // Unknown X-Auth-Key or X-Auth-Email.
+	9106:  errorClassTerminal, // Missing X-Auth-Key, X-Auth-Email or Authorization headers.
+	9109:  errorClassTerminal, // Invalid access token.
+	81053: errorClassTerminal, // An A, AAAA, or CNAME record with that host already exists.
+	81057: errorClassTerminal, // The record already exists.
+}
+
+// classifyAPIResponse classifies a failed Cloudflare API response. A rate
+// limit code outweighs any other code, and a known code outweighs the
+// status.
+func classifyAPIResponse(r apiResponse) errorClass {
+	var known []errorClass
+	for _, e := range r.Errors {
+		if c, ok := errorCodes[e.Code]; ok {
+			known = append(known, c)
+		}
+	}
+	for _, c := range known {
+		if c == errorClassRateLimited {
+			return c
+		}
+	}
+	if len(known) > 0 {
+		return known[0]
+	}
+	switch {
+	case r.StatusCode == http.StatusTooManyRequests:
+		return errorClassRateLimited
+	case r.StatusCode == http.StatusRequestTimeout, r.StatusCode == http.StatusConflict, r.StatusCode >= http.StatusInternalServerError:
+		return errorClassRetryable
+	case r.StatusCode >= http.StatusBadRequest:
+		// Validation, authentication and authorization errors do not go
+		// away by retrying the same request.
+		return errorClassTerminal
+	default:
+		return errorClassRetryable
+	}
+}
+
+// describeAPIFailure renders the status, the Cloudflare error codes and
+// messages and the request ID of a failed response.
+func describeAPIFailure(r apiResponse, class errorClass) string {
+	errs := make([]string, 0, len(r.Errors))
+	for _, e := range r.Errors {
+		errs = append(errs, fmt.Sprintf("error %d: %s", e.Code, e.Message))
+	}
+	msg := fmt.Sprintf("Cloudflare API returned HTTP %d", r.StatusCode)
+	if len(errs) > 0 {
+		msg += " with " + strings.Join(errs, "; ")
+	}
+	if r.RayID != "" {
+		return fmt.Sprintf("%s (%s, cf-ray %s)", msg, class, r.RayID)
+	}
+	return fmt.Sprintf("%s (%s)", msg, class)
+}
+
+// An apiBackoff holds back the changes and reads of a managed resource after
+// a failed Cloudflare API call that retrying right away would not fix.
+type apiBackoff struct {
+	class   errorClass
+	until   time.Time
+	planned tftypes.Value
+	message string
+
+	// read is true if the call failed while the resource was read rather
+	// than changed.
+	read bool
+}
+
+type apiBackoffs struct {
+	mu      sync.Mutex
+	entries map[types.UID]apiBackoff
+}
+
+// backoffs are the current backoffs, keyed by managed resource UID. They are
+// shared by all reconcilers, since the framework provider is set up anew for
+// every reconcile.
+var backoffs = &apiBackoffs{entries: map[types.UID]apiBackoff{}}
+
+func (b *apiBackoffs) get(uid types.UID, now time.Time) (apiBackoff, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e, ok := b.entries[uid]
+	if ok && !now.Before(e.until) {
+		delete(b.entries, uid)
+		return apiBackoff{}, false
+	}
+	return e, ok
+}
+
+// hold starts backing off. It drops the backoffs that ended, since those of
+// managed resources that were deleted while backing off are never read again.
+func (b *apiBackoffs) hold(uid types.UID, e apiBackoff) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	for id, o := range b.entries {
+		if !now.Before(o.until) {
+			delete(b.entries, id)
+		}
+	}
+	b.entries[uid] = e
+}
+
+func (b *apiBackoffs) forget(uid types.UID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.entries, uid)
+}
+
+// apiBackoffGuard returns an apply guard that holds back the changes and
+// reads of a managed resource while it is backing off from a terminal or rate
+// limited Cloudflare API error. Rate limits hold back every call, while a
+// terminal error only holds back calls like the one that failed: a failed
+// change is retried as soon as the change itself differs from it.
+func apiBackoffGuard(mg resource.Managed) applyGuard {
+	uid := mg.GetUID()
+	return func(_ context.Context, req applyRequest) error {
+		b, ok := backoffs.get(uid, time.Now())
+		if !ok {
+			return nil
+		}
+		if b.class == errorClassTerminal {
+			read := req.Operation == operationRead
+			if read != b.read {
+				return nil
+			}
+			if !read && !b.planned.Equal(req.Planned) {
+				backoffs.forget(uid)
+				return nil
+			}
+		}
+		return errors.Errorf("%s, not retried before %s", b.message, b.until.UTC().Format(time.RFC3339))
+	}
+}
+
+// apiErrorObserver returns an apply observer that classifies the Cloudflare
+// API error a change or read failed with. It reports the Cloudflare error
+// codes and messages, and starts backing off from terminal and rate limited
+// errors.
+func apiErrorObserver(mg resource.Managed) applyObserver {
+	uid := mg.GetUID()
+	return func(_ context.Context, req applyRequest, res applyResult) {
+		read := req.Operation == operationRead
+		if !res.Diagnostics.HasError() {
+			// A successful read says nothing about the change that failed.
+			if b, ok := backoffs.get(uid, time.Now()); !read || (ok && b.read) {
+				backoffs.forget(uid)
+			}
+			return
+		}
+		f, ok := res.Calls.lastFailure()
+		if !ok {
+			return
+		}
+		class := classifyAPIResponse(f)
+		msg := describeAPIFailure(f, class)
+		now := time.Now()
+		switch class {
+		case errorClassTerminal:
+			if read {
+				until := now.Add(terminalReadBackoff)
+				backoffs.hold(uid, apiBackoff{class: class, until: until, message: msg, read: true})
+				msg += fmt.Sprintf(". The read is not retried before %s.", until.UTC().Format(time.RFC3339))
+				break
+			}
+			until := now.Add(terminalBackoff)
+			backoffs.hold(uid, apiBackoff{class: class, until: until, planned: req.Planned, message: msg})
+			msg += fmt.Sprintf(". The %s is not retried before %s unless the resource changes.", req.Operation, until.UTC().Format(time.RFC3339))
+		case errorClassRateLimited:
+			d := f.RetryAfter
+			if d == 0 {
+				d = defaultRateLimitBackoff
+			}
+			until := now.Add(d)
+			backoffs.hold(uid, apiBackoff{class: class, until: until, planned: req.Planned, message: msg, read: read})
+			msg += fmt.Sprintf(". Calls are retried after %s.", until.UTC().Format(time.RFC3339))
+		}
+		res.Diagnostics.AddError("Cloudflare API error", msg)
+	}
+}
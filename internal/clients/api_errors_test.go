@@ -0,0 +1,139 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource/fake"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestClassifyAPIResponse(t *testing.T) {
+	cases := map[string]struct {
+		r    apiResponse
+		want errorClass
+	}{
+		"TooManyRequests":           {r: apiResponse{StatusCode: http.StatusTooManyRequests}, want: errorClassRateLimited},
+		"RateLimitCode":             {r: apiResponse{StatusCode: http.StatusBadRequest, Errors: []apiError{{Code: 971}}}, want: errorClassRateLimited},
+		"RequestTimeout":            {r: apiResponse{StatusCode: http.StatusRequestTimeout}, want: errorClassRetryable},
+		"Conflict":                  {r: apiResponse{StatusCode: http.StatusConflict}, want: errorClassRetryable},
+		"InternalServerError":       {r: apiResponse{StatusCode: http.StatusInternalServerError}, want: errorClassRetryable},
+		"BadGateway":                {r: apiResponse{StatusCode: http.StatusBadGateway}, want: errorClassRetryable},
+		"BadRequest":                {r: apiResponse{StatusCode: http.StatusBadRequest, Errors: []apiError{{Code: 1004}}}, want: errorClassTerminal},
+		"Forbidden":                 {r: apiResponse{StatusCode: http.StatusForbidden}, want: errorClassTerminal},
+		"NotFound":                  {r: apiResponse{StatusCode: http.StatusNotFound}, want: errorClassTerminal},
+		"ServerErrorWithRateCode":   {r: apiResponse{StatusCode: http.StatusServiceUnavailable, Errors: []apiError{{Code: 971}}}, want: errorClassRateLimited},
+		"AuthenticationPropagating": {r: apiResponse{StatusCode: http.StatusBadRequest, Errors: []apiError{{Code: 10000}}}, want: errorClassRetryable},
+		"ServerErrorWithValidation": {r: apiResponse{StatusCode: http.StatusInternalServerError, Errors: []apiError{{Code: 1004}}}, want: errorClassTerminal},
+		"UnknownCode":               {r: apiResponse{StatusCode: http.StatusBadGateway, Errors: []apiError{{Code: 1}}}, want: errorClassRetryable},
+		"RateCodeAfterOtherCode":    {r: apiResponse{StatusCode: http.StatusBadRequest, Errors: []apiError{{Code: 1004}, {Code: 971}}}, want: errorClassRateLimited},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := classifyAPIResponse(tc.r); got != tc.want {
+				t.Errorf("classifyAPIResponse(%+v): want %s, got %s", tc.r, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestAPIBackoff(t *testing.T) {
+	update := applyRequest{
+		Operation: operationUpdate,
+		TypeName:  "cloudflare_dns_record",
+		Prior:     record("www", 300, false, nil, nil),
+		Planned:   record("www", 1, false, nil, nil),
+	}
+	changed := update
+	changed.Planned = record("www", 60, false, nil, nil)
+	read := applyRequest{Operation: operationRead, TypeName: "cloudflare_dns_record", Prior: update.Prior, Planned: update.Prior}
+	cases := map[string]struct {
+		failed   applyRequest
+		status   int
+		next     applyRequest
+		wantHeld bool
+	}{
+		"TerminalUpdateHoldsSameUpdate": {
+			failed:   update,
+			status:   http.StatusBadRequest,
+			next:     update,
+			wantHeld: true,
+		},
+		"TerminalUpdateAllowsChangedUpdate": {
+			failed: update,
+			status: http.StatusBadRequest,
+			next:   changed,
+		},
+		"TerminalUpdateAllowsRead": {
+			failed: update,
+			status: http.StatusBadRequest,
+			next:   read,
+		},
+		"TerminalReadHoldsRead": {
+			failed:   read,
+			status:   http.StatusForbidden,
+			next:     read,
+			wantHeld: true,
+		},
+		"RateLimitedReadHoldsUpdate": {
+			failed:   read,
+			status:   http.StatusTooManyRequests,
+			next:     update,
+			wantHeld: true,
+		},
+		"RetryableUpdateIsNotHeld": {
+			failed: update,
+			status: http.StatusBadGateway,
+			next:   update,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mg := &fake.Managed{}
+			mg.SetUID(types.UID(name))
+			t.Cleanup(func() { backoffs.forget(mg.GetUID()) })
+
+			calls := &apiCalls{}
+			calls.add(apiResponse{StatusCode: tc.status})
+			diags := &diag.Diagnostics{}
+			diags.AddError("failed", "failed")
+			apiErrorObserver(mg)(context.Background(), tc.failed, applyResult{Calls: calls, Diagnostics: diags})
+
+			err := apiBackoffGuard(mg)(context.Background(), tc.next)
+			if (err != nil) != tc.wantHeld {
+				t.Errorf("apiBackoffGuard(...): want held %t, got %v", tc.wantHeld, err)
+			}
+		})
+	}
+}
+
+func TestAPIBackoffSuccessfulRead(t *testing.T) {
+	mg := &fake.Managed{}
+	mg.SetUID("uid")
+	t.Cleanup(func() { backoffs.forget(mg.GetUID()) })
+	update := applyRequest{Operation: operationUpdate, Planned: record("www", 1, false, nil, nil)}
+	backoffs.hold(mg.GetUID(), apiBackoff{class: errorClassTerminal, until: time.Now().Add(time.Hour), planned: update.Planned})
+
+	// Reading the resource must not lift the backoff of the update.
+	apiErrorObserver(mg)(context.Background(), applyRequest{Operation: operationRead}, applyResult{Calls: &apiCalls{}, Diagnostics: &diag.Diagnostics{}})
+	if err := apiBackoffGuard(mg)(context.Background(), update); err == nil {
+		t.Error("apiBackoffGuard(...): want update held after a successful read, got nil")
+	}
+}
+
+func TestAPIBackoffsHoldDropsEnded(t *testing.T) {
+	b := &apiBackoffs{entries: map[types.UID]apiBackoff{
+		"ended":   {until: time.Now().Add(-time.Second)},
+		"current": {until: time.Now().Add(time.Hour)},
+	}}
+	b.hold("new", apiBackoff{until: time.Now().Add(time.Hour)})
+	if _, ok := b.entries["ended"]; ok {
+		t.Error("hold(...): want ended backoff dropped")
+	}
+	if len(b.entries) != 2 {
+		t.Errorf("hold(...): want 2 backoffs, got %d", len(b.entries))
+	}
+}
@@ -0,0 +1,161 @@
+package clients
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	ujresource "github.com/crossplane/upjet/v2/pkg/resource"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/prolixalias/provider-cloudflare/config/certificate"
+)
+
+const (
+	// AnnotationKeyCertificateHash is the SHA-256 hash of the certificate
+	// and private key a certificate resource that cannot be changed in place
+	// last uploaded from its Secrets.
+	AnnotationKeyCertificateHash = "cloudflare.upbound.io/certificate-sha256"
+
+	// AnnotationKeyCertificatePreviousID is the ID of the certificate an
+	// uploaded certificate replaces, which is deleted once the new one is
+	// uploaded.
+	AnnotationKeyCertificatePreviousID = "cloudflare.upbound.io/certificate-previous-id"
+)
+
+const errCertificateSource = "cannot read the certificate source"
+
+// resolveCertificateSource reads the certificate of a certificate resource
+// from its certificateSecretRef and sets the certificate argument to it, in
+// memory. Certificates that Cloudflare can update are uploaded again by
+// Terraform when the content of their Secrets changes. Others are uploaded
+// again as a new certificate when it changes: their external name and
+// observation are cleared, so that Terraform uploads it, and the previous
+// certificate is deleted once the new one is uploaded.
+func resolveCertificateSource(ctx context.Context, kube client.Client, trackers trackerRemover, mg resource.Managed, outside outsideApplier, changesAllowed bool) error {
+	tr, ok := mg.(ujresource.Terraformed)
+	if !ok {
+		return nil
+	}
+	cr, ok := certificate.Resources[tr.GetTerraformResourceType()]
+	if !ok || meta.WasDeleted(mg) {
+		return nil
+	}
+	paved, err := fieldpath.PaveObject(mg)
+	if err != nil {
+		return errors.Wrap(err, errCertificateSource)
+	}
+	src, err := paved.GetValue("spec.forProvider.certificateSecretRef")
+	if err != nil || src == nil {
+		return nil
+	}
+	ref, _ := src.(map[string]any)
+	cert, err := readSecretKey(ctx, kube, mg.GetNamespace(), ref, corev1.TLSCertKey)
+	if err != nil {
+		return errors.Wrap(err, errCertificateSource)
+	}
+	if cr.DeletePath != nil && changesAllowed {
+		if err := replaceCertificate(ctx, kube, trackers, mg, outside, cr, paved, cert); err != nil {
+			return errors.Wrap(err, errCertificateSource)
+		}
+		if paved, err = fieldpath.PaveObject(mg); err != nil {
+			return errors.Wrap(err, errCertificateSource)
+		}
+	}
+	if err := paved.SetValue("spec.forProvider."+cr.Field, string(cert)); err != nil {
+		return errors.Wrap(err, errCertificateSource)
+	}
+	return errors.Wrap(runtime.DefaultUnstructuredConverter.FromUnstructured(paved.UnstructuredContent(), mg), errCertificateSource)
+}
+
+// replaceCertificate replaces an uploaded certificate that cannot be changed
+// in place when the content of its Secrets changes. The hash of the content
+// is recorded once the certificate holding it is uploaded and the one it
+// replaces is deleted.
+func replaceCertificate(ctx context.Context, kube client.Client, trackers trackerRemover, mg resource.Managed, outside outsideApplier, cr certificate.Resource, paved *fieldpath.Paved, cert []byte) error {
+	h := sha256.New()
+	h.Write(cert)
+	if keyRef, err := paved.GetValue("spec.forProvider.privateKeySecretRef"); err == nil && keyRef != nil {
+		ref, _ := keyRef.(map[string]any)
+		key, err := readSecretKey(ctx, kube, mg.GetNamespace(), ref, corev1.TLSPrivateKeyKey)
+		if err != nil {
+			return err
+		}
+		h.Write(key)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	id := meta.GetExternalName(mg)
+	prevID := mg.GetAnnotations()[AnnotationKeyCertificatePreviousID]
+	switch {
+	case id == "":
+		// The certificate is not uploaded yet.
+		return nil
+	case prevID != "" && prevID != id:
+		// The new certificate is uploaded, so the one it replaces can go.
+		api, err := newManagedAPIClient(ctx, kube, mg)
+		if err != nil {
+			return err
+		}
+		tr, _ := mg.(ujresource.Terraformed)
+		req := applyRequest{
+			Operation: operationDelete,
+			TypeName:  tr.GetTerraformResourceType(),
+			Prior:     tftypes.NewValue(tftypes.String, prevID),
+		}
+		path := cr.DeletePath(func(name string) string { return parameter(paved, name) }, prevID)
+		err = outside.apply(ctx, req, func(ctx context.Context) error {
+			// A certificate that does not exist anymore needs no deleting.
+			if err := api.do(ctx, http.MethodDelete, path, nil, nil, nil); err != nil && !isAPINotFound(err) {
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return errors.Wrapf(err, "cannot delete the previous certificate %s", prevID)
+		}
+		return annotate(ctx, kube, mg, map[string]any{AnnotationKeyCertificatePreviousID: nil, AnnotationKeyCertificateHash: sum})
+	}
+	switch prev := mg.GetAnnotations()[AnnotationKeyCertificateHash]; prev {
+	case sum:
+		return nil
+	case "":
+		// The certificate was uploaded before its hash was recorded.
+		return annotate(ctx, kube, mg, map[string]any{AnnotationKeyCertificateHash: sum})
+	}
+	meta.AddAnnotations(mg, map[string]string{AnnotationKeyCertificatePreviousID: id})
+	meta.SetExternalName(mg, "")
+	if err := kube.Update(ctx, mg); err != nil {
+		return err
+	}
+	if trackers != nil {
+		if err := trackers.RemoveTracker(mg); err != nil {
+			return err
+		}
+	}
+	p, err := fieldpath.PaveObject(mg)
+	if err != nil {
+		return err
+	}
+	if err := p.SetValue("status.atProvider", map[string]any{}); err != nil {
+		return err
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(p.UnstructuredContent(), mg)
+}
+
+// readSecretKey reads a key of the Secret a managed resource references,
+// which defaults to the supplied key.
+func readSecretKey(ctx context.Context, kube client.Client, namespace string, ref map[string]any, defaultKey string) ([]byte, error) {
+	if k, _ := ref["key"].(string); k == "" {
+		ref = map[string]any{"name": ref["name"], "namespace": ref["namespace"], "key": defaultKey}
+	}
+	return readContentSource(ctx, kube, namespace, map[string]any{"secretKeyRef": ref}, nil)
+}
@@ -0,0 +1,93 @@
+package clients
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/prolixalias/provider-cloudflare/config/certificate"
+)
+
+// patchRecorder is a client that records the patches it is asked to apply
+// and accepts updates.
+type patchRecorder struct {
+	client.Client
+	patches []string
+}
+
+func (c *patchRecorder) Update(context.Context, client.Object, ...client.UpdateOption) error {
+	return nil
+}
+
+func (c *patchRecorder) Patch(_ context.Context, _ client.Object, p client.Patch, _ ...client.PatchOption) error {
+	b, err := p.Data(nil)
+	c.patches = append(c.patches, string(b))
+	return err
+}
+
+func TestReplaceCertificate(t *testing.T) {
+	const cert = "-----BEGIN CERTIFICATE-----"
+	h := sha256.Sum256([]byte(cert))
+	sum := hex.EncodeToString(h[:])
+	cases := map[string]struct {
+		id          string
+		hash        string
+		wantID      string
+		wantPrev    string
+		wantPatches []string
+	}{
+		"NotUploaded": {},
+		"HashNotRecorded": {
+			id:          "old",
+			wantID:      "old",
+			wantPatches: []string{`{"metadata":{"annotations":{"cloudflare.upbound.io/certificate-sha256":"` + sum + `"}}}`},
+		},
+		"Unchanged": {
+			id:     "old",
+			hash:   sum,
+			wantID: "old",
+		},
+		// The hash of changed content is only recorded once the
+		// certificate holding it is uploaded.
+		"Changed": {
+			id:       "old",
+			hash:     "stale",
+			wantPrev: "old",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mg := &parameterized{}
+			meta.SetExternalName(mg, tc.id)
+			if tc.hash != "" {
+				meta.AddAnnotations(mg, map[string]string{AnnotationKeyCertificateHash: tc.hash})
+			}
+			mg.Spec.ForProvider = map[string]any{"certificateSecretRef": map[string]any{"name": "app-tls"}}
+			kube := &patchRecorder{}
+			paved, err := fieldpath.PaveObject(mg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cr := certificate.Resources["cloudflare_keyless_certificate"]
+			if err := replaceCertificate(context.Background(), kube, nil, mg, outsideApplier{}, cr, paved, []byte(cert)); err != nil {
+				t.Fatalf("replaceCertificate(...): unexpected error: %v", err)
+			}
+			if id := meta.GetExternalName(mg); id != tc.wantID {
+				t.Errorf("replaceCertificate(...): want external name %q, got %q", tc.wantID, id)
+			}
+			if prev := mg.GetAnnotations()[AnnotationKeyCertificatePreviousID]; prev != tc.wantPrev {
+				t.Errorf("replaceCertificate(...): want previous ID %q, got %q", tc.wantPrev, prev)
+			}
+			if diff := cmp.Diff(tc.wantPatches, kube.patches, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("replaceCertificate(...): patches: -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	namespacedv1beta1 "github.com/prolixalias/provider-cloudflare/apis/namespaced/v1beta1"
+)
+
+// AnnotationKeyChangeWindows holds a JSON list of change windows that
+// overrides the change windows of the ProviderConfig of a managed resource.
+const AnnotationKeyChangeWindows = "cloudflare.upbound.io/change-windows"
+
+// TypeWaitingForChangeWindow indicates whether changes to a managed resource
+// are deferred until its next change window.
+const TypeWaitingForChangeWindow xpv1.ConditionType = "WaitingForChangeWindow"
+
+// Reasons a managed resource is or is not waiting for a change window.
+const (
+	ReasonOutsideChangeWindow xpv1.ConditionReason = "OutsideChangeWindow"
+	ReasonChangeWindowOpen    xpv1.ConditionReason = "ChangeWindowOpen"
+)
+
+const (
+	reasonChangeDeferred event.Reason = "ChangeDeferred"
+
+	errChangeWindows          = "invalid change windows"
+	errChangeWindowAnnotation = "cannot parse " + AnnotationKeyChangeWindows + " annotation"
+)
+
+// WaitingForChangeWindow returns a condition indicating that changes to a
+// managed resource are deferred until the supplied time.
+func WaitingForChangeWindow(next time.Time) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeWaitingForChangeWindow,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonOutsideChangeWindow,
+		Message:            "Changes are deferred until the next change window starts at " + next.UTC().Format(time.RFC3339),
+	}
+}
+
+// ChangeWindowOpen returns a condition indicating that changes to a managed
+// resource may be applied.
+func ChangeWindowOpen() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeWaitingForChangeWindow,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonChangeWindowOpen,
+	}
+}
+
+type changeWindow struct {
+	schedule cron.Schedule
+	duration time.Duration
+	location *time.Location
+}
+
+// changeWindows are the windows in which changes may be applied. Changes may
+// be applied at any time if there are none.
+type changeWindows []changeWindow
+
+func parseChangeWindows(specs []namespacedv1beta1.ChangeWindow) (changeWindows, error) {
+	windows := make(changeWindows, 0, len(specs))
+	for i, s := range specs {
+		sched, err := cron.ParseStandard(s.Schedule)
+		if err != nil {
+			return nil, errors.Wrapf(err, "window %d: invalid schedule %q", i, s.Schedule)
+		}
+		if s.Duration.Duration <= 0 {
+			return nil, errors.Errorf("window %d: duration must be positive", i)
+		}
+		loc, err := time.LoadLocation(s.TimeZone)
+		if err != nil {
+			return nil, errors.Wrapf(err, "window %d: invalid time zone %q", i, s.TimeZone)
+		}
+		windows = append(windows, changeWindow{schedule: sched, duration: s.Duration.Duration, location: loc})
+	}
+	return windows, nil
+}
+
+// resourceChangeWindows returns the change windows of the supplied managed
+// resource, which are those of its annotation if it has one and those of its
+// ProviderConfig otherwise.
+func resourceChangeWindows(mg resource.Managed, pc []namespacedv1beta1.ChangeWindow) (changeWindows, error) {
+	if v, ok := mg.GetAnnotations()[AnnotationKeyChangeWindows]; ok {
+		var specs []namespacedv1beta1.ChangeWindow
+		if err := json.Unmarshal([]byte(v), &specs); err != nil {
+			return nil, errors.Wrap(err, errChangeWindowAnnotation)
+		}
+		return parseChangeWindows(specs)
+	}
+	return parseChangeWindows(pc)
+}
+
+// open reports whether any of the windows is open at the supplied time.
+func (w changeWindows) open(now time.Time) bool {
+	if len(w) == 0 {
+		return true
+	}
+	for _, cw := range w {
+		// The window is open if it started in the last duration.
+		if t := cw.schedule.Next(now.In(cw.location).Add(-cw.duration)); !t.IsZero() && !t.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// next returns the time the next window starts after the supplied time.
+func (w changeWindows) next(now time.Time) time.Time {
+	var next time.Time
+	for _, cw := range w {
+		if t := cw.schedule.Next(now.In(cw.location)); !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	return next
+}
+
+// windowHold holds back the changes to a managed resource outside of its
+// change windows, recording the start of the next window in the
+// WaitingForChangeWindow condition of the managed resource and in an event
+// when it changes.
+type windowHold struct {
+	rec     event.Recorder
+	windows changeWindows
+
+	// mg is the managed resource of the reconciler, which persists its
+	// conditions once it observed the resource.
+	mg resource.Managed
+}
+
+func (h *windowHold) plan(req applyRequest, now time.Time) (bool, error) {
+	if h.windows.open(now) {
+		return false, nil
+	}
+	req = heldRequest(h.mg, req)
+	changed, err := hasChanges(req)
+	if err != nil {
+		return false, errors.Wrap(err, "cannot compute deferred changes")
+	}
+	if !changed {
+		if h.mg.GetCondition(TypeWaitingForChangeWindow).Status == corev1.ConditionTrue {
+			h.mg.SetConditions(ChangeWindowOpen())
+		}
+		return false, nil
+	}
+	next := h.windows.next(now)
+	c := WaitingForChangeWindow(next)
+	if h.mg.GetCondition(TypeWaitingForChangeWindow).Message != c.Message {
+		msg := windowMessage(req, next)
+		h.rec.Event(h.mg, event.Normal(reasonChangeDeferred, msg, "operation", string(req.Operation)))
+	}
+	h.mg.SetConditions(c)
+	return true, nil
+}
+
+func (h *windowHold) held(req applyRequest, now time.Time) error {
+	if h.windows.open(now) {
+		return nil
+	}
+	return heldError{msg: windowMessage(heldRequest(h.mg, req), h.windows.next(now))}
+}
+
+func windowMessage(req applyRequest, next time.Time) string {
+	return fmt.Sprintf("%s of %s deferred until the next change window starts at %s", req.Operation, req.TypeName, next.UTC().Format(time.RFC3339))
+}
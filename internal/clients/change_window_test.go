@@ -0,0 +1,171 @@
+package clients
+
+import (
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource/fake"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	namespacedv1beta1 "github.com/prolixalias/provider-cloudflare/apis/namespaced/v1beta1"
+)
+
+func mustWindows(t *testing.T, specs ...namespacedv1beta1.ChangeWindow) changeWindows {
+	t.Helper()
+	w, err := parseChangeWindows(specs)
+	if err != nil {
+		t.Fatalf("parseChangeWindows(...): unexpected error: %v", err)
+	}
+	return w
+}
+
+func window(schedule string, d time.Duration, tz string) namespacedv1beta1.ChangeWindow {
+	return namespacedv1beta1.ChangeWindow{Schedule: schedule, Duration: metav1.Duration{Duration: d}, TimeZone: tz}
+}
+
+func at(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestChangeWindows(t *testing.T) {
+	nightly := window("0 22 * * *", 2*time.Hour, "")
+	cases := map[string]struct {
+		windows  []namespacedv1beta1.ChangeWindow
+		now      time.Time
+		wantOpen bool
+		wantNext time.Time
+	}{
+		"NoWindows": {
+			now:      at("2026-06-01T12:00:00Z"),
+			wantOpen: true,
+		},
+		"BeforeStart": {
+			windows:  []namespacedv1beta1.ChangeWindow{nightly},
+			now:      at("2026-06-01T21:59:59Z"),
+			wantNext: at("2026-06-01T22:00:00Z"),
+		},
+		"AtStart": {
+			windows:  []namespacedv1beta1.ChangeWindow{nightly},
+			now:      at("2026-06-01T22:00:00Z"),
+			wantOpen: true,
+			wantNext: at("2026-06-02T22:00:00Z"),
+		},
+		"BeforeEnd": {
+			windows:  []namespacedv1beta1.ChangeWindow{nightly},
+			now:      at("2026-06-01T23:59:59Z"),
+			wantOpen: true,
+			wantNext: at("2026-06-02T22:00:00Z"),
+		},
+		"AtEnd": {
+			windows:  []namespacedv1beta1.ChangeWindow{nightly},
+			now:      at("2026-06-02T00:00:00Z"),
+			wantNext: at("2026-06-02T22:00:00Z"),
+		},
+		"OverlappingWindowsExtendEachOther": {
+			windows:  []namespacedv1beta1.ChangeWindow{nightly, window("0 23 * * *", 2*time.Hour, "")},
+			now:      at("2026-06-02T00:30:00Z"),
+			wantOpen: true,
+			wantNext: at("2026-06-02T22:00:00Z"),
+		},
+		"OverlappingWindowsEnd": {
+			windows:  []namespacedv1beta1.ChangeWindow{nightly, window("0 23 * * *", 2*time.Hour, "")},
+			now:      at("2026-06-02T01:00:00Z"),
+			wantNext: at("2026-06-02T22:00:00Z"),
+		},
+		"NextIsEarliestWindow": {
+			windows:  []namespacedv1beta1.ChangeWindow{nightly, window("0 6 * * *", time.Hour, "")},
+			now:      at("2026-06-02T03:00:00Z"),
+			wantNext: at("2026-06-02T06:00:00Z"),
+		},
+		"TimeZone": {
+			windows:  []namespacedv1beta1.ChangeWindow{window("0 9 * * *", time.Hour, "Europe/Berlin")},
+			now:      at("2026-06-01T07:30:00Z"),
+			wantOpen: true,
+			wantNext: at("2026-06-02T07:00:00Z"),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			w := mustWindows(t, tc.windows...)
+			if got := w.open(tc.now); got != tc.wantOpen {
+				t.Errorf("open(%s): want %t, got %t", tc.now, tc.wantOpen, got)
+			}
+			if got := w.next(tc.now); !got.Equal(tc.wantNext) {
+				t.Errorf("next(%s): want %s, got %s", tc.now, tc.wantNext, got)
+			}
+		})
+	}
+}
+
+func TestWindowHold(t *testing.T) {
+	w := mustWindows(t, window("0 22 * * *", 2*time.Hour, ""))
+	update := applyRequest{
+		Operation: operationUpdate,
+		TypeName:  "cloudflare_dns_record",
+		Prior:     record("www", 300, false, nil, nil),
+		Planned:   record("www", 1, false, nil, nil),
+	}
+	noChange := update
+	noChange.Planned = update.Prior
+	cases := map[string]struct {
+		req        applyRequest
+		now        time.Time
+		wantHeld   bool
+		wantStatus corev1.ConditionStatus
+		wantErr    bool
+	}{
+		"Open": {
+			req:        update,
+			now:        at("2026-06-01T23:00:00Z"),
+			wantStatus: corev1.ConditionUnknown,
+		},
+		"Closed": {
+			req:        update,
+			now:        at("2026-06-01T12:00:00Z"),
+			wantHeld:   true,
+			wantStatus: corev1.ConditionTrue,
+			wantErr:    true,
+		},
+		"ClosedWithoutChanges": {
+			req:        noChange,
+			now:        at("2026-06-01T12:00:00Z"),
+			wantStatus: corev1.ConditionUnknown,
+			wantErr:    true,
+		},
+		"ClosedCreation": {
+			req:        applyRequest{Operation: operationCreate, TypeName: "cloudflare_dns_record", Prior: tftypes.NewValue(recordType, nil), Planned: update.Planned},
+			now:        at("2026-06-01T12:00:00Z"),
+			wantHeld:   true,
+			wantStatus: corev1.ConditionTrue,
+			wantErr:    true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mg := &fake.Managed{}
+			h := &windowHold{rec: event.NewNopRecorder(), mg: mg, windows: w}
+			held, err := h.plan(tc.req, tc.now)
+			if err != nil {
+				t.Fatalf("plan(...): unexpected error: %v", err)
+			}
+			if held != tc.wantHeld {
+				t.Errorf("plan(...): want held %t, got %t", tc.wantHeld, held)
+			}
+			if got := mg.GetCondition(TypeWaitingForChangeWindow).Status; got != tc.wantStatus {
+				t.Errorf("plan(...): want %s condition %s, got %s", TypeWaitingForChangeWindow, tc.wantStatus, got)
+			}
+			// Outside of the windows even changes without a diff are held
+			// when they are applied.
+			if err := h.held(tc.req, tc.now); (err != nil) != tc.wantErr {
+				t.Errorf("held(...): want error %t, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cloudflare/cloudflare-go/v6"
+	"github.com/cloudflare/cloudflare-go/v6/option"
+)
+
+const (
+	headerRayID      = "Cf-Ray"
+	headerRetryAfter = "Retry-After"
+
+	// maxErrorBodyBytes caps how much of a failed response is parsed for
+	// Cloudflare error codes.
+	maxErrorBodyBytes = 64 << 10
+)
+
+// An apiResponse is a response of the Cloudflare API received while a change
+// was applied.
+type apiResponse struct {
+	Method     string
+	StatusCode int
+
+	// RayID is the Cloudflare request identifier support asks for.
+	RayID string
+
+	// RetryAfter is how long Cloudflare asked the client to wait before
+	// retrying, if it did.
+	RetryAfter time.Duration
+
+	// Errors are the errors of a failed response.
+	Errors []apiError
+}
+
+// An apiError is an entry of the errors array of a Cloudflare API response.
+type apiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (r apiResponse) failed() bool {
+	return r.StatusCode >= http.StatusBadRequest
+}
+
+// apiCalls collects the Cloudflare API responses received on behalf of a
+// single change.
+type apiCalls struct {
+	mu        sync.Mutex
+	responses []apiResponse
+}
+
+func (c *apiCalls) add(r apiResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses = append(c.responses, r)
+}
+
+// Responses returns the responses received so far.
+func (c *apiCalls) Responses() []apiResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]apiResponse(nil), c.responses...)
+}
+
+// lastFailure returns the last failed response, if any.
+func (c *apiCalls) lastFailure() (apiResponse, bool) {
+	rs := c.Responses()
+	for i := len(rs) - 1; i >= 0; i-- {
+		if rs[i].failed() {
+			return rs[i], true
+		}
+	}
+	return apiResponse{}, false
+}
+
+type apiCallsKey struct{}
+
+// withAPICalls returns a context that collects the Cloudflare API responses
+// of every request made with it.
+func withAPICalls(ctx context.Context) (context.Context, *apiCalls) {
+	c := &apiCalls{}
+	return context.WithValue(ctx, apiCallsKey{}, c), c
+}
+
+// apiHTTPClient is the HTTP client of every Cloudflare API request. It
+// records the responses of requests made with a context returned by
+// withAPICalls.
+var apiHTTPClient = &http.Client{
+	Transport: &recordingTransport{RoundTripper: http.DefaultTransport.(*http.Transport).Clone()},
+}
+
+// instrumentClient returns a copy of the supplied Cloudflare client, which
+// the Terraform provider configures, that sends its requests with
+// apiHTTPClient. Other values are returned as is.
+func instrumentClient(v any) any {
+	c, ok := v.(*cloudflare.Client)
+	if !ok || c == nil {
+		return v
+	}
+	opts := append(append([]option.RequestOption{}, c.Options...), option.WithHTTPClient(apiHTTPClient))
+	return cloudflare.NewClient(opts...)
+}
+
+// recordingTransport records the responses of requests whose context
+// collects API calls.
+type recordingTransport struct {
+	http.RoundTripper
+}
+
+// RoundTrip sends the request with the wrapped transport and records its
+// response.
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.RoundTripper.RoundTrip(req)
+	calls, ok := req.Context().Value(apiCallsKey{}).(*apiCalls)
+	if !ok || err != nil {
+		return resp, err
+	}
+	r := apiResponse{
+		Method:     req.Method,
+		StatusCode: resp.StatusCode,
+		RayID:      resp.Header.Get(headerRayID),
+		RetryAfter: parseRetryAfter(resp.Header.Get(headerRetryAfter), time.Now()),
+	}
+	if r.failed() && resp.Body != nil {
+		body, rErr := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		// Hand the complete body to the caller, which reports the error.
+		resp.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+		if rErr == nil {
+			var envelope struct {
+				Errors []apiError `json:"errors"`
+			}
+			if json.Unmarshal(body, &envelope) == nil {
+				r.Errors = envelope.Errors
+			}
+		}
+	}
+	calls.add(r)
+	return resp, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// parseRetryAfter parses a Retry-After header, which holds either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
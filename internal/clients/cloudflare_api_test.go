@@ -0,0 +1,69 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloudflare/cloudflare-go/v6"
+	"github.com/cloudflare/cloudflare-go/v6/option"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	cases := map[string]struct {
+		v    string
+		want time.Duration
+	}{
+		"Empty":       {v: "", want: 0},
+		"Seconds":     {v: "30", want: 30 * time.Second},
+		"ZeroSeconds": {v: "0", want: 0},
+		"Negative":    {v: "-5", want: 0},
+		"Date":        {v: "Mon, 01 Jun 2026 12:01:30 GMT", want: 90 * time.Second},
+		"PastDate":    {v: "Mon, 01 Jun 2026 11:00:00 GMT", want: 0},
+		"Invalid":     {v: "soon", want: 0},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := parseRetryAfter(tc.v, now); got != tc.want {
+				t.Errorf("parseRetryAfter(%q): want %s, got %s", tc.v, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestInstrumentClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(headerRayID, "8f0000000000abcd-AMS")
+		w.Header().Set(headerRetryAfter, "7")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"success":false,"errors":[{"code":971,"message":"Please wait"}]}`))
+	}))
+	defer srv.Close()
+
+	c, ok := instrumentClient(cloudflare.NewClient(option.WithBaseURL(srv.URL), option.WithAPIToken("token"), option.WithMaxRetries(0))).(*cloudflare.Client)
+	if !ok {
+		t.Fatal("instrumentClient(...): want a Cloudflare client")
+	}
+	ctx, calls := withAPICalls(context.Background())
+	if err := c.Get(ctx, "zones", nil, nil); err == nil {
+		t.Fatal("Get(...): want error, got none")
+	}
+	want := []apiResponse{{
+		Method:     http.MethodGet,
+		StatusCode: http.StatusTooManyRequests,
+		RayID:      "8f0000000000abcd-AMS",
+		RetryAfter: 7 * time.Second,
+		Errors:     []apiError{{Code: 971, Message: "Please wait"}},
+	}}
+	if diff := cmp.Diff(want, calls.Responses()); diff != "" {
+		t.Errorf("Responses(): -want, +got:\n%s", diff)
+	}
+	if v := instrumentClient("not a client"); v != "not a client" {
+		t.Errorf("instrumentClient(...): want other values returned as is, got %v", v)
+	}
+}
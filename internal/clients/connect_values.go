@@ -0,0 +1,175 @@
+package clients
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// connectValueTTL is how long a value resolved when connecting is kept if the
+// connection fails before it is injected.
+const connectValueTTL = time.Minute
+
+// connectValues hand values resolved when the provider connects to a managed
+// resource, such as content read from a ConfigMap, over to the Terraform
+// configuration injector of the resource, which runs right after in the same
+// connection. Values are stored by the UID of the resource. The injector only
+// has the spec.forProvider of the resource, so it takes the values of the
+// resource connecting with that spec.forProvider, which ConnectInTurn keeps
+// unique. Values are forgotten once taken, when resolving them fails, and
+// after connectValueTTL otherwise.
+type connectValues[V any] struct {
+	mu     sync.Mutex
+	values map[types.UID]connectValue[V]
+}
+
+type connectValue[V any] struct {
+	value  V
+	stored time.Time
+}
+
+// forProviderKey returns the key of the supplied spec.forProvider.
+func forProviderKey(forProvider map[string]any) (string, error) {
+	// Maps are marshalled with sorted keys.
+	b, err := json.Marshal(forProvider)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot marshal spec.forProvider")
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// managedKey returns the key of the spec.forProvider of a managed resource.
+func managedKey(mg resource.Managed) (string, error) {
+	paved, err := fieldpath.PaveObject(mg)
+	if err != nil {
+		return "", err
+	}
+	fp, err := paved.GetValue("spec.forProvider")
+	if err != nil {
+		return "", err
+	}
+	m, _ := fp.(map[string]any)
+	return forProviderKey(m)
+}
+
+// store stores the value resolved for the supplied managed resource.
+func (c *connectValues[V]) store(mg resource.Managed, v V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for uid, cv := range c.values {
+		if now.Sub(cv.stored) > connectValueTTL {
+			delete(c.values, uid)
+		}
+	}
+	if c.values == nil {
+		c.values = map[types.UID]connectValue[V]{}
+	}
+	c.values[mg.GetUID()] = connectValue[V]{value: v, stored: now}
+}
+
+// forget forgets the value resolved for the supplied managed resource.
+func (c *connectValues[V]) forget(mg resource.Managed) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.values, mg.GetUID())
+}
+
+// take returns and forgets the value resolved for the managed resource that
+// is connecting with the supplied spec.forProvider.
+func (c *connectValues[V]) take(forProvider map[string]any) (V, bool) {
+	var zero V
+	key, err := forProviderKey(forProvider)
+	if err != nil {
+		return zero, false
+	}
+	uid, ok := connecting.connected(key)
+	if !ok {
+		return zero, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cv, ok := c.values[uid]
+	if !ok || time.Since(cv.stored) > connectValueTTL {
+		return zero, false
+	}
+	delete(c.values, uid)
+	return cv.value, true
+}
+
+// connectingResources are the managed resources that are connecting, by the
+// key of their spec.forProvider.
+type connectingResources struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	uids map[string]types.UID
+}
+
+// connecting are the managed resources connecting through ConnectInTurn.
+var connecting = newConnectingResources()
+
+func newConnectingResources() *connectingResources {
+	c := &connectingResources{uids: map[string]types.UID{}}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// connect waits until no other managed resource with the same
+// spec.forProvider is connecting, and records that the supplied one is. The
+// returned function records that it connected.
+func (c *connectingResources) connect(mg resource.Managed) (func(), error) {
+	key, err := managedKey(mg)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		if _, ok := c.uids[key]; !ok {
+			break
+		}
+		c.cond.Wait()
+	}
+	c.uids[key] = mg.GetUID()
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.uids, key)
+		c.cond.Broadcast()
+	}, nil
+}
+
+// connected returns the UID of the managed resource connecting with the
+// supplied key of its spec.forProvider.
+func (c *connectingResources) connected(key string) (types.UID, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	uid, ok := c.uids[key]
+	return uid, ok
+}
+
+// ConnectInTurn returns an external connector that connects to one managed
+// resource at a time of those with the same spec.forProvider, such as two
+// Workers scripts in different namespaces that read their content from
+// ConfigMaps of the same name. The Terraform configuration injector of each
+// thus takes the values resolved for that resource.
+func ConnectInTurn(c managed.ExternalConnector) managed.ExternalConnector {
+	return managed.ExternalConnectorFn(func(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+		done, err := connecting.connect(mg)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot get the spec.forProvider of the managed resource")
+		}
+		defer done()
+		return c.Connect(ctx, mg)
+	})
+}
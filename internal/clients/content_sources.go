@@ -0,0 +1,259 @@
+package clients
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// maxContentBytes is the largest content read from a source. Workers
+	// scripts are limited to 10 MB after compression.
+	maxContentBytes = 25 << 20
+
+	errContentSource = "cannot read content source"
+)
+
+// contentDir holds the content read from sources, named by its SHA-256 hash.
+var contentDir = filepath.Join(os.TempDir(), "provider-cloudflare", "content")
+
+// contentMaxAge is how long content that is no longer read from a source is
+// kept, long enough for the asynchronous changes deploying it to finish.
+const contentMaxAge = time.Hour
+
+// resolvedContent are the local copies of the content of the contentFrom
+// sources of a Workers resource.
+type resolvedContent struct {
+	// file and sum are the copy and hash of the content of the resource.
+	file, sum string
+
+	// modules are the copies of the content of its modules, by index.
+	modules map[int]string
+}
+
+// resolvedContents hand the copies over to InjectContentSources.
+var resolvedContents connectValues[resolvedContent]
+
+// resolveContentSources reads the content of the contentFrom sources of a
+// Workers resource into local copies, which InjectContentSources points the
+// content_file argument next to each of them at. The managed resource is not
+// changed, so the paths of the copies are never stored with it.
+func resolveContentSources(ctx context.Context, kube client.Client, mg resource.Managed, registries ociRegistries) error {
+	rc, resolved, err := readContentSources(ctx, kube, mg, registries)
+	if err != nil || !resolved {
+		resolvedContents.forget(mg)
+		return err
+	}
+	resolvedContents.store(mg, rc)
+	return nil
+}
+
+func readContentSources(ctx context.Context, kube client.Client, mg resource.Managed, registries ociRegistries) (resolvedContent, bool, error) {
+	rc := resolvedContent{modules: map[int]string{}}
+	paved, err := fieldpath.PaveObject(mg)
+	if err != nil {
+		return rc, false, errors.Wrap(err, errContentSource)
+	}
+	parents := []string{"spec.forProvider"}
+	if modules, err := paved.GetValue("spec.forProvider.modules"); err == nil {
+		if l, ok := modules.([]any); ok {
+			for i := range l {
+				parents = append(parents, fmt.Sprintf("spec.forProvider.modules[%d]", i))
+			}
+		}
+	}
+	resolved := false
+	for i, p := range parents {
+		src, err := paved.GetValue(p + ".contentFrom")
+		if err != nil || src == nil {
+			continue
+		}
+		for _, inline := range []string{"content", "contentBase64"} {
+			if v, err := paved.GetString(p + "." + inline); err == nil && v != "" {
+				return rc, false, errors.Errorf("%s: %s.%s and %s.contentFrom are mutually exclusive", errContentSource, p, inline, p)
+			}
+		}
+		m, ok := src.(map[string]any)
+		if !ok {
+			return rc, false, errors.Errorf("%s: %s.contentFrom is not an object", errContentSource, p)
+		}
+		content, err := readContentSource(ctx, kube, mg.GetNamespace(), m, registries)
+		if err != nil {
+			return rc, false, errors.Wrapf(err, "%s %s.contentFrom", errContentSource, p)
+		}
+		file, sum, err := storeContent(content)
+		if err != nil {
+			return rc, false, errors.Wrap(err, errContentSource)
+		}
+		if i == 0 {
+			rc.file, rc.sum = file, sum
+		} else {
+			rc.modules[i-1] = file
+		}
+		resolved = true
+	}
+	return rc, resolved, nil
+}
+
+// InjectContentSources points the content_file argument of a Workers resource
+// with a contentFrom source, and of each of its modules with one, at the
+// copy of the content read when the provider connected. The content_sha256
+// argument is set to its hash where there is one, so that changed content is
+// deployed.
+func InjectContentSources(jsonMap map[string]any, tfMap map[string]any) error {
+	modules, _ := jsonMap["modules"].([]any)
+	sourced := jsonMap["contentFrom"] != nil
+	for _, m := range modules {
+		if m, ok := m.(map[string]any); ok && m["contentFrom"] != nil {
+			sourced = true
+		}
+	}
+	if !sourced {
+		return nil
+	}
+	rc, ok := resolvedContents.take(jsonMap)
+	if !ok {
+		return errors.New(errContentSource + ": the content was not read when connecting")
+	}
+	if rc.file != "" {
+		tfMap["content_file"] = rc.file
+		tfMap["content_sha256"] = rc.sum
+	}
+	tfModules, _ := tfMap["modules"].([]any)
+	for i, file := range rc.modules {
+		if i >= len(tfModules) {
+			continue
+		}
+		if m, ok := tfModules[i].(map[string]any); ok {
+			m["content_file"] = file
+		}
+	}
+	return nil
+}
+
+// readContentSource reads the content of a contentFrom source of a managed
+// resource in the supplied namespace, which is empty for cluster-scoped
+// resources. OCI artifacts are only pulled from the supplied registries.
+func readContentSource(ctx context.Context, kube client.Client, namespace string, src map[string]any, registries ociRegistries) ([]byte, error) {
+	var sources []string
+	for k, v := range src {
+		if v != nil {
+			sources = append(sources, k)
+		}
+	}
+	if len(sources) != 1 {
+		return nil, errors.Errorf("exactly one of configMapKeyRef, secretKeyRef and oci must be set, got %d", len(sources))
+	}
+	ref, _ := src[sources[0]].(map[string]any)
+	str := func(k string) string {
+		s, _ := ref[k].(string)
+		return s
+	}
+	switch sources[0] {
+	case "configMapKeyRef":
+		nn, err := sourceName(namespace, str("namespace"), str("name"))
+		if err != nil {
+			return nil, err
+		}
+		cm := &corev1.ConfigMap{}
+		if err := kube.Get(ctx, nn, cm); err != nil {
+			return nil, errors.Wrapf(err, "cannot get ConfigMap %s", nn)
+		}
+		if v, ok := cm.Data[str("key")]; ok {
+			return []byte(v), nil
+		}
+		if v, ok := cm.BinaryData[str("key")]; ok {
+			return v, nil
+		}
+		return nil, errors.Errorf("ConfigMap %s has no key %q", nn, str("key"))
+	case "secretKeyRef":
+		nn, err := sourceName(namespace, str("namespace"), str("name"))
+		if err != nil {
+			return nil, err
+		}
+		s := &corev1.Secret{}
+		if err := kube.Get(ctx, nn, s); err != nil {
+			return nil, errors.Wrapf(err, "cannot get Secret %s", nn)
+		}
+		v, ok := s.Data[str("key")]
+		if !ok {
+			return nil, errors.Errorf("Secret %s has no key %q", nn, str("key"))
+		}
+		return v, nil
+	case "oci":
+		insecure, _ := ref["insecure"].(bool)
+		return pullOCILayer(ctx, registries, str("reference"), str("path"), insecure)
+	default:
+		return nil, errors.Errorf("unknown content source %q", sources[0])
+	}
+}
+
+// sourceName returns the name of a ConfigMap or Secret a managed resource
+// reads from. Namespaced resources may only read from their own namespace.
+func sourceName(mgNamespace, namespace, name string) (types.NamespacedName, error) {
+	switch {
+	case mgNamespace == "" && namespace == "":
+		return types.NamespacedName{}, errors.Errorf("the namespace of %s is required for cluster-scoped resources", name)
+	case mgNamespace != "" && namespace != "" && namespace != mgNamespace:
+		return types.NamespacedName{}, errors.Errorf("cannot read %s from namespace %s, namespaced resources may only read from their own namespace %s", name, namespace, mgNamespace)
+	case namespace == "":
+		namespace = mgNamespace
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+// storeContent writes content to a file named by its SHA-256 hash, unless it
+// exists already, and returns its path and the hash. Content that was not
+// stored for contentMaxAge is removed.
+func storeContent(content []byte) (string, string, error) {
+	h := sha256.Sum256(content)
+	sum := hex.EncodeToString(h[:])
+	file := filepath.Join(contentDir, sum)
+	now := time.Now()
+	pruneContent(now)
+	if err := os.Chtimes(file, now, now); err == nil {
+		return file, sum, nil
+	}
+	if err := os.MkdirAll(contentDir, 0o700); err != nil {
+		return "", "", errors.Wrap(err, "cannot create content directory")
+	}
+	tmp, err := os.CreateTemp(contentDir, sum+".*")
+	if err != nil {
+		return "", "", errors.Wrap(err, "cannot store content")
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return "", "", errors.Wrap(err, "cannot store content")
+	}
+	if err := tmp.Close(); err != nil {
+		return "", "", errors.Wrap(err, "cannot store content")
+	}
+	return file, sum, errors.Wrap(os.Rename(tmp.Name(), file), "cannot store content")
+}
+
+// pruneContent removes the content that was last stored before contentMaxAge.
+func pruneContent(now time.Time) {
+	entries, err := os.ReadDir(contentDir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || now.Sub(info.ModTime()) < contentMaxAge {
+			continue
+		}
+		_ = os.Remove(filepath.Join(contentDir, e.Name()))
+	}
+}
@@ -0,0 +1,67 @@
+package clients
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource/fake"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// parameterized is a managed resource with the supplied parameters.
+type parameterized struct {
+	fake.Managed
+	Spec struct {
+		ForProvider map[string]any `json:"forProvider"`
+	} `json:"spec"`
+}
+
+func TestContentSources(t *testing.T) {
+	contentDir = t.TempDir()
+	kube := kfake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "app"},
+		Data:       map[string]string{"worker.js": "export default {}"},
+	}).Build()
+	params := func() map[string]any {
+		return map[string]any{
+			"scriptName":  "app",
+			"contentFrom": map[string]any{"configMapKeyRef": map[string]any{"name": "app", "key": "worker.js"}},
+		}
+	}
+	forProvider := params()
+	mg := &parameterized{}
+	mg.SetNamespace("team")
+	mg.Spec.ForProvider = params()
+	done, err := connecting.connect(mg)
+	if err != nil {
+		t.Fatalf("connect(...): unexpected error: %v", err)
+	}
+	defer done()
+
+	if err := resolveContentSources(context.Background(), kube, mg, nil); err != nil {
+		t.Fatalf("resolveContentSources(...): unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(params(), mg.Spec.ForProvider); diff != "" {
+		t.Errorf("resolveContentSources(...): the managed resource changed: -want, +got:\n%s", diff)
+	}
+
+	tfMap := map[string]any{"script_name": "app"}
+	if err := InjectContentSources(forProvider, tfMap); err != nil {
+		t.Fatalf("InjectContentSources(...): unexpected error: %v", err)
+	}
+	file, _ := tfMap["content_file"].(string)
+	if b, err := os.ReadFile(file); err != nil || string(b) != "export default {}" {
+		t.Errorf("InjectContentSources(...): content_file %q holds %q, %v", file, b, err)
+	}
+	if tfMap["content_sha256"] == nil {
+		t.Errorf("InjectContentSources(...): content_sha256 is not set")
+	}
+	// The content is handed over once.
+	if err := InjectContentSources(forProvider, map[string]any{}); err == nil {
+		t.Errorf("InjectContentSources(...): want error injecting content again")
+	}
+}
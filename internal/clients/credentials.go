@@ -0,0 +1,86 @@
+package clients
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// dotenvCredentials maps the variables of dotenv credentials, which are named
+// like the environment variables of the Terraform provider, to the keys of
+// JSON credentials.
+var dotenvCredentials = map[string]string{
+	"CLOUDFLARE_API_TOKEN": "api_token",
+	"CLOUDFLARE_API_KEY":   "api_key",
+	"CLOUDFLARE_EMAIL":     "email",
+	"CLOUDFLARE_BASE_URL":  "base_url",
+}
+
+// parseCredentials parses ProviderConfig credentials, which are either a JSON
+// object or a dotenv file such as one published by an API token.
+func parseCredentials(data []byte) (map[string]string, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] == '{' {
+		creds := map[string]string{}
+		if err := json.Unmarshal(data, &creds); err != nil {
+			return nil, errors.Wrap(err, errUnmarshalCredentials)
+		}
+		return creds, nil
+	}
+	return parseDotenvCredentials(data)
+}
+
+// parseDotenvCredentials parses credentials given as KEY=value lines. Blank
+// lines, comments and an export prefix are allowed, and values may be quoted.
+// Unknown variables are ignored.
+func parseDotenvCredentials(data []byte) (map[string]string, error) {
+	creds := map[string]string{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			return nil, errors.Errorf("%s: line %d is not KEY=value", errParseDotenv, n)
+		}
+		v = strings.TrimSpace(v)
+		switch {
+		case strings.HasPrefix(v, `"`):
+			uq, err := strconv.Unquote(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s: line %d", errParseDotenv, n)
+			}
+			v = uq
+		case len(v) >= 2 && strings.HasPrefix(v, "'") && strings.HasSuffix(v, "'"):
+			v = v[1 : len(v)-1]
+		}
+		if key, ok := dotenvCredentials[strings.TrimSpace(k)]; ok {
+			creds[key] = v
+		}
+	}
+	return creds, errors.Wrap(sc.Err(), errParseDotenv)
+}
+
+// APITokenConnectionDetails returns the value of an API token as ProviderConfig
+// credentials, both as JSON, under credentials, and as a dotenv file, under
+// credentials.env. Either can be referenced by the credentials secretRef of a
+// ProviderConfig.
+func APITokenConnectionDetails(attr map[string]any) (map[string][]byte, error) {
+	token, _ := attr["value"].(string)
+	if token == "" {
+		return nil, nil
+	}
+	b, err := json.Marshal(map[string]string{"api_token": token})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot encode API token credentials")
+	}
+	return map[string][]byte{
+		"credentials":     b,
+		"credentials.env": []byte("CLOUDFLARE_API_TOKEN=" + token + "\n"),
+	}, nil
+}
@@ -0,0 +1,467 @@
+package clients
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	fwprovider "github.com/hashicorp/terraform-plugin-framework/provider"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	rschema "github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/pkg/errors"
+)
+
+// operation is the kind of change a managed resource applies through the
+// framework provider, or a read of its state.
+type operation string
+
+const (
+	operationCreate operation = "create"
+	operationUpdate operation = "update"
+	operationDelete operation = "delete"
+	operationRead   operation = "read"
+)
+
+// applyRequest describes a change that is about to be applied to a
+// framework resource. Prior is null for creations and Planned is null for
+// deletions.
+type applyRequest struct {
+	Operation operation
+	TypeName  string
+	Prior     tftypes.Value
+	Planned   tftypes.Value
+
+	// Sensitive reports whether the attribute at the given path is marked
+	// sensitive in the resource schema.
+	Sensitive func(*tftypes.AttributePath) bool
+}
+
+// A changeHold holds back the changes to a managed resource. Holds are
+// consulted when a change is planned, which the managed reconciler does
+// while it observes the resource, and again before a change is applied.
+type changeHold interface {
+	// plan records whether the planned change is held in the conditions of
+	// the managed resource, and reports whether it is.
+	plan(req applyRequest, now time.Time) (bool, error)
+
+	// held returns why the change may not be applied at the supplied time,
+	// or nil if it may.
+	held(req applyRequest, now time.Time) error
+}
+
+// applyGuard is consulted before a change is passed to the wrapped framework
+// resource. Returning an error skips the change and reports the error as a
+// diagnostic instead.
+type applyGuard func(ctx context.Context, req applyRequest) error
+
+// applyResult is the outcome of a change applied to a framework resource.
+type applyResult struct {
+	// Calls are the Cloudflare API calls made while applying the change.
+	Calls *apiCalls
+
+	// Diagnostics are the diagnostics the wrapped resource reported.
+	// Observers may add to them.
+	Diagnostics *diag.Diagnostics
+}
+
+// applyObserver is informed of the outcome of every change that was passed to
+// the wrapped framework resource.
+type applyObserver func(ctx context.Context, req applyRequest, res applyResult)
+
+// frameworkProvider wraps a Terraform plugin framework provider so that every
+// resource it serves runs through the configured change holds, apply guards
+// and observers, and its Cloudflare client records API responses.
+type frameworkProvider struct {
+	fwprovider.Provider
+
+	holds     []changeHold
+	guards    []applyGuard
+	observers []applyObserver
+}
+
+func newFrameworkProvider(p fwprovider.Provider, holds []changeHold, guards []applyGuard, observers []applyObserver) fwprovider.Provider {
+	return withProviderInterfaces(&frameworkProvider{Provider: p, holds: holds, guards: guards, observers: observers})
+}
+
+// Configure configures the wrapped provider and then makes the Cloudflare
+// client it hands to resources and data sources send its requests through
+// the instrumented HTTP client.
+func (p *frameworkProvider) Configure(ctx context.Context, req fwprovider.ConfigureRequest, resp *fwprovider.ConfigureResponse) {
+	p.Provider.Configure(ctx, req, resp)
+	resp.ResourceData = instrumentClient(resp.ResourceData)
+	resp.DataSourceData = instrumentClient(resp.DataSourceData)
+	resp.EphemeralResourceData = instrumentClient(resp.EphemeralResourceData)
+}
+
+// Resources returns the resources of the wrapped provider, each wrapped so
+// that holds and apply guards are consulted before and observers informed
+// after any change is made.
+func (p *frameworkProvider) Resources(ctx context.Context) []func() fwresource.Resource {
+	md := &fwprovider.MetadataResponse{}
+	p.Metadata(ctx, fwprovider.MetadataRequest{}, md)
+	inner := p.Provider.Resources(ctx)
+	wrapped := make([]func() fwresource.Resource, 0, len(inner))
+	for _, newResource := range inner {
+		wrapped = append(wrapped, func() fwresource.Resource {
+			return wrapFrameworkResource(ctx, newResource(), md.TypeName, p)
+		})
+	}
+	return wrapped
+}
+
+func wrapFrameworkResource(ctx context.Context, r fwresource.Resource, providerTypeName string, p *frameworkProvider) fwresource.Resource {
+	md := &fwresource.MetadataResponse{}
+	r.Metadata(ctx, fwresource.MetadataRequest{ProviderTypeName: providerTypeName}, md)
+	sch := &fwresource.SchemaResponse{}
+	r.Schema(ctx, fwresource.SchemaRequest{}, sch)
+	w := &frameworkResource{Resource: r, typeName: md.TypeName, schema: sch.Schema, holds: p.holds, guards: p.guards, observers: p.observers}
+	// The framework server detects optional behaviour, such as import and
+	// identity support, through type assertions, so only advertise what the
+	// wrapped resource implements.
+	return withResourceInterfaces(w)
+}
+
+// frameworkResource forwards every call to the wrapped resource, consulting
+// the change holds when a change is planned and before it is applied, the
+// apply guards before and the observers after Create, Read, Update and
+// Delete.
+type frameworkResource struct {
+	fwresource.Resource
+
+	typeName  string
+	schema    rschema.Schema
+	holds     []changeHold
+	guards    []applyGuard
+	observers []applyObserver
+}
+
+func (r *frameworkResource) request(ctx context.Context, op operation, prior, planned tftypes.Value) applyRequest {
+	return applyRequest{
+		Operation: op,
+		TypeName:  r.typeName,
+		Prior:     prior,
+		Planned:   planned,
+		Sensitive: func(p *tftypes.AttributePath) bool {
+			a, err := r.schema.AttributeAtTerraformPath(ctx, p)
+			return err == nil && a.IsSensitive()
+		},
+	}
+}
+
+func (r *frameworkResource) guard(ctx context.Context, req applyRequest) error {
+	now := time.Now()
+	for _, h := range r.holds {
+		if req.Operation == operationRead {
+			// Holds only hold back changes.
+			break
+		}
+		if err := h.held(req, now); err != nil {
+			return err
+		}
+	}
+	for _, g := range r.guards {
+		if err := g(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply passes a change to the wrapped resource through fn unless a hold or
+// guard objects, and informs the observers of its outcome.
+func (r *frameworkResource) apply(ctx context.Context, req applyRequest, diags *diag.Diagnostics, fn func(context.Context)) {
+	if err := r.guard(ctx, req); err != nil {
+		diags.AddError(skippedSummary(req.Operation), err.Error())
+		return
+	}
+	ctx, calls := withAPICalls(ctx)
+	fn(ctx)
+	for _, o := range r.observers {
+		o(ctx, req, applyResult{Calls: calls, Diagnostics: diags})
+	}
+}
+
+// An outsideApplier applies the changes the provider makes with the
+// Cloudflare API itself rather than through Terraform, such as syncing
+// Workers KV values, through the apply guards and observers of a managed
+// resource, so that they back off from and report Cloudflare API errors like
+// the changes Terraform applies.
+type outsideApplier struct {
+	guards    []applyGuard
+	observers []applyObserver
+}
+
+// apply applies a change through fn unless a guard objects.
+func (a outsideApplier) apply(ctx context.Context, req applyRequest, fn func(context.Context) error) error {
+	for _, g := range a.guards {
+		if err := g(ctx, req); err != nil {
+			return errors.Wrap(err, skippedSummary(req.Operation))
+		}
+	}
+	var diags diag.Diagnostics
+	ctx, calls := withAPICalls(ctx)
+	if err := fn(ctx); err != nil {
+		diags.AddError(string(req.Operation)+" failed", err.Error())
+	}
+	for _, o := range a.observers {
+		o(ctx, req, applyResult{Calls: calls, Diagnostics: &diags})
+	}
+	if !diags.HasError() {
+		return nil
+	}
+	msgs := make([]string, 0, len(diags))
+	for _, d := range diags.Errors() {
+		msgs = append(msgs, d.Detail())
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+func skippedSummary(op operation) string {
+	switch op {
+	case operationCreate:
+		return "Create skipped"
+	case operationUpdate:
+		return "Update skipped"
+	case operationRead:
+		return "Read skipped"
+	default:
+		return "Delete skipped"
+	}
+}
+
+// Create runs the holds and apply guards and creates the resource if none objects.
+func (r *frameworkResource) Create(ctx context.Context, req fwresource.CreateRequest, resp *fwresource.CreateResponse) {
+	areq := r.request(ctx, operationCreate, tftypes.NewValue(req.Plan.Raw.Type(), nil), req.Plan.Raw)
+	r.apply(ctx, areq, &resp.Diagnostics, func(ctx context.Context) {
+		r.Resource.Create(ctx, req, resp)
+	})
+}
+
+// Update runs the holds and apply guards and updates the resource if none objects.
+func (r *frameworkResource) Update(ctx context.Context, req fwresource.UpdateRequest, resp *fwresource.UpdateResponse) {
+	areq := r.request(ctx, operationUpdate, req.State.Raw, req.Plan.Raw)
+	r.apply(ctx, areq, &resp.Diagnostics, func(ctx context.Context) {
+		r.Resource.Update(ctx, req, resp)
+	})
+}
+
+// Delete runs the holds and apply guards and deletes the resource if none objects.
+func (r *frameworkResource) Delete(ctx context.Context, req fwresource.DeleteRequest, resp *fwresource.DeleteResponse) {
+	areq := r.request(ctx, operationDelete, req.State.Raw, tftypes.NewValue(req.State.Raw.Type(), nil))
+	r.apply(ctx, areq, &resp.Diagnostics, func(ctx context.Context) {
+		r.Resource.Delete(ctx, req, resp)
+	})
+}
+
+// Read runs the apply guards and reads the resource if none objects, so that
+// reads back off from Cloudflare API errors like changes do.
+func (r *frameworkResource) Read(ctx context.Context, req fwresource.ReadRequest, resp *fwresource.ReadResponse) {
+	areq := r.request(ctx, operationRead, req.State.Raw, req.State.Raw)
+	r.apply(ctx, areq, &resp.Diagnostics, func(ctx context.Context) {
+		r.Resource.Read(ctx, req, resp)
+	})
+}
+
+// Configure passes the configured provider client to the wrapped resource.
+func (r *frameworkResource) Configure(ctx context.Context, req fwresource.ConfigureRequest, resp *fwresource.ConfigureResponse) {
+	if c, ok := r.Resource.(fwresource.ResourceWithConfigure); ok {
+		c.Configure(ctx, req, resp)
+	}
+}
+
+// ModifyPlan lets the wrapped resource adjust the plan, if it supports it,
+// and then consults the change holds. An update that is held is planned as
+// no change, so that the managed reconciler reports the resource as up to
+// date rather than failing to apply the update.
+func (r *frameworkResource) ModifyPlan(ctx context.Context, req fwresource.ModifyPlanRequest, resp *fwresource.ModifyPlanResponse) {
+	if m, ok := r.Resource.(fwresource.ResourceWithModifyPlan); ok {
+		m.ModifyPlan(ctx, req, resp)
+	}
+	if len(r.holds) == 0 || resp.Diagnostics.HasError() {
+		return
+	}
+	op := operationUpdate
+	switch {
+	case req.State.Raw.IsNull():
+		op = operationCreate
+	case resp.Plan.Raw.IsNull():
+		op = operationDelete
+	}
+	areq := r.request(ctx, op, req.State.Raw, resp.Plan.Raw)
+	now := time.Now()
+	held := false
+	for _, h := range r.holds {
+		ok, err := h.plan(areq, now)
+		if err != nil {
+			resp.Diagnostics.AddError("Cannot plan held changes", err.Error())
+			return
+		}
+		held = held || ok
+	}
+	if held && op == operationUpdate {
+		resp.Plan.Raw = req.State.Raw.Copy()
+		resp.RequiresReplace = nil
+	}
+}
+
+// The framework server detects optional behaviour through type assertions.
+// Most optional interfaces are only consulted when the framework calls them,
+// so the wrappers implement them all and behave like a value without the
+// interface when the wrapped value lacks it. Only identity support and the
+// provider meta schema change the schema the framework serves, so they are
+// advertised by separate wrappers, and only for values that implement them.
+
+var (
+	_ fwprovider.ProviderWithConfigValidators   = &frameworkProvider{}
+	_ fwprovider.ProviderWithEphemeralResources = &frameworkProvider{}
+	_ fwprovider.ProviderWithFunctions          = &frameworkProvider{}
+	_ fwprovider.ProviderWithValidateConfig     = &frameworkProvider{}
+	_ fwprovider.ProviderWithMetaSchema         = metaSchemaProvider{}
+
+	_ fwresource.ResourceWithConfigure        = &frameworkResource{}
+	_ fwresource.ResourceWithConfigValidators = &frameworkResource{}
+	_ fwresource.ResourceWithImportState      = &frameworkResource{}
+	_ fwresource.ResourceWithModifyPlan       = &frameworkResource{}
+	_ fwresource.ResourceWithMoveState        = &frameworkResource{}
+	_ fwresource.ResourceWithUpgradeState     = &frameworkResource{}
+	_ fwresource.ResourceWithValidateConfig   = &frameworkResource{}
+	_ fwresource.ResourceWithUpgradeIdentity  = &frameworkResource{}
+	_ fwresource.ResourceWithIdentity         = identityResource{}
+)
+
+// withProviderInterfaces advertises the meta schema of the wrapped provider,
+// if it has one.
+func withProviderInterfaces(p *frameworkProvider) fwprovider.Provider {
+	if m, ok := p.Provider.(fwprovider.ProviderWithMetaSchema); ok {
+		return metaSchemaProvider{frameworkProvider: p, m: m}
+	}
+	return p
+}
+
+// ConfigValidators returns the configuration validators of the wrapped
+// provider, if any.
+func (p *frameworkProvider) ConfigValidators(ctx context.Context) []fwprovider.ConfigValidator {
+	if v, ok := p.Provider.(fwprovider.ProviderWithConfigValidators); ok {
+		return v.ConfigValidators(ctx)
+	}
+	return nil
+}
+
+// ValidateConfig validates the configuration with the wrapped provider, if
+// it supports it.
+func (p *frameworkProvider) ValidateConfig(ctx context.Context, req fwprovider.ValidateConfigRequest, resp *fwprovider.ValidateConfigResponse) {
+	if v, ok := p.Provider.(fwprovider.ProviderWithValidateConfig); ok {
+		v.ValidateConfig(ctx, req, resp)
+	}
+}
+
+// EphemeralResources returns the ephemeral resources of the wrapped provider,
+// if any.
+func (p *frameworkProvider) EphemeralResources(ctx context.Context) []func() ephemeral.EphemeralResource {
+	if e, ok := p.Provider.(fwprovider.ProviderWithEphemeralResources); ok {
+		return e.EphemeralResources(ctx)
+	}
+	return nil
+}
+
+// Functions returns the functions of the wrapped provider, if any.
+func (p *frameworkProvider) Functions(ctx context.Context) []func() function.Function {
+	if f, ok := p.Provider.(fwprovider.ProviderWithFunctions); ok {
+		return f.Functions(ctx)
+	}
+	return nil
+}
+
+// A metaSchemaProvider is a framework provider whose wrapped provider has a
+// meta schema.
+type metaSchemaProvider struct {
+	*frameworkProvider
+
+	m fwprovider.ProviderWithMetaSchema
+}
+
+func (p metaSchemaProvider) MetaSchema(ctx context.Context, req fwprovider.MetaSchemaRequest, resp *fwprovider.MetaSchemaResponse) {
+	p.m.MetaSchema(ctx, req, resp)
+}
+
+// withResourceInterfaces advertises the identity of the wrapped resource, if
+// it has one.
+func withResourceInterfaces(r *frameworkResource) fwresource.Resource {
+	if i, ok := r.Resource.(fwresource.ResourceWithIdentity); ok {
+		return identityResource{frameworkResource: r, i: i}
+	}
+	return r
+}
+
+// ConfigValidators returns the configuration validators of the wrapped
+// resource, if any.
+func (r *frameworkResource) ConfigValidators(ctx context.Context) []fwresource.ConfigValidator {
+	if v, ok := r.Resource.(fwresource.ResourceWithConfigValidators); ok {
+		return v.ConfigValidators(ctx)
+	}
+	return nil
+}
+
+// ValidateConfig validates the configuration with the wrapped resource, if
+// it supports it.
+func (r *frameworkResource) ValidateConfig(ctx context.Context, req fwresource.ValidateConfigRequest, resp *fwresource.ValidateConfigResponse) {
+	if v, ok := r.Resource.(fwresource.ResourceWithValidateConfig); ok {
+		v.ValidateConfig(ctx, req, resp)
+	}
+}
+
+// ImportState imports the resource with the wrapped resource, which reports
+// the error the framework reports for resources without import otherwise.
+func (r *frameworkResource) ImportState(ctx context.Context, req fwresource.ImportStateRequest, resp *fwresource.ImportStateResponse) {
+	if i, ok := r.Resource.(fwresource.ResourceWithImportState); ok {
+		i.ImportState(ctx, req, resp)
+		return
+	}
+	resp.Diagnostics.AddError(
+		"Resource Import Not Implemented",
+		"This resource does not support import. Please contact the provider developer for additional information.",
+	)
+}
+
+// MoveState returns the state movers of the wrapped resource, if any. The
+// framework cannot move state without them.
+func (r *frameworkResource) MoveState(ctx context.Context) []fwresource.StateMover {
+	if m, ok := r.Resource.(fwresource.ResourceWithMoveState); ok {
+		return m.MoveState(ctx)
+	}
+	return nil
+}
+
+// UpgradeState returns the state upgraders of the wrapped resource, if any.
+// The framework cannot upgrade state without them.
+func (r *frameworkResource) UpgradeState(ctx context.Context) map[int64]fwresource.StateUpgrader {
+	if u, ok := r.Resource.(fwresource.ResourceWithUpgradeState); ok {
+		return u.UpgradeState(ctx)
+	}
+	return nil
+}
+
+// UpgradeIdentity returns the identity upgraders of the wrapped resource, if
+// any. The framework cannot upgrade identities without them.
+func (r *frameworkResource) UpgradeIdentity(ctx context.Context) map[int64]fwresource.IdentityUpgrader {
+	if u, ok := r.Resource.(fwresource.ResourceWithUpgradeIdentity); ok {
+		return u.UpgradeIdentity(ctx)
+	}
+	return nil
+}
+
+// An identityResource is a framework resource whose wrapped resource has an
+// identity.
+type identityResource struct {
+	*frameworkResource
+
+	i fwresource.ResourceWithIdentity
+}
+
+func (r identityResource) IdentitySchema(ctx context.Context, req fwresource.IdentitySchemaRequest, resp *fwresource.IdentitySchemaResponse) {
+	r.i.IdentitySchema(ctx, req, resp)
+}
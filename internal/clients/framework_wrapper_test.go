@@ -0,0 +1,108 @@
+package clients
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	fwprovider "github.com/hashicorp/terraform-plugin-framework/provider"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+)
+
+// identifiedResource only implements an identity, of the optional interfaces.
+type identifiedResource struct {
+	recordResource
+}
+
+func (identifiedResource) IdentitySchema(context.Context, fwresource.IdentitySchemaRequest, *fwresource.IdentitySchemaResponse) {
+}
+
+func TestWrapFrameworkResourceInterfaces(t *testing.T) {
+	p := &frameworkProvider{guards: []applyGuard{func(context.Context, applyRequest) error { return nil }}}
+	cases := map[string]struct {
+		r            fwresource.Resource
+		wantIdentity bool
+	}{
+		"WithoutIdentity": {
+			r: recordResource{},
+		},
+		"WithIdentity": {
+			r:            identifiedResource{},
+			wantIdentity: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := wrapFrameworkResource(context.Background(), tc.r, "cloudflare", p)
+			if got := is[fwresource.ResourceWithIdentity](r); got != tc.wantIdentity {
+				t.Errorf("wrapFrameworkResource(...): want identity %t, got %t", tc.wantIdentity, got)
+			}
+			// Import is always advertised, and fails like it does for
+			// resources without import.
+			i, ok := r.(fwresource.ResourceWithImportState)
+			if !ok {
+				t.Fatalf("wrapFrameworkResource(...): want import advertised")
+			}
+			resp := &fwresource.ImportStateResponse{}
+			i.ImportState(context.Background(), fwresource.ImportStateRequest{}, resp)
+			if !resp.Diagnostics.HasError() {
+				t.Errorf("ImportState(...): want error importing a resource without import")
+			}
+		})
+	}
+}
+
+// functionsProvider only implements functions, of the optional interfaces.
+type functionsProvider struct {
+	fwprovider.Provider
+}
+
+func (functionsProvider) Functions(context.Context) []func() function.Function {
+	return []func() function.Function{nil}
+}
+
+// metaProvider only implements a meta schema, of the optional interfaces.
+type metaProvider struct {
+	fwprovider.Provider
+}
+
+func (metaProvider) MetaSchema(context.Context, fwprovider.MetaSchemaRequest, *fwprovider.MetaSchemaResponse) {
+}
+
+func TestNewFrameworkProviderInterfaces(t *testing.T) {
+	guards := []applyGuard{func(context.Context, applyRequest) error { return nil }}
+	cases := map[string]struct {
+		p              fwprovider.Provider
+		wantMetaSchema bool
+		wantFunctions  int
+	}{
+		"Functions": {
+			p:             functionsProvider{},
+			wantFunctions: 1,
+		},
+		"MetaSchema": {
+			p:              metaProvider{},
+			wantMetaSchema: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := newFrameworkProvider(tc.p, nil, guards, nil)
+			if got := is[fwprovider.ProviderWithMetaSchema](p); got != tc.wantMetaSchema {
+				t.Errorf("newFrameworkProvider(...): want meta schema %t, got %t", tc.wantMetaSchema, got)
+			}
+			f, ok := p.(fwprovider.ProviderWithFunctions)
+			if !ok {
+				t.Fatalf("newFrameworkProvider(...): want functions advertised")
+			}
+			if got := len(f.Functions(context.Background())); got != tc.wantFunctions {
+				t.Errorf("Functions(...): want %d functions, got %d", tc.wantFunctions, got)
+			}
+		})
+	}
+}
+
+func is[T any](v any) bool {
+	_, ok := v.(T)
+	return ok
+}
@@ -0,0 +1,143 @@
+package clients
+
+import (
+	"context"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	ujresource "github.com/crossplane/upjet/v2/pkg/resource"
+	"github.com/pkg/errors"
+)
+
+// A heldError reports that a change hold held back a change.
+type heldError struct {
+	msg string
+}
+
+func (e heldError) Error() string {
+	return e.msg
+}
+
+// isHeld reports whether err reports a change that a change hold held back.
+func isHeld(err error) bool {
+	return errors.As(err, &heldError{})
+}
+
+// heldChanges hands the change holds the Terraform setup of a managed
+// resource configured over to the external client of the same connection.
+type heldChanges struct {
+	holds []changeHold
+}
+
+type heldChangesKey struct{}
+
+// setHeldChanges hands the supplied holds over to the external client
+// connecting with ctx, if it was connected through HoldChanges.
+func setHeldChanges(ctx context.Context, holds []changeHold) {
+	if h, ok := ctx.Value(heldChangesKey{}).(*heldChanges); ok {
+		h.holds = holds
+	}
+}
+
+// held returns why the supplied change to a managed resource may not be
+// applied now, or nil if it may.
+func (h *heldChanges) held(mg resource.Managed, op operation) error {
+	req := applyRequest{Operation: op}
+	if tr, ok := mg.(ujresource.Terraformed); ok {
+		req.TypeName = tr.GetTerraformResourceType()
+	}
+	now := time.Now()
+	for _, hold := range h.holds {
+		if err := hold.held(heldRequest(mg, req), now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HoldChanges returns an external connector whose clients do not create or
+// delete external resources while a change hold, such as plan-only mode or a
+// closed change window, holds the change back. A held creation is observed
+// as an up to date resource, and a held deletion as requested, so that the
+// managed reconciler waits for the hold to end, as it does for held updates,
+// rather than backing off from an error. The holds record why the change is
+// held in the conditions of the managed resource when it is observed.
+// Resources whose Terraform setup is held, such as those of a paused
+// ProviderConfig, are neither observed nor changed.
+func HoldChanges(c managed.ExternalConnector) managed.ExternalConnector {
+	return managed.ExternalConnectorFn(func(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+		h := &heldChanges{}
+		ext, err := c.Connect(context.WithValue(ctx, heldChangesKey{}, h), mg)
+		if isHeld(err) {
+			return heldClient{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &holdingClient{ExternalClient: ext, held: h}, nil
+	})
+}
+
+// A holdingClient is an external client that holds back held creations and
+// deletions.
+type holdingClient struct {
+	managed.ExternalClient
+
+	held *heldChanges
+}
+
+// Observe observes the external resource, which is reported as up to date
+// if it does not exist and its creation is held.
+func (c *holdingClient) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	o, err := c.ExternalClient.Observe(ctx, mg)
+	if err != nil || o.ResourceExists || meta.WasDeleted(mg) {
+		return o, err
+	}
+	if isHeld(c.held.held(mg, operationCreate)) {
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+	}
+	return o, nil
+}
+
+// Create creates the external resource unless its creation is held.
+func (c *holdingClient) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	if isHeld(c.held.held(mg, operationCreate)) {
+		return managed.ExternalCreation{}, nil
+	}
+	return c.ExternalClient.Create(ctx, mg)
+}
+
+// Delete deletes the external resource unless its deletion is held.
+func (c *holdingClient) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
+	if isHeld(c.held.held(mg, operationDelete)) {
+		return managed.ExternalDelete{}, nil
+	}
+	return c.ExternalClient.Delete(ctx, mg)
+}
+
+// A heldClient is the external client of a managed resource whose Terraform
+// setup is held. It reports the resource as up to date without observing it,
+// and changes nothing.
+type heldClient struct{}
+
+func (heldClient) Observe(context.Context, resource.Managed) (managed.ExternalObservation, error) {
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+}
+
+func (heldClient) Create(context.Context, resource.Managed) (managed.ExternalCreation, error) {
+	return managed.ExternalCreation{}, nil
+}
+
+func (heldClient) Update(context.Context, resource.Managed) (managed.ExternalUpdate, error) {
+	return managed.ExternalUpdate{}, nil
+}
+
+func (heldClient) Delete(context.Context, resource.Managed) (managed.ExternalDelete, error) {
+	return managed.ExternalDelete{}, nil
+}
+
+func (heldClient) Disconnect(context.Context) error {
+	return nil
+}
@@ -0,0 +1,110 @@
+package clients
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource/fake"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// missingClient is an external client of a resource that does not exist,
+// which records the changes it applies.
+type missingClient struct {
+	managed.ExternalClient
+
+	applied []string
+}
+
+func (c *missingClient) Observe(context.Context, resource.Managed) (managed.ExternalObservation, error) {
+	return managed.ExternalObservation{}, nil
+}
+
+func (c *missingClient) Create(context.Context, resource.Managed) (managed.ExternalCreation, error) {
+	c.applied = append(c.applied, "create")
+	return managed.ExternalCreation{}, nil
+}
+
+func (c *missingClient) Delete(context.Context, resource.Managed) (managed.ExternalDelete, error) {
+	c.applied = append(c.applied, "delete")
+	return managed.ExternalDelete{}, nil
+}
+
+func TestHoldChanges(t *testing.T) {
+	cases := map[string]struct {
+		planOnly    bool
+		deleted     bool
+		wantExists  bool
+		wantApplied []string
+	}{
+		"Create": {
+			wantApplied: []string{"create", "delete"},
+		},
+		"HeldCreate": {
+			planOnly:   true,
+			wantExists: true,
+		},
+		"HeldDelete": {
+			planOnly: true,
+			deleted:  true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mg := &fake.Managed{}
+			if tc.deleted {
+				mg.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+			}
+			var holds []changeHold
+			if tc.planOnly {
+				holds = append(holds, &planOnlyHold{rec: event.NewNopRecorder(), mg: mg})
+			}
+			ext := &missingClient{}
+			c := HoldChanges(managed.ExternalConnectorFn(func(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+				// The Terraform setup configures the holds of the resource.
+				setHeldChanges(ctx, holds)
+				return ext, nil
+			}))
+			client, err := c.Connect(context.Background(), mg)
+			if err != nil {
+				t.Fatalf("Connect(...): unexpected error: %v", err)
+			}
+			o, err := client.Observe(context.Background(), mg)
+			if err != nil {
+				t.Fatalf("Observe(...): unexpected error: %v", err)
+			}
+			if o.ResourceExists != tc.wantExists {
+				t.Errorf("Observe(...): want exists %t, got %t", tc.wantExists, o.ResourceExists)
+			}
+			if _, err := client.Create(context.Background(), mg); err != nil {
+				t.Errorf("Create(...): unexpected error: %v", err)
+			}
+			if _, err := client.Delete(context.Background(), mg); err != nil {
+				t.Errorf("Delete(...): unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantApplied, ext.applied); diff != "" {
+				t.Errorf("HoldChanges(...): applied changes: -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHoldChangesHeldSetup(t *testing.T) {
+	c := HoldChanges(managed.ExternalConnectorFn(func(context.Context, resource.Managed) (managed.ExternalClient, error) {
+		return nil, errors.Wrap(heldError{msg: errProviderConfigPaused}, "cannot get terraform setup")
+	}))
+	client, err := c.Connect(context.Background(), &fake.Managed{})
+	if err != nil {
+		t.Fatalf("Connect(...): unexpected error: %v", err)
+	}
+	o, err := client.Observe(context.Background(), &fake.Managed{})
+	if err != nil || !o.ResourceExists || !o.ResourceUpToDate {
+		t.Errorf("Observe(...): want an up to date resource, got %+v, %v", o, err)
+	}
+}
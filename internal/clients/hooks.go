@@ -0,0 +1,21 @@
+package clients
+
+import (
+	"github.com/prolixalias/provider-cloudflare/internal/hooks"
+)
+
+// Hooks returns the implementations of the hooks the resource configuration
+// refers to, for the provider to register when it starts.
+func Hooks() hooks.Hooks {
+	return hooks.Hooks{
+		AccountIDInitializer:                NewAccountIDInitializer,
+		DNSRecordAdoptionInitializer:        NewDNSRecordAdoptionInitializer,
+		TunnelConfigInitializer:             NewTunnelConfigInitializer,
+		ZoneIDByName:                        ZoneIDByName,
+		InjectContentSources:                InjectContentSources,
+		InjectKVValue:                       InjectKVValue,
+		APITokenConnectionDetails:           APITokenConnectionDetails,
+		AccessServiceTokenConnectionDetails: AccessServiceTokenConnectionDetails,
+		TunnelConnectionDetails:             TunnelConnectionDetails,
+	}
+}
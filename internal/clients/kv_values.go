@@ -0,0 +1,254 @@
+package clients
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sort"
+	"unicode/utf8"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// AnnotationKeyKVSyncedKeys lists, as a JSON array, the keys a Workers KV
+	// namespace last synced from its valuesFrom ConfigMap, so that keys
+	// removed from the ConfigMap are removed from the namespace.
+	AnnotationKeyKVSyncedKeys = "cloudflare.upbound.io/kv-synced-keys"
+
+	// AnnotationKeyKVSyncedHash is the SHA-256 hash of the ConfigMap content
+	// a Workers KV namespace last synced, so that unchanged content is not
+	// written again.
+	AnnotationKeyKVSyncedHash = "cloudflare.upbound.io/kv-synced-sha256"
+
+	// maxKVBulkKeys is the largest number of keys the KV bulk API writes or
+	// deletes in one request.
+	maxKVBulkKeys = 10000
+
+	errKVValueSource = "cannot read the valueFrom source of the KV value"
+	errKVSync        = "cannot sync the values of the KV namespace"
+)
+
+// kvValues hand the values read from the valueFrom sources of Workers KV
+// values over to InjectKVValue.
+var kvValues connectValues[string]
+
+// resolveKVValueSource reads the value of the valueFrom source of a Workers
+// KV value, for InjectKVValue to pass it to Terraform.
+func resolveKVValueSource(ctx context.Context, kube client.Client, mg resource.Managed) error {
+	v, err := readKVValueSource(ctx, kube, mg)
+	if err != nil || v == nil {
+		kvValues.forget(mg)
+		return err
+	}
+	kvValues.store(mg, string(v))
+	return nil
+}
+
+func readKVValueSource(ctx context.Context, kube client.Client, mg resource.Managed) ([]byte, error) {
+	paved, err := fieldpath.PaveObject(mg)
+	if err != nil {
+		return nil, errors.Wrap(err, errKVValueSource)
+	}
+	src, err := paved.GetValue("spec.forProvider.valueFrom")
+	if err != nil || src == nil {
+		return nil, nil
+	}
+	if ref, err := paved.GetValue("spec.forProvider.valueSecretRef"); err == nil && ref != nil {
+		return nil, errors.New(errKVValueSource + ": spec.forProvider.valueSecretRef and spec.forProvider.valueFrom are mutually exclusive")
+	}
+	m, ok := src.(map[string]any)
+	if !ok || m["configMapKeyRef"] == nil {
+		return nil, errors.New(errKVValueSource + ": spec.forProvider.valueFrom.configMapKeyRef is required")
+	}
+	v, err := readContentSource(ctx, kube, mg.GetNamespace(), map[string]any{"configMapKeyRef": m["configMapKeyRef"]}, nil)
+	return v, errors.Wrap(err, errKVValueSource)
+}
+
+// InjectKVValue sets the value argument of a Workers KV value that reads it
+// from a ConfigMap to the value read when the provider connected, and removes
+// the value_from argument the provider adds to its Terraform schema.
+func InjectKVValue(jsonMap map[string]any, tfMap map[string]any) error {
+	delete(tfMap, "value_from")
+	if jsonMap["valueFrom"] == nil {
+		return nil
+	}
+	v, ok := kvValues.take(jsonMap)
+	if !ok {
+		return errors.New(errKVValueSource + ": the value was not read when connecting")
+	}
+	tfMap["value"] = v
+	return nil
+}
+
+// A kvPair is a key-value pair written with the KV bulk API.
+type kvPair struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Base64 bool   `json:"base64,omitempty"`
+}
+
+// syncKVValues writes every key of the valuesFrom ConfigMap of an existing
+// Workers KV namespace to the namespace and deletes the keys it synced before
+// that were removed from the ConfigMap. The ConfigMap is only synced when its
+// content changed since the last sync, which is recorded in annotations of
+// the managed resource.
+func syncKVValues(ctx context.Context, kube client.Client, mg resource.Managed, outside outsideApplier) error {
+	paved, err := fieldpath.PaveObject(mg)
+	if err != nil {
+		return errors.Wrap(err, errKVSync)
+	}
+	src, err := paved.GetValue("spec.forProvider.valuesFrom.configMapRef")
+	if err != nil || src == nil {
+		return nil
+	}
+	// Values are synced once the namespace exists, and no longer while it
+	// is deleted or only observed.
+	if meta.GetExternalName(mg) == "" || meta.WasDeleted(mg) || !updatesAllowed(mg) {
+		return nil
+	}
+	account := parameter(paved, "accountId")
+	if account == "" {
+		return errors.New(errKVSync + ": spec.forProvider.accountId is required")
+	}
+	ref, _ := src.(map[string]any)
+	name, _ := ref["name"].(string)
+	namespace, _ := ref["namespace"].(string)
+	nn, err := sourceName(mg.GetNamespace(), namespace, name)
+	if err != nil {
+		return errors.Wrap(err, errKVSync)
+	}
+	cm := &corev1.ConfigMap{}
+	if err := kube.Get(ctx, nn, cm); err != nil {
+		return errors.Wrapf(err, "%s: cannot get ConfigMap %s", errKVSync, nn)
+	}
+	pairs, sum := kvPairs(cm)
+	if mg.GetAnnotations()[AnnotationKeyKVSyncedHash] == sum {
+		return nil
+	}
+
+	removed, err := removedKVKeys(mg.GetAnnotations()[AnnotationKeyKVSyncedKeys], pairs)
+	if err != nil {
+		return errors.Wrap(err, errKVSync)
+	}
+
+	api, err := newManagedAPIClient(ctx, kube, mg)
+	if err != nil {
+		return errors.Wrap(err, errKVSync)
+	}
+	path := "/accounts/" + account + "/storage/kv/namespaces/" + meta.GetExternalName(mg) + "/bulk"
+	// A failed sync backs off until the ConfigMap changes, like a change
+	// Terraform applies backs off until the resource changes.
+	req := applyRequest{
+		Operation: operationUpdate,
+		TypeName:  "cloudflare_workers_kv_namespace",
+		Planned:   tftypes.NewValue(tftypes.String, sum),
+	}
+	err = outside.apply(ctx, req, func(ctx context.Context) error {
+		for i := 0; i < len(pairs); i += maxKVBulkKeys {
+			if err := api.do(ctx, http.MethodPut, path, nil, pairs[i:min(i+maxKVBulkKeys, len(pairs))], nil); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < len(removed); i += maxKVBulkKeys {
+			if err := api.do(ctx, http.MethodPost, path+"/delete", nil, removed[i:min(i+maxKVBulkKeys, len(removed))], nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, errKVSync)
+	}
+
+	names := make([]string, 0, len(pairs))
+	for _, p := range pairs {
+		names = append(names, p.Key)
+	}
+	b, err := json.Marshal(names)
+	if err != nil {
+		return errors.Wrap(err, errKVSync)
+	}
+	return errors.Wrap(annotate(ctx, kube, mg, map[string]any{AnnotationKeyKVSyncedKeys: string(b), AnnotationKeyKVSyncedHash: sum}), errKVSync)
+}
+
+// removedKVKeys returns the keys of the supplied JSON array of keys synced
+// before that are not among the pairs to sync now.
+func removedKVKeys(synced string, pairs []kvPair) ([]string, error) {
+	if synced == "" {
+		return nil, nil
+	}
+	var keys []string
+	if err := json.Unmarshal([]byte(synced), &keys); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse annotation %s", AnnotationKeyKVSyncedKeys)
+	}
+	current := make(map[string]bool, len(pairs))
+	for _, p := range pairs {
+		current[p.Key] = true
+	}
+	var removed []string
+	for _, k := range keys {
+		if !current[k] {
+			removed = append(removed, k)
+		}
+	}
+	return removed, nil
+}
+
+// kvPairs returns the key-value pairs of a ConfigMap, sorted by key, and the
+// SHA-256 hash of their content. Binary data that is not valid UTF-8 is
+// written base64 encoded.
+func kvPairs(cm *corev1.ConfigMap) ([]kvPair, string) {
+	pairs := make([]kvPair, 0, len(cm.Data)+len(cm.BinaryData))
+	for k, v := range cm.Data {
+		pairs = append(pairs, kvPair{Key: k, Value: v})
+	}
+	for k, v := range cm.BinaryData {
+		if utf8.Valid(v) {
+			pairs = append(pairs, kvPair{Key: k, Value: string(v)})
+			continue
+		}
+		pairs = append(pairs, kvPair{Key: k, Value: base64.StdEncoding.EncodeToString(v), Base64: true})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	h := sha256.New()
+	for _, p := range pairs {
+		b, _ := json.Marshal(p)
+		h.Write(b)
+	}
+	return pairs, hex.EncodeToString(h.Sum(nil))
+}
+
+// updatesAllowed reports whether the management policies of a managed
+// resource allow updating its external resource.
+func updatesAllowed(mg resource.Managed) bool {
+	return actionAllowed(mg, xpv1.ManagementActionUpdate)
+}
+
+// createAllowed reports whether the management policies of a managed
+// resource allow creating its external resource.
+func createAllowed(mg resource.Managed) bool {
+	return actionAllowed(mg, xpv1.ManagementActionCreate)
+}
+
+// deleteAllowed reports whether the management policies of a managed
+// resource allow deleting its external resource.
+func deleteAllowed(mg resource.Managed) bool {
+	return actionAllowed(mg, xpv1.ManagementActionDelete)
+}
+
+func actionAllowed(mg resource.Managed, a xpv1.ManagementAction) bool {
+	p := mg.GetManagementPolicies()
+	return len(p) == 0 || slices.Contains(p, xpv1.ManagementActionAll) || slices.Contains(p, a)
+}
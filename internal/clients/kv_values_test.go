@@ -0,0 +1,240 @@
+package clients
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource/fake"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestKVPairs(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		Data:       map[string]string{"b": "2", "a": "1"},
+		BinaryData: map[string][]byte{"text": []byte("3"), "bin": {0xff, 0xfe}},
+	}
+	pairs, sum := kvPairs(cm)
+	want := []kvPair{
+		{Key: "a", Value: "1"},
+		{Key: "b", Value: "2"},
+		{Key: "bin", Value: "//4=", Base64: true},
+		{Key: "text", Value: "3"},
+	}
+	if diff := cmp.Diff(want, pairs); diff != "" {
+		t.Errorf("kvPairs(...): -want, +got:\n%s", diff)
+	}
+
+	cases := map[string]struct {
+		cm       *corev1.ConfigMap
+		wantSame bool
+	}{
+		"Same": {
+			cm:       cm.DeepCopy(),
+			wantSame: true,
+		},
+		"TextAsBinaryData": {
+			cm: &corev1.ConfigMap{
+				Data:       map[string]string{"b": "2", "a": "1", "text": "3"},
+				BinaryData: map[string][]byte{"bin": {0xff, 0xfe}},
+			},
+			wantSame: true,
+		},
+		"ChangedValue": {
+			cm: &corev1.ConfigMap{
+				Data:       map[string]string{"b": "2", "a": "changed"},
+				BinaryData: cm.BinaryData,
+			},
+		},
+		"RemovedKey": {
+			cm: &corev1.ConfigMap{
+				Data:       map[string]string{"a": "1"},
+				BinaryData: cm.BinaryData,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, got := kvPairs(tc.cm); (got == sum) != tc.wantSame {
+				t.Errorf("kvPairs(...): want same hash %t, got %s and %s", tc.wantSame, sum, got)
+			}
+		})
+	}
+}
+
+func TestRemovedKVKeys(t *testing.T) {
+	pairs := []kvPair{{Key: "a"}, {Key: "c"}}
+	cases := map[string]struct {
+		synced  string
+		want    []string
+		wantErr bool
+	}{
+		"NeverSynced": {},
+		"NoneRemoved": {
+			synced: `["a"]`,
+		},
+		"Removed": {
+			synced: `["a","b","c","d"]`,
+			want:   []string{"b", "d"},
+		},
+		"InvalidAnnotation": {
+			synced:  `a,b`,
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := removedKVKeys(tc.synced, pairs)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("removedKVKeys(%q, ...): want error %t, got %v", tc.synced, tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("removedKVKeys(%q, ...): -want, +got:\n%s", tc.synced, diff)
+			}
+		})
+	}
+}
+
+func TestKVValueSource(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "flags"},
+		Data:       map[string]string{"beta": "on"},
+	}
+	forProvider := map[string]any{
+		"keyName":   "beta",
+		"valueFrom": map[string]any{"configMapKeyRef": map[string]any{"name": "flags", "key": "beta"}},
+	}
+	mg := &parameterized{}
+	mg.SetNamespace("team")
+	mg.Spec.ForProvider = forProvider
+	done, err := connecting.connect(mg)
+	if err != nil {
+		t.Fatalf("connect(...): unexpected error: %v", err)
+	}
+	defer done()
+
+	kube := kfake.NewClientBuilder().WithObjects(cm).Build()
+	if err := resolveKVValueSource(context.Background(), kube, mg); err != nil {
+		t.Fatalf("resolveKVValueSource(...): unexpected error: %v", err)
+	}
+	// The value is forgotten when it can no longer be read, rather than
+	// injected from an earlier connection.
+	if err := resolveKVValueSource(context.Background(), kfake.NewClientBuilder().Build(), mg); err == nil {
+		t.Fatalf("resolveKVValueSource(...): want error reading a missing ConfigMap")
+	}
+	if err := InjectKVValue(forProvider, map[string]any{}); err == nil {
+		t.Errorf("InjectKVValue(...): want error injecting a value that could not be read")
+	}
+
+	if err := resolveKVValueSource(context.Background(), kube, mg); err != nil {
+		t.Fatalf("resolveKVValueSource(...): unexpected error: %v", err)
+	}
+	tfMap := map[string]any{"value_from": []any{}}
+	if err := InjectKVValue(forProvider, tfMap); err != nil {
+		t.Fatalf("InjectKVValue(...): unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(map[string]any{"value": "on"}, tfMap); diff != "" {
+		t.Errorf("InjectKVValue(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestKVValueSourceNamespaces(t *testing.T) {
+	kube := kfake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "flags"}, Data: map[string]string{"beta": "a"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "flags"}, Data: map[string]string{"beta": "b"}},
+	).Build()
+	params := func() map[string]any {
+		return map[string]any{
+			"keyName":   "beta",
+			"valueFrom": map[string]any{"configMapKeyRef": map[string]any{"name": "flags", "key": "beta"}},
+		}
+	}
+	// Both resources have the same spec.forProvider, but read different
+	// ConfigMaps, and connect at the same time.
+	var got sync.Map
+	c := ConnectInTurn(managed.ExternalConnectorFn(func(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+		if err := resolveKVValueSource(ctx, kube, mg); err != nil {
+			return nil, err
+		}
+		tfMap := map[string]any{}
+		if err := InjectKVValue(params(), tfMap); err != nil {
+			return nil, err
+		}
+		got.Store(mg.GetNamespace(), tfMap["value"])
+		return nil, nil
+	}))
+	var wg sync.WaitGroup
+	for _, ns := range []string{"team-a", "team-b"} {
+		mg := &parameterized{}
+		mg.SetNamespace(ns)
+		mg.SetUID(types.UID(ns))
+		mg.Spec.ForProvider = params()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Connect(context.Background(), mg); err != nil {
+				t.Errorf("Connect(...): unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	for ns, want := range map[string]string{"team-a": "a", "team-b": "b"} {
+		if v, _ := got.Load(ns); v != want {
+			t.Errorf("InjectKVValue(...): want value %q in namespace %s, got %v", want, ns, v)
+		}
+	}
+}
+
+func TestActionAllowed(t *testing.T) {
+	cases := map[string]struct {
+		policies   xpv1.ManagementPolicies
+		wantUpdate bool
+		wantCreate bool
+		wantDelete bool
+	}{
+		"Default":     {wantUpdate: true, wantCreate: true, wantDelete: true},
+		"All":         {policies: xpv1.ManagementPolicies{xpv1.ManagementActionAll}, wantUpdate: true, wantCreate: true, wantDelete: true},
+		"ObserveOnly": {policies: xpv1.ManagementPolicies{xpv1.ManagementActionObserve}},
+		"NoDelete": {
+			policies:   xpv1.ManagementPolicies{xpv1.ManagementActionObserve, xpv1.ManagementActionCreate, xpv1.ManagementActionUpdate},
+			wantUpdate: true,
+			wantCreate: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mg := &fake.Managed{}
+			mg.SetManagementPolicies(tc.policies)
+			if got := updatesAllowed(mg); got != tc.wantUpdate {
+				t.Errorf("updatesAllowed(...): want %t, got %t", tc.wantUpdate, got)
+			}
+			if got := createAllowed(mg); got != tc.wantCreate {
+				t.Errorf("createAllowed(...): want %t, got %t", tc.wantCreate, got)
+			}
+			if got := deleteAllowed(mg); got != tc.wantDelete {
+				t.Errorf("deleteAllowed(...): want %t, got %t", tc.wantDelete, got)
+			}
+		})
+	}
+}
+
+func TestSyncKVValuesRequiresAccount(t *testing.T) {
+	mg := &parameterized{}
+	mg.SetNamespace("team")
+	meta.SetExternalName(mg, "namespace-id")
+	mg.Spec.ForProvider = map[string]any{
+		"title":      "flags",
+		"valuesFrom": map[string]any{"configMapRef": map[string]any{"name": "flags"}},
+	}
+	if err := syncKVValues(context.Background(), nil, mg, outsideApplier{}); err == nil {
+		t.Error("syncKVValues(...): want error syncing without an account ID")
+	}
+}
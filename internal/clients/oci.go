@@ -0,0 +1,293 @@
+package clients
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	annotationTitle = "org.opencontainers.image.title"
+
+	manifestMediaTypes = "application/vnd.oci.image.manifest.v1+json, application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// An ociReference is a parsed reference to an artifact in an OCI registry.
+type ociReference struct {
+	registry   string
+	repository string
+
+	// reference is a tag or a digest.
+	reference string
+}
+
+// parseOCIReference parses references such as registry:5000/repo/app:v1 and
+// registry/repo/app@sha256:<hash>. The tag defaults to latest. Docker Hub
+// references such as docker.io/app refer to its registry and official images.
+func parseOCIReference(s string) (ociReference, error) {
+	registry, rest, ok := strings.Cut(s, "/")
+	if !ok || registry == "" || rest == "" {
+		return ociReference{}, errors.Errorf("OCI reference %q must include a registry and a repository", s)
+	}
+	r := ociReference{registry: registry, repository: rest, reference: "latest"}
+	if repo, digest, ok := strings.Cut(rest, "@"); ok {
+		r.repository, r.reference = repo, digest
+	} else if i := strings.LastIndex(rest, ":"); i > 0 {
+		r.repository, r.reference = rest[:i], rest[i+1:]
+	}
+	if r.registry == "docker.io" {
+		r.registry = "registry-1.docker.io"
+		if !strings.Contains(r.repository, "/") {
+			r.repository = "library/" + r.repository
+		}
+	}
+	return r, nil
+}
+
+// ociRegistries are the OCI registries content may be pulled from, by host
+// such as ghcr.io or registry.workers.svc:5000. Registries that map to true
+// may also be pulled from over plain HTTP. Content is pulled from no other
+// registry, so that managed resources cannot make the provider send requests
+// to arbitrary hosts of its network.
+type ociRegistries map[string]bool
+
+// allowed returns an error unless the supplied reference may be pulled, over
+// plain HTTP if insecure is true.
+func (r ociRegistries) allowed(reference string, insecure bool) error {
+	host, _, _ := strings.Cut(reference, "/")
+	plain, ok := r[host]
+	if !ok {
+		return errors.Errorf("OCI registry %s is not allowed, allow it with the --oci-registry flag", host)
+	}
+	if insecure && !plain {
+		return errors.Errorf("OCI registry %s may not be pulled from over plain HTTP, allow it with the --insecure-oci-registry flag", host)
+	}
+	return nil
+}
+
+// A descriptor describes a blob of an OCI artifact.
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// pullOCILayer returns the content of a layer of an OCI artifact of one of the
+// supplied registries: the layer titled with the supplied path, or the only
+// layer if no path is supplied.
+// Layers are verified against their digest and cached by it, so unchanged
+// content is only downloaded once.
+func pullOCILayer(ctx context.Context, registries ociRegistries, reference, path string, insecure bool) ([]byte, error) {
+	if err := registries.allowed(reference, insecure); err != nil {
+		return nil, err
+	}
+	ref, err := parseOCIReference(reference)
+	if err != nil {
+		return nil, err
+	}
+	scheme := "https"
+	if insecure {
+		scheme = "http"
+	}
+	base := scheme + "://" + ref.registry + "/v2/" + ref.repository
+	c := &ociClient{repository: ref.repository}
+
+	manifest := struct {
+		Layers []descriptor `json:"layers"`
+	}{}
+	body, err := c.get(ctx, base+"/manifests/"+ref.reference, manifestMediaTypes, 4<<20)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse the manifest of %s", reference)
+	}
+	layer, err := selectLayer(manifest.Layers, path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "OCI artifact %s", reference)
+	}
+	sum, ok := strings.CutPrefix(layer.Digest, "sha256:")
+	if !ok {
+		return nil, errors.Errorf("layer %s of %s does not have a SHA-256 digest", layer.Digest, reference)
+	}
+	if content, err := os.ReadFile(filepath.Join(contentDir, sum)); err == nil {
+		return content, nil
+	}
+	if layer.Size > maxContentBytes {
+		return nil, errors.Errorf("layer %s of %s is larger than %d bytes", layer.Digest, reference, maxContentBytes)
+	}
+	content, err := c.get(ctx, base+"/blobs/"+layer.Digest, "", maxContentBytes)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(content)
+	if hex.EncodeToString(h[:]) != sum {
+		return nil, errors.Errorf("layer %s of %s does not match its digest", layer.Digest, reference)
+	}
+	return content, nil
+}
+
+// selectLayer returns the layer titled with path, or the only layer if path
+// is empty.
+func selectLayer(layers []descriptor, path string) (descriptor, error) {
+	if path == "" {
+		if len(layers) != 1 {
+			return descriptor{}, errors.Errorf("has %d layers, set the path of the content", len(layers))
+		}
+		return layers[0], nil
+	}
+	for _, l := range layers {
+		if l.Annotations[annotationTitle] == path {
+			return l, nil
+		}
+	}
+	return descriptor{}, errors.Errorf("has no layer titled %s", path)
+}
+
+// An ociClient pulls the manifests and blobs of a repository of an OCI
+// registry anonymously. Registries that require a token even for anonymous
+// pulls, such as GHCR and Docker Hub, are challenged for one.
+type ociClient struct {
+	repository string
+	token      string
+}
+
+// get fetches a manifest or blob.
+func (c *ociClient) get(ctx context.Context, u, accept string, limit int64) ([]byte, error) {
+	resp, err := c.do(ctx, u, accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && c.token == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		_ = resp.Body.Close()
+		if c.token, err = c.fetchToken(ctx, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = c.do(ctx, u, accept); err != nil {
+			return nil, err
+		}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("cannot pull OCI artifact: GET %s returned HTTP %d", u, resp.StatusCode)
+	}
+	return readLimited(resp.Body, u, limit)
+}
+
+func (c *ociClient) do(ctx context.Context, u, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot pull OCI artifact")
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	return resp, errors.Wrap(err, "cannot pull OCI artifact")
+}
+
+// fetchToken fetches an anonymous pull token from the authorization server
+// named in the supplied WWW-Authenticate challenge of a registry.
+func (c *ociClient) fetchToken(ctx context.Context, challenge string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	if !strings.EqualFold(scheme, "Bearer") || params["realm"] == "" {
+		return "", errors.Errorf("cannot pull OCI artifact: the registry requires %s authentication, only anonymous pulls are supported", scheme)
+	}
+	u, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", errors.Wrap(err, "cannot parse the token realm of the registry")
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + c.repository + ":pull"
+	}
+	q := u.Query()
+	q.Set("scope", scope)
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot fetch a registry token")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot fetch a registry token")
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("cannot fetch a registry token: GET %s returned HTTP %d", u.Redacted(), resp.StatusCode)
+	}
+	body, err := readLimited(resp.Body, u.Redacted(), 1<<20)
+	if err != nil {
+		return "", err
+	}
+	t := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.Unmarshal(body, &t); err != nil {
+		return "", errors.Wrap(err, "cannot parse the registry token")
+	}
+	if t.Token == "" {
+		t.Token = t.AccessToken
+	}
+	if t.Token == "" {
+		return "", errors.New("cannot fetch a registry token: the response holds no token")
+	}
+	return t.Token, nil
+}
+
+// parseChallenge parses a WWW-Authenticate challenge such as
+// Bearer realm="https://ghcr.io/token",service="ghcr.io" into its scheme and
+// parameters.
+func parseChallenge(h string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(h), " ")
+	params := map[string]string{}
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimLeft(rest, ", ") {
+		k, v, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		k = strings.ToLower(strings.TrimSpace(k))
+		v = strings.TrimLeft(v, " ")
+		if quoted, ok := strings.CutPrefix(v, `"`); ok {
+			end := strings.Index(quoted, `"`)
+			if end < 0 {
+				break
+			}
+			params[k], rest = quoted[:end], quoted[end+1:]
+			continue
+		}
+		params[k], rest, _ = strings.Cut(v, ",")
+		params[k] = strings.TrimSpace(params[k])
+	}
+	return scheme, params
+}
+
+// readLimited reads a response body of at most limit bytes.
+func readLimited(r io.Reader, u string, limit int64) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot pull OCI artifact")
+	}
+	if int64(len(body)) > limit {
+		return nil, errors.Errorf("cannot pull OCI artifact: GET %s returned more than %d bytes", u, limit)
+	}
+	return body, nil
+}
@@ -0,0 +1,207 @@
+package clients
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseOCIReference(t *testing.T) {
+	cases := map[string]struct {
+		reference string
+		want      ociReference
+		wantErr   bool
+	}{
+		"Tag": {
+			reference: "registry.example:5000/workers/app:v1",
+			want:      ociReference{registry: "registry.example:5000", repository: "workers/app", reference: "v1"},
+		},
+		"DefaultTag": {
+			reference: "registry.example:5000/workers/app",
+			want:      ociReference{registry: "registry.example:5000", repository: "workers/app", reference: "latest"},
+		},
+		"Digest": {
+			reference: "ghcr.io/acme/app@sha256:0123",
+			want:      ociReference{registry: "ghcr.io", repository: "acme/app", reference: "sha256:0123"},
+		},
+		"DockerHubOfficialImage": {
+			reference: "docker.io/app:v1",
+			want:      ociReference{registry: "registry-1.docker.io", repository: "library/app", reference: "v1"},
+		},
+		"DockerHub": {
+			reference: "docker.io/acme/app",
+			want:      ociReference{registry: "registry-1.docker.io", repository: "acme/app", reference: "latest"},
+		},
+		"NoRegistry": {
+			reference: "app:v1",
+			wantErr:   true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseOCIReference(tc.reference)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseOCIReference(%q): want error %t, got %v", tc.reference, tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(ociReference{})); diff != "" {
+				t.Errorf("parseOCIReference(%q): -want, +got:\n%s", tc.reference, diff)
+			}
+		})
+	}
+}
+
+func TestSelectLayer(t *testing.T) {
+	worker := descriptor{Digest: "sha256:1", Annotations: map[string]string{annotationTitle: "worker.js"}}
+	lib := descriptor{Digest: "sha256:2", Annotations: map[string]string{annotationTitle: "lib.js"}}
+	cases := map[string]struct {
+		layers  []descriptor
+		path    string
+		want    descriptor
+		wantErr bool
+	}{
+		"OnlyLayer": {
+			layers: []descriptor{worker},
+			want:   worker,
+		},
+		"SeveralLayersWithoutPath": {
+			layers:  []descriptor{worker, lib},
+			wantErr: true,
+		},
+		"Titled": {
+			layers: []descriptor{worker, lib},
+			path:   "lib.js",
+			want:   lib,
+		},
+		"NotFound": {
+			layers:  []descriptor{worker, lib},
+			path:    "app.js",
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := selectLayer(tc.layers, tc.path)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("selectLayer(...): want error %t, got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("selectLayer(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+// registry returns a registry serving an artifact with a single layer holding
+// blob, announced with the digest of content, which requires a token from its
+// authorization server if token is set.
+func registry(t *testing.T, content, blob, token string) *httptest.Server {
+	t.Helper()
+	h := sha256.Sum256([]byte(content))
+	digest := "sha256:" + hex.EncodeToString(h[:])
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("scope") != "repository:workers/app:pull" || r.URL.Query().Get("service") != "registry" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": token})
+	})
+	mux.HandleFunc("/v2/workers/app/", func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",service="registry",scope="repository:workers/app:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/manifests/v1"):
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"layers": []descriptor{{Digest: digest, Size: int64(len(blob))}},
+			})
+		case strings.HasSuffix(r.URL.Path, "/blobs/"+digest):
+			_, _ = w.Write([]byte(blob))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPullOCILayer(t *testing.T) {
+	const script = "export default {}"
+	cases := map[string]struct {
+		blob       string
+		token      string
+		registries func(host string) ociRegistries
+		want       string
+		wantErr    bool
+	}{
+		"Anonymous": {
+			blob: script,
+			want: script,
+		},
+		"RegistryNotAllowed": {
+			blob:       script,
+			registries: func(string) ociRegistries { return ociRegistries{"ghcr.io": true} },
+			wantErr:    true,
+		},
+		"PlainHTTPNotAllowed": {
+			blob:       script,
+			registries: func(host string) ociRegistries { return ociRegistries{host: false} },
+			wantErr:    true,
+		},
+		"TokenChallenge": {
+			blob:  script,
+			token: "anonymous",
+			want:  script,
+		},
+		"DigestMismatch": {
+			blob:    "tampered",
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			contentDir = t.TempDir()
+			srv := registry(t, script, tc.blob, tc.token)
+			host := strings.TrimPrefix(srv.URL, "http://")
+			registries := ociRegistries{host: true}
+			if tc.registries != nil {
+				registries = tc.registries(host)
+			}
+			ref := host + "/workers/app:v1"
+			got, err := pullOCILayer(context.Background(), registries, ref, "", true)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("pullOCILayer(%q): want error %t, got %v", ref, tc.wantErr, err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("pullOCILayer(%q): want %q, got %q", ref, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/app:pull,push", error=invalid_token`)
+	if scheme != "Bearer" {
+		t.Errorf("parseChallenge(...): want scheme Bearer, got %s", scheme)
+	}
+	want := map[string]string{
+		"realm":   "https://auth.docker.io/token",
+		"service": "registry.docker.io",
+		"scope":   "repository:library/app:pull,push",
+		"error":   "invalid_token",
+	}
+	if diff := cmp.Diff(want, params); diff != "" {
+		t.Errorf("parseChallenge(...): -want, +got:\n%s", diff)
+	}
+}
@@ -0,0 +1,411 @@
+package clients
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// AnnotationKeyOriginCAPreviousID is the ID of the Origin CA certificate
+	// a reissued certificate replaces, which is revoked once the new
+	// certificate is written to the TLS Secret.
+	AnnotationKeyOriginCAPreviousID = "cloudflare.upbound.io/origin-ca-previous-id"
+
+	// keyTLSCSR is the key of the certificate signing request in the TLS
+	// Secret of an Origin CA certificate.
+	keyTLSCSR = "tls.csr"
+
+	// keyTLSPendingKey is the key of the private key of a reissued
+	// certificate in the TLS Secret of an Origin CA certificate, until the
+	// certificate is written to the Secret along with it.
+	keyTLSPendingKey = "tls.key.pending"
+
+	// defaultValidityDays is the validity of Origin CA certificates that do
+	// not request one.
+	defaultValidityDays = 5475
+
+	errOriginCA = "cannot prepare the key of the Origin CA certificate"
+)
+
+// A trackerRemover forgets the Terraform state the provider keeps in memory
+// for a managed resource.
+type trackerRemover interface {
+	RemoveTracker(obj resource.Object) error
+}
+
+// resolveOriginCertificate generates the private key and certificate signing
+// request of an Origin CA certificate with a tlsSecretRef, keeps them in that
+// kubernetes.io/tls Secret and sets the csr argument to the request, in
+// memory. Once the certificate is issued it is written to the Secret, so the
+// Secret can be mounted like any TLS Secret. A Secret the provider creates is
+// owned by the managed resource.
+//
+// A certificate is reissued with a new key when its hostnames or validity
+// change, which Cloudflare cannot update in place, and before it expires.
+// Its external name and observation are cleared, so that the certificate is
+// created again. The new key is kept apart until the new certificate is
+// written to the Secret along with it, and the previous certificate is
+// revoked then. Certificates are only reissued and revoked when changes are
+// allowed, and when the management policies of the resource allow updating
+// and creating it, and deleting it, respectively.
+func resolveOriginCertificate(ctx context.Context, kube client.Client, trackers trackerRemover, mg resource.Managed, outside outsideApplier, changesAllowed bool) error {
+	paved, err := fieldpath.PaveObject(mg)
+	if err != nil {
+		return errors.Wrap(err, errOriginCA)
+	}
+	src, err := paved.GetValue("spec.forProvider.tlsSecretRef")
+	if err != nil || src == nil || meta.WasDeleted(mg) {
+		return nil
+	}
+	ref, _ := src.(map[string]any)
+	name, _ := ref["name"].(string)
+	namespace, _ := ref["namespace"].(string)
+	nn, err := sourceName(mg.GetNamespace(), namespace, name)
+	if err != nil {
+		return errors.Wrap(err, errOriginCA)
+	}
+	var hostnames []string
+	if err := paved.GetValueInto("spec.forProvider.hostnames", &hostnames); err != nil || len(hostnames) == 0 {
+		return errors.New(errOriginCA + ": spec.forProvider.hostnames is required")
+	}
+	requestType := parameter(paved, "requestType")
+
+	s, err := tlsSecret(ctx, kube, mg, nn)
+	if err != nil {
+		return errors.Wrap(err, errOriginCA)
+	}
+
+	changed := false
+	key, err := parsePrivateKey(s.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return errors.Wrapf(err, "%s: Secret %s", errOriginCA, nn)
+	}
+	if key == nil {
+		if key, err = newPrivateKey(s, corev1.TLSPrivateKeyKey, requestType); err != nil {
+			return errors.Wrap(err, errOriginCA)
+		}
+		changed = true
+	}
+	if err := checkKeyType(key, requestType); err != nil {
+		return errors.Wrapf(err, "%s: Secret %s", errOriginCA, nn)
+	}
+	pending, err := parsePrivateKey(s.Data[keyTLSPendingKey])
+	if err != nil {
+		return errors.Wrapf(err, "%s: Secret %s", errOriginCA, nn)
+	}
+
+	// The issued certificate is written along with its key, which is the
+	// pending key of a reissued certificate.
+	if cert, err := paved.GetString("status.atProvider.certificate"); err == nil && cert != "" && string(s.Data[corev1.TLSCertKey]) != cert {
+		switch pub := certificateKey(cert); {
+		case pending != nil && publicKeysEqual(pub, pending.Public()):
+			s.Data[corev1.TLSPrivateKeyKey] = s.Data[keyTLSPendingKey]
+			delete(s.Data, keyTLSPendingKey)
+			key, pending = pending, nil
+			s.Data[corev1.TLSCertKey] = []byte(cert)
+			changed = true
+		case publicKeysEqual(pub, key.Public()):
+			s.Data[corev1.TLSCertKey] = []byte(cert)
+			changed = true
+		}
+	}
+
+	csrKey := key
+	if pending != nil {
+		csrKey = pending
+	}
+	stale := !csrMatches(s.Data[keyTLSCSR], hostnames, csrKey)
+	id := meta.GetExternalName(mg)
+	reissue := false
+	if id != "" && changesAllowed && updatesAllowed(mg) && createAllowed(mg) {
+		due, err := renewalDue(paved, time.Now())
+		if err != nil {
+			return errors.Wrap(err, errOriginCA)
+		}
+		reissue = stale || due
+	}
+	if reissue && pending == nil {
+		if pending, err = newPrivateKey(s, keyTLSPendingKey, requestType); err != nil {
+			return errors.Wrap(err, errOriginCA)
+		}
+		csrKey, stale = pending, true
+	}
+	if stale {
+		der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject:  pkix.Name{CommonName: hostnames[0]},
+			DNSNames: hostnames,
+		}, csrKey)
+		if err != nil {
+			return errors.Wrap(err, errOriginCA)
+		}
+		s.Data[keyTLSCSR] = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+		changed = true
+	}
+
+	if changed {
+		if s.ResourceVersion == "" {
+			err = kube.Create(ctx, s)
+		} else {
+			err = kube.Update(ctx, s)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "%s: cannot write Secret %s", errOriginCA, nn)
+		}
+	}
+
+	// The certificate a reissued one replaced is revoked once the new one
+	// is in the Secret.
+	if prev := mg.GetAnnotations()[AnnotationKeyOriginCAPreviousID]; prev != "" && id != "" && id != prev && pending == nil && changesAllowed && deleteAllowed(mg) {
+		if err := revokeOriginCertificate(ctx, kube, mg, outside, prev); err != nil {
+			return errors.Wrap(err, errOriginCA)
+		}
+	}
+
+	if reissue {
+		meta.AddAnnotations(mg, map[string]string{AnnotationKeyOriginCAPreviousID: id})
+		meta.SetExternalName(mg, "")
+		if err := kube.Update(ctx, mg); err != nil {
+			return errors.Wrap(err, errOriginCA)
+		}
+		if trackers != nil {
+			if err := trackers.RemoveTracker(mg); err != nil {
+				return errors.Wrap(err, errOriginCA)
+			}
+		}
+	}
+	if paved, err = fieldpath.PaveObject(mg); err != nil {
+		return errors.Wrap(err, errOriginCA)
+	}
+	if reissue {
+		if err := paved.SetValue("status.atProvider", map[string]any{}); err != nil {
+			return errors.Wrap(err, errOriginCA)
+		}
+	}
+	if err := paved.SetValue("spec.forProvider.csr", string(s.Data[keyTLSCSR])); err != nil {
+		return errors.Wrap(err, errOriginCA)
+	}
+	return errors.Wrap(runtime.DefaultUnstructuredConverter.FromUnstructured(paved.UnstructuredContent(), mg), errOriginCA)
+}
+
+// tlsSecret returns the TLS Secret of an Origin CA certificate, or a new one
+// owned by its managed resource if it does not exist.
+func tlsSecret(ctx context.Context, kube client.Client, mg resource.Managed, nn types.NamespacedName) (*corev1.Secret, error) {
+	s := &corev1.Secret{}
+	if err := kube.Get(ctx, nn, s); kerrors.IsNotFound(err) {
+		s = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: nn.Namespace, Name: nn.Name},
+			Type:       corev1.SecretTypeTLS,
+			Data:       map[string][]byte{corev1.TLSCertKey: {}},
+		}
+		if err := controllerutil.SetOwnerReference(mg, s, kube.Scheme()); err != nil {
+			return nil, errors.Wrapf(err, "cannot own Secret %s", nn)
+		}
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "cannot get Secret %s", nn)
+	}
+	if s.Data == nil {
+		s.Data = map[string][]byte{}
+	}
+	return s, nil
+}
+
+// revokeOriginCertificate revokes the Origin CA certificate with the supplied
+// ID and forgets it as the previous certificate of the managed resource.
+func revokeOriginCertificate(ctx context.Context, kube client.Client, mg resource.Managed, outside outsideApplier, id string) error {
+	api, err := newManagedAPIClient(ctx, kube, mg)
+	if err != nil {
+		return err
+	}
+	req := applyRequest{
+		Operation: operationDelete,
+		TypeName:  "cloudflare_origin_ca_certificate",
+		Prior:     tftypes.NewValue(tftypes.String, id),
+	}
+	err = outside.apply(ctx, req, func(ctx context.Context) error {
+		// A certificate that does not exist anymore needs no revoking.
+		if err := api.do(ctx, http.MethodDelete, "/certificates/"+id, nil, nil, nil); err != nil && !isAPINotFound(err) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "cannot revoke the previous certificate %s", id)
+	}
+	return annotate(ctx, kube, mg, map[string]any{AnnotationKeyOriginCAPreviousID: nil})
+}
+
+// renewalDue reports whether an issued Origin CA certificate must be reissued
+// because its requested validity changed or it expires within its
+// renewBeforeDays, which defaults to a third of its validity.
+func renewalDue(paved *fieldpath.Paved, now time.Time) (bool, error) {
+	validity := float64(defaultValidityDays)
+	if observed, err := number(paved, "status.atProvider.requestedValidity"); err == nil && observed > 0 {
+		validity = observed
+		if v, err := number(paved, "spec.forProvider.requestedValidity"); err == nil && v > 0 && v != observed {
+			return true, nil
+		}
+	}
+	expiresOn, err := paved.GetString("status.atProvider.expiresOn")
+	if err != nil || expiresOn == "" {
+		return false, nil
+	}
+	expires, err := parseCloudflareTime(expiresOn)
+	if err != nil {
+		return false, errors.Wrapf(err, "cannot parse expiresOn %q", expiresOn)
+	}
+	renewBefore := validity / 3
+	if v, err := number(paved, "spec.forProvider.renewBeforeDays"); err == nil && v > 0 {
+		if v >= validity {
+			return false, errors.Errorf("renewBeforeDays must be less than the validity of %v days", validity)
+		}
+		renewBefore = v
+	}
+	return now.After(expires.Add(-time.Duration(renewBefore * float64(24*time.Hour)))), nil
+}
+
+// number returns a numeric field of a managed resource, or an error if it is
+// not set.
+func number(paved *fieldpath.Paved, path string) (float64, error) {
+	v, err := paved.GetValue(path)
+	if err != nil {
+		return 0, err
+	}
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case int64:
+		return float64(n), nil
+	default:
+		return 0, errors.Errorf("%s is not a number", path)
+	}
+}
+
+// parseCloudflareTime parses a timestamp of the Cloudflare API, which Origin
+// CA certificates return in Go's default time format.
+func parseCloudflareTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05 -0700 MST"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("unknown time format")
+}
+
+// newPrivateKey generates a private key for the supplied Origin CA request
+// type and stores it PEM encoded under the supplied key of a Secret.
+func newPrivateKey(s *corev1.Secret, dataKey, requestType string) (crypto.Signer, error) {
+	key, err := generatePrivateKey(requestType)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	s.Data[dataKey] = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	return key, nil
+}
+
+// generatePrivateKey generates a private key for the supplied Origin CA
+// request type.
+func generatePrivateKey(requestType string) (crypto.Signer, error) {
+	switch requestType {
+	case "origin-rsa":
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case "origin-ecc":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, errors.Errorf("cannot generate a key for request type %q, use origin-rsa or origin-ecc", requestType)
+	}
+}
+
+// checkKeyType checks that a private key suits the supplied request type.
+func checkKeyType(key crypto.Signer, requestType string) error {
+	_, isRSA := key.(*rsa.PrivateKey)
+	_, isECDSA := key.(*ecdsa.PrivateKey)
+	switch {
+	case requestType == "origin-rsa" && !isRSA, requestType == "origin-ecc" && !isECDSA:
+		return errors.Errorf("the private key does not suit request type %s, delete the Secret to generate a new key", requestType)
+	case requestType != "origin-rsa" && requestType != "origin-ecc":
+		return errors.Errorf("request type %q cannot be used with tlsSecretRef, use origin-rsa or origin-ecc", requestType)
+	}
+	return nil
+}
+
+// parsePrivateKey parses a PEM encoded PKCS #8, PKCS #1 or SEC 1 private key.
+// It returns nil if there is no key.
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	b, _ := pem.Decode(data)
+	if b == nil {
+		return nil, nil
+	}
+	if k, err := x509.ParsePKCS8PrivateKey(b.Bytes); err == nil {
+		if s, ok := k.(crypto.Signer); ok {
+			return s, nil
+		}
+	}
+	if k, err := x509.ParsePKCS1PrivateKey(b.Bytes); err == nil {
+		return k, nil
+	}
+	if k, err := x509.ParseECPrivateKey(b.Bytes); err == nil {
+		return k, nil
+	}
+	return nil, errors.New("cannot parse the private key in tls.key")
+}
+
+// csrMatches reports whether a PEM encoded certificate signing request is for
+// the supplied hostnames and key.
+func csrMatches(data []byte, hostnames []string, key crypto.Signer) bool {
+	b, _ := pem.Decode(data)
+	if b == nil {
+		return false
+	}
+	req, err := x509.ParseCertificateRequest(b.Bytes)
+	if err != nil {
+		return false
+	}
+	return publicKeysEqual(req.PublicKey, key.Public()) && slices.Equal(req.DNSNames, hostnames)
+}
+
+// certificateKey returns the public key of a PEM encoded certificate, or nil
+// if it cannot be parsed.
+func certificateKey(data string) crypto.PublicKey {
+	b, _ := pem.Decode([]byte(data))
+	if b == nil {
+		return nil
+	}
+	cert, err := x509.ParseCertificate(b.Bytes)
+	if err != nil {
+		return nil
+	}
+	return cert.PublicKey
+}
+
+// publicKeysEqual reports whether two public keys are equal.
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(b)
+}
@@ -0,0 +1,197 @@
+package clients
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+)
+
+func TestGeneratePrivateKey(t *testing.T) {
+	cases := map[string]struct {
+		requestType string
+		wantErr     bool
+	}{
+		"RSA": {
+			requestType: "origin-rsa",
+		},
+		"ECC": {
+			requestType: "origin-ecc",
+		},
+		"KeylessRequestType": {
+			requestType: "keyless-certificate",
+			wantErr:     true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			key, err := generatePrivateKey(tc.requestType)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("generatePrivateKey(%q): want error %t, got %v", tc.requestType, tc.wantErr, err)
+			}
+			if tc.wantErr {
+				return
+			}
+			if err := checkKeyType(key, tc.requestType); err != nil {
+				t.Errorf("checkKeyType(...): unexpected error: %v", err)
+			}
+			der, err := x509.MarshalPKCS8PrivateKey(key)
+			if err != nil {
+				t.Fatalf("MarshalPKCS8PrivateKey(...): unexpected error: %v", err)
+			}
+			parsed, err := parsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+			if err != nil || !publicKeysEqual(parsed.Public(), key.Public()) {
+				t.Errorf("parsePrivateKey(...): want the generated key, got %v", err)
+			}
+		})
+	}
+
+	ecc, _ := generatePrivateKey("origin-ecc")
+	if err := checkKeyType(ecc, "origin-rsa"); err == nil {
+		t.Errorf("checkKeyType(...): want error for an ECC key of an RSA request")
+	}
+}
+
+func TestCSRMatches(t *testing.T) {
+	key, _ := generatePrivateKey("origin-ecc")
+	other, _ := generatePrivateKey("origin-ecc")
+	hostnames := []string{"app.example.com", "*.app.example.com"}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: hostnames[0]},
+		DNSNames: hostnames,
+	}, key)
+	if err != nil {
+		t.Fatalf("CreateCertificateRequest(...): unexpected error: %v", err)
+	}
+	csr := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+	cases := map[string]struct {
+		csr       []byte
+		hostnames []string
+		want      bool
+	}{
+		"Matches": {
+			csr:       csr,
+			hostnames: hostnames,
+			want:      true,
+		},
+		"OtherHostnames": {
+			csr:       csr,
+			hostnames: []string{"app.example.com"},
+		},
+		"NoCSR": {
+			hostnames: hostnames,
+		},
+		"Garbage": {
+			csr:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: []byte("garbage")}),
+			hostnames: hostnames,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := csrMatches(tc.csr, tc.hostnames, key); got != tc.want {
+				t.Errorf("csrMatches(...): want %t, got %t", tc.want, got)
+			}
+		})
+	}
+	if csrMatches(csr, hostnames, other) {
+		t.Errorf("csrMatches(...): want false for another key")
+	}
+}
+
+func TestRenewalDue(t *testing.T) {
+	now := at("2026-06-01T00:00:00Z")
+	cases := map[string]struct {
+		forProvider map[string]any
+		atProvider  map[string]any
+		want        bool
+		wantErr     bool
+	}{
+		"NotIssued": {
+			forProvider: map[string]any{"requestedValidity": float64(90)},
+		},
+		"ValidityChanged": {
+			forProvider: map[string]any{"requestedValidity": float64(365)},
+			atProvider:  map[string]any{"requestedValidity": float64(90), "expiresOn": "2026-08-01T00:00:00Z"},
+			want:        true,
+		},
+		"BeforeDefaultRenewal": {
+			forProvider: map[string]any{"requestedValidity": float64(90)},
+			atProvider:  map[string]any{"requestedValidity": float64(90), "expiresOn": "2026-07-01T00:00:01Z"},
+		},
+		"DefaultRenewal": {
+			forProvider: map[string]any{"requestedValidity": float64(90)},
+			atProvider:  map[string]any{"requestedValidity": float64(90), "expiresOn": "2026-06-30T23:59:59Z"},
+			want:        true,
+		},
+		"RenewBeforeDays": {
+			forProvider: map[string]any{"requestedValidity": float64(90), "renewBeforeDays": float64(10)},
+			atProvider:  map[string]any{"requestedValidity": float64(90), "expiresOn": "2026-06-30T23:59:59Z"},
+		},
+		"CloudflareTimeFormat": {
+			forProvider: map[string]any{"renewBeforeDays": float64(10)},
+			atProvider:  map[string]any{"expiresOn": "2026-06-05 00:00:00 +0000 UTC"},
+			want:        true,
+		},
+		"RenewBeforeValidity": {
+			forProvider: map[string]any{"requestedValidity": float64(7), "renewBeforeDays": float64(7)},
+			atProvider:  map[string]any{"requestedValidity": float64(7), "expiresOn": "2026-06-05T00:00:00Z"},
+			wantErr:     true,
+		},
+		"InvalidExpiresOn": {
+			atProvider: map[string]any{"expiresOn": "June 5th"},
+			wantErr:    true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			paved := fieldpath.Pave(map[string]any{
+				"spec":   map[string]any{"forProvider": tc.forProvider},
+				"status": map[string]any{"atProvider": tc.atProvider},
+			})
+			got, err := renewalDue(paved, now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("renewalDue(...): want error %t, got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("renewalDue(...): want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseCloudflareTime(t *testing.T) {
+	want := at("2026-06-05T12:30:00Z")
+	cases := map[string]struct {
+		s       string
+		wantErr bool
+	}{
+		"RFC3339": {
+			s: "2026-06-05T12:30:00Z",
+		},
+		"GoDefault": {
+			s: "2026-06-05 12:30:00 +0000 UTC",
+		},
+		"OtherZone": {
+			s: "2026-06-05 14:30:00 +0200 CEST",
+		},
+		"Unknown": {
+			s:       "05/06/2026",
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseCloudflareTime(tc.s)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseCloudflareTime(%q): want error %t, got %v", tc.s, tc.wantErr, err)
+			}
+			if !tc.wantErr && !got.Equal(want) {
+				t.Errorf("parseCloudflareTime(%q): want %s, got %s", tc.s, want, got.Format(time.RFC3339))
+			}
+		})
+	}
+}
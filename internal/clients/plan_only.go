@@ -0,0 +1,214 @@
+package clients
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TypePendingChanges indicates whether the provider computed changes for a
+// managed resource that it did not apply.
+const TypePendingChanges xpv1.ConditionType = "PendingChanges"
+
+// Reasons a managed resource does or does not have pending changes.
+const (
+	ReasonPlanOnly         xpv1.ConditionReason = "PlanOnly"
+	ReasonNoPendingChanges xpv1.ConditionReason = "NoPendingChanges"
+)
+
+const (
+	reasonPlanOnly event.Reason = "PlanOnly"
+
+	// maxPendingChanges caps the number of attribute changes reported in
+	// conditions and events so that large plans stay readable.
+	maxPendingChanges = 25
+)
+
+// PendingChanges returns a condition indicating that the provider computed
+// the supplied changes for a managed resource but did not apply them.
+func PendingChanges(msg string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePendingChanges,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonPlanOnly,
+		Message:            msg,
+	}
+}
+
+// NoPendingChanges returns a condition indicating that the provider is not
+// holding back any changes for a managed resource.
+func NoPendingChanges() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePendingChanges,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNoPendingChanges,
+	}
+}
+
+// planOnlyHold holds back every change to a managed resource. Instead it
+// records the attribute-level diff of the change in the PendingChanges
+// condition of the managed resource, and in an event when the diff changes.
+type planOnlyHold struct {
+	rec event.Recorder
+
+	// mg is the managed resource of the reconciler, which persists its
+	// conditions once it observed the resource.
+	mg resource.Managed
+}
+
+func (h *planOnlyHold) plan(req applyRequest, _ time.Time) (bool, error) {
+	req = heldRequest(h.mg, req)
+	changed, err := hasChanges(req)
+	if err != nil {
+		return false, errors.Wrap(err, "cannot compute pending changes")
+	}
+	if !changed {
+		h.mg.SetConditions(NoPendingChanges())
+		return false, nil
+	}
+	changes, err := describeChanges(req)
+	if err != nil {
+		return false, errors.Wrap(err, "cannot compute pending changes")
+	}
+	msg := planOnlyMessage(req)
+	if changes != "" {
+		msg += ":\n" + changes
+	}
+	if h.mg.GetCondition(TypePendingChanges).Message != msg {
+		h.rec.Event(h.mg, event.Normal(reasonPlanOnly, msg, "operation", string(req.Operation)))
+	}
+	h.mg.SetConditions(PendingChanges(msg))
+	return true, nil
+}
+
+func (h *planOnlyHold) held(req applyRequest, _ time.Time) error {
+	return heldError{msg: planOnlyMessage(heldRequest(h.mg, req))}
+}
+
+func planOnlyMessage(req applyRequest) string {
+	return fmt.Sprintf("plan-only mode: %s of %s was not applied", req.Operation, req.TypeName)
+}
+
+// heldRequest returns the change planned for a managed resource that is
+// being deleted as its deletion. The managed reconciler plans the resource
+// as configured before it deletes it.
+func heldRequest(mg resource.Managed, req applyRequest) applyRequest {
+	if meta.WasDeleted(mg) && req.Operation != operationCreate {
+		req.Operation = operationDelete
+	}
+	return req
+}
+
+// hasChanges reports whether an apply request changes anything. Like the
+// upjet external client, attributes that are computed or not specified in
+// the plan are not considered.
+func hasChanges(req applyRequest) (bool, error) {
+	if req.Operation != operationUpdate {
+		return true, nil
+	}
+	diffs, err := req.Planned.Diff(req.Prior)
+	if err != nil {
+		return false, err
+	}
+	for _, d := range diffs {
+		if d.Value1 != nil && d.Value1.IsKnown() && !d.Value1.IsNull() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// describeChanges renders the attribute-level changes of an apply request,
+// one attribute per line. Like the upjet external client, attributes that
+// are computed or not specified in the plan are not reported.
+func describeChanges(req applyRequest) (string, error) {
+	if req.Operation == operationDelete {
+		return "- " + req.TypeName, nil
+	}
+	diffs, err := req.Planned.Diff(req.Prior)
+	if err != nil {
+		return "", err
+	}
+	lines := make([]string, 0, len(diffs))
+	for _, d := range diffs {
+		if d.Value1 == nil || !d.Value1.IsKnown() || d.Value1.IsNull() || !isPrimitive(d.Value1.Type()) {
+			continue
+		}
+		path := formatAttributePath(d.Path)
+		planned := formatValue(d.Value1, req.Sensitive(d.Path))
+		if d.Value2 == nil || d.Value2.IsNull() {
+			lines = append(lines, fmt.Sprintf("+ %s = %s", path, planned))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("~ %s: %s -> %s", path, formatValue(d.Value2, req.Sensitive(d.Path)), planned))
+	}
+	sort.Strings(lines)
+	if len(lines) > maxPendingChanges {
+		lines = append(lines[:maxPendingChanges], fmt.Sprintf("... and %d more", len(lines)-maxPendingChanges))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func isPrimitive(t tftypes.Type) bool {
+	return t.Is(tftypes.String) || t.Is(tftypes.Number) || t.Is(tftypes.Bool)
+}
+
+func formatAttributePath(p *tftypes.AttributePath) string {
+	var sb strings.Builder
+	for _, step := range p.Steps() {
+		switch s := step.(type) {
+		case tftypes.AttributeName:
+			if sb.Len() > 0 {
+				sb.WriteString(".")
+			}
+			sb.WriteString(string(s))
+		case tftypes.ElementKeyString:
+			fmt.Fprintf(&sb, "[%q]", string(s))
+		case tftypes.ElementKeyInt:
+			fmt.Fprintf(&sb, "[%d]", int64(s))
+		default:
+			sb.WriteString("[*]")
+		}
+	}
+	return sb.String()
+}
+
+func formatValue(v *tftypes.Value, sensitive bool) string {
+	switch {
+	case sensitive:
+		return "(sensitive)"
+	case !v.IsKnown():
+		return "(known after apply)"
+	case v.IsNull():
+		return "null"
+	}
+	switch {
+	case v.Type().Is(tftypes.String):
+		var s string
+		_ = v.As(&s)
+		return fmt.Sprintf("%q", s)
+	case v.Type().Is(tftypes.Number):
+		n := big.NewFloat(0)
+		_ = v.As(&n)
+		return n.Text('f', -1)
+	case v.Type().Is(tftypes.Bool):
+		var b bool
+		_ = v.As(&b)
+		return fmt.Sprintf("%t", b)
+	default:
+		return v.String()
+	}
+}
@@ -0,0 +1,250 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource/fake"
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var recordType = tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+	"name":    tftypes.String,
+	"ttl":     tftypes.Number,
+	"proxied": tftypes.Bool,
+	"secret":  tftypes.String,
+	"tags":    tftypes.Map{ElementType: tftypes.String},
+}}
+
+func record(name string, ttl any, proxied any, secret any, tags map[string]tftypes.Value) tftypes.Value {
+	var t any
+	if tags != nil {
+		t = tags
+	}
+	return tftypes.NewValue(recordType, map[string]tftypes.Value{
+		"name":    tftypes.NewValue(tftypes.String, name),
+		"ttl":     tftypes.NewValue(tftypes.Number, ttl),
+		"proxied": tftypes.NewValue(tftypes.Bool, proxied),
+		"secret":  tftypes.NewValue(tftypes.String, secret),
+		"tags":    tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, t),
+	})
+}
+
+func secretSensitive(p *tftypes.AttributePath) bool {
+	return p.Equal(tftypes.NewAttributePath().WithAttributeName("secret"))
+}
+
+func TestDescribeChanges(t *testing.T) {
+	many := map[string]tftypes.Value{}
+	for i := range 30 {
+		many[fmt.Sprintf("k%02d", i)] = tftypes.NewValue(tftypes.String, "v")
+	}
+	cases := map[string]struct {
+		req  applyRequest
+		want string
+	}{
+		"Delete": {
+			req:  applyRequest{Operation: operationDelete, TypeName: "cloudflare_dns_record"},
+			want: "- cloudflare_dns_record",
+		},
+		"Create": {
+			req: applyRequest{
+				Operation: operationCreate,
+				Prior:     tftypes.NewValue(recordType, nil),
+				Planned:   record("www", 300, true, "s3cr3t", nil),
+			},
+			want: strings.Join([]string{
+				`+ name = "www"`,
+				`+ proxied = true`,
+				`+ secret = (sensitive)`,
+				`+ ttl = 300`,
+			}, "\n"),
+		},
+		"Update": {
+			req: applyRequest{
+				Operation: operationUpdate,
+				Prior:     record("www", 300, false, "old", nil),
+				Planned:   record("www", 1, false, "new", map[string]tftypes.Value{"env": tftypes.NewValue(tftypes.String, "prod")}),
+			},
+			want: strings.Join([]string{
+				`+ tags["env"] = "prod"`,
+				`~ secret: (sensitive) -> (sensitive)`,
+				`~ ttl: 300 -> 1`,
+			}, "\n"),
+		},
+		"UnknownAndNullPlannedValuesAreNotReported": {
+			req: applyRequest{
+				Operation: operationUpdate,
+				Prior:     record("www", 300, false, nil, nil),
+				Planned:   record("www", tftypes.UnknownValue, nil, nil, nil),
+			},
+			want: "",
+		},
+		"ManyChangesAreCapped": {
+			req: applyRequest{
+				Operation: operationUpdate,
+				Prior:     record("www", 300, false, nil, nil),
+				Planned:   record("www", 300, false, nil, many),
+			},
+			want: func() string {
+				lines := make([]string, 0, maxPendingChanges+1)
+				for i := range maxPendingChanges {
+					lines = append(lines, fmt.Sprintf(`+ tags["k%02d"] = "v"`, i))
+				}
+				return strings.Join(append(lines, "... and 5 more"), "\n")
+			}(),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tc.req.Sensitive = secretSensitive
+			got, err := describeChanges(tc.req)
+			if err != nil {
+				t.Fatalf("describeChanges(...): unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("describeChanges(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	cases := map[string]struct {
+		v         tftypes.Value
+		sensitive bool
+		want      string
+	}{
+		"String":    {v: tftypes.NewValue(tftypes.String, `a "b"`), want: `"a \"b\""`},
+		"Integer":   {v: tftypes.NewValue(tftypes.Number, 300), want: "300"},
+		"Float":     {v: tftypes.NewValue(tftypes.Number, big.NewFloat(0.5)), want: "0.5"},
+		"Bool":      {v: tftypes.NewValue(tftypes.Bool, false), want: "false"},
+		"Null":      {v: tftypes.NewValue(tftypes.String, nil), want: "null"},
+		"Unknown":   {v: tftypes.NewValue(tftypes.String, tftypes.UnknownValue), want: "(known after apply)"},
+		"Sensitive": {v: tftypes.NewValue(tftypes.String, "s3cr3t"), sensitive: true, want: "(sensitive)"},
+		"SensitiveUnknown": {
+			v:         tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+			sensitive: true,
+			want:      "(sensitive)",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := formatValue(&tc.v, tc.sensitive); got != tc.want {
+				t.Errorf("formatValue(%s, %t): want %s, got %s", tc.v, tc.sensitive, tc.want, got)
+			}
+		})
+	}
+}
+
+// recordResource is a framework resource with the attributes of recordType.
+type recordResource struct{}
+
+func (recordResource) Metadata(_ context.Context, req fwresource.MetadataRequest, resp *fwresource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_dns_record"
+}
+
+func (recordResource) Schema(context.Context, fwresource.SchemaRequest, *fwresource.SchemaResponse) {}
+
+func (recordResource) Create(context.Context, fwresource.CreateRequest, *fwresource.CreateResponse) {}
+
+func (recordResource) Read(context.Context, fwresource.ReadRequest, *fwresource.ReadResponse) {}
+
+func (recordResource) Update(context.Context, fwresource.UpdateRequest, *fwresource.UpdateResponse) {}
+
+func (recordResource) Delete(context.Context, fwresource.DeleteRequest, *fwresource.DeleteResponse) {}
+
+// recordResourceWithModifyPlan requires the resource to be replaced when its
+// name changes.
+type recordResourceWithModifyPlan struct {
+	recordResource
+}
+
+func (recordResourceWithModifyPlan) ModifyPlan(_ context.Context, _ fwresource.ModifyPlanRequest, resp *fwresource.ModifyPlanResponse) {
+	resp.RequiresReplace = append(resp.RequiresReplace, path.Root("name"))
+}
+
+func TestPlanOnlyHold(t *testing.T) {
+	cases := map[string]struct {
+		deleted     bool
+		prior       tftypes.Value
+		planned     tftypes.Value
+		wantPlan    tftypes.Value
+		wantStatus  corev1.ConditionStatus
+		wantMessage string
+		wantReplace bool
+	}{
+		"UpdateIsPlannedAsNoChange": {
+			prior:       record("www", 300, false, nil, nil),
+			planned:     record("api", 300, false, nil, nil),
+			wantPlan:    record("www", 300, false, nil, nil),
+			wantStatus:  corev1.ConditionTrue,
+			wantMessage: "plan-only mode: update of cloudflare_dns_record was not applied:\n~ name: \"www\" -> \"api\"",
+		},
+		"NoChanges": {
+			prior:       record("www", 300, false, nil, nil),
+			planned:     record("www", 300, false, nil, nil),
+			wantPlan:    record("www", 300, false, nil, nil),
+			wantStatus:  corev1.ConditionFalse,
+			wantReplace: true,
+		},
+		"CreationIsReported": {
+			prior:       tftypes.NewValue(recordType, nil),
+			planned:     record("www", nil, nil, nil, nil),
+			wantPlan:    record("www", nil, nil, nil, nil),
+			wantStatus:  corev1.ConditionTrue,
+			wantMessage: "plan-only mode: create of cloudflare_dns_record was not applied:\n+ name = \"www\"",
+			wantReplace: true,
+		},
+		"DeletionIsReported": {
+			deleted:     true,
+			prior:       record("www", 300, false, nil, nil),
+			planned:     record("www", 300, false, nil, nil),
+			wantPlan:    record("www", 300, false, nil, nil),
+			wantStatus:  corev1.ConditionTrue,
+			wantMessage: "plan-only mode: delete of cloudflare_dns_record was not applied:\n- cloudflare_dns_record",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mg := &fake.Managed{}
+			if tc.deleted {
+				now := metav1.Now()
+				mg.SetDeletionTimestamp(&now)
+			}
+			p := &frameworkProvider{holds: []changeHold{&planOnlyHold{rec: event.NewNopRecorder(), mg: mg}}}
+			r := wrapFrameworkResource(context.Background(), recordResourceWithModifyPlan{}, "cloudflare", p).(fwresource.ResourceWithModifyPlan)
+
+			req := fwresource.ModifyPlanRequest{State: tfsdk.State{Raw: tc.prior}, Plan: tfsdk.Plan{Raw: tc.planned}}
+			resp := &fwresource.ModifyPlanResponse{Plan: req.Plan}
+			r.ModifyPlan(context.Background(), req, resp)
+
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("ModifyPlan(...): unexpected diagnostics: %v", resp.Diagnostics)
+			}
+			if !resp.Plan.Raw.Equal(tc.wantPlan) {
+				t.Errorf("ModifyPlan(...): want plan %s, got %s", tc.wantPlan, resp.Plan.Raw)
+			}
+			if got := len(resp.RequiresReplace) > 0; got != tc.wantReplace {
+				t.Errorf("ModifyPlan(...): want replacement required %t, got %t", tc.wantReplace, got)
+			}
+			c := mg.GetCondition(TypePendingChanges)
+			if c.Status != tc.wantStatus {
+				t.Errorf("ModifyPlan(...): want %s condition %s, got %s", TypePendingChanges, tc.wantStatus, c.Status)
+			}
+			if diff := cmp.Diff(tc.wantMessage, c.Message); diff != "" {
+				t.Errorf("ModifyPlan(...): %s condition message: -want, +got:\n%s", TypePendingChanges, diff)
+			}
+		})
+	}
+}
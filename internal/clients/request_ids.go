@@ -0,0 +1,93 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlLog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// AnnotationKeyLastRequestIDs holds the comma separated Cloudflare request
+// IDs (cf-ray) of the last create, update or delete of a managed resource.
+const AnnotationKeyLastRequestIDs = "cloudflare.upbound.io/last-request-ids"
+
+const (
+	reasonCloudflareRequest event.Reason = "CloudflareRequest"
+
+	// maxRequestIDs caps how many request IDs are recorded for a single
+	// change, keeping the most recent ones.
+	maxRequestIDs = 10
+
+	errAnnotateRequestIDs = "cannot annotate managed resource with Cloudflare request IDs"
+)
+
+// requestIDs returns the Cloudflare request IDs of the supplied responses.
+func requestIDs(rs []apiResponse) []string {
+	ids := make([]string, 0, len(rs))
+	for _, r := range rs {
+		if r.RayID != "" {
+			ids = append(ids, r.RayID)
+		}
+	}
+	if len(ids) > maxRequestIDs {
+		ids = ids[len(ids)-maxRequestIDs:]
+	}
+	return ids
+}
+
+// requestIDObserver returns an apply observer that reports the Cloudflare
+// request IDs of every change in an event and in the last-request-ids
+// annotation of the managed resource, so they can be handed to Cloudflare
+// support.
+func requestIDObserver(kube client.Client, rec event.Recorder, mg resource.Managed) applyObserver {
+	// Async operations run the observer outside of the managed reconciler,
+	// so keep a private copy rather than sharing the reconciler's object.
+	obj := mg.DeepCopyObject().(resource.Managed)
+	return func(ctx context.Context, req applyRequest, res applyResult) {
+		if req.Operation == operationRead {
+			// Reads happen on every poll, so only changes are reported.
+			return
+		}
+		ids := requestIDs(res.Calls.Responses())
+		if len(ids) == 0 {
+			return
+		}
+		v := strings.Join(ids, ",")
+		if res.Diagnostics.HasError() {
+			err := errors.Errorf("%s of %s failed, Cloudflare request IDs: %s", req.Operation, req.TypeName, v)
+			rec.Event(obj, event.Warning(reasonCloudflareRequest, err, "operation", string(req.Operation), "requestIDs", v))
+		} else {
+			msg := fmt.Sprintf("%s of %s succeeded, Cloudflare request IDs: %s", req.Operation, req.TypeName, v)
+			rec.Event(obj, event.Normal(reasonCloudflareRequest, msg, "operation", string(req.Operation), "requestIDs", v))
+		}
+		if err := annotate(ctx, kube, obj, map[string]any{AnnotationKeyLastRequestIDs: v}); err != nil && !kerrors.IsNotFound(err) {
+			// The event already carries the request IDs, so this does not
+			// fail the change.
+			ctrlLog.FromContext(ctx).Info(errAnnotateRequestIDs, "error", err.Error())
+		}
+	}
+}
+
+// annotate sets the supplied annotations on the managed resource, or removes
+// those with a nil value, with a merge patch of only those annotations, so
+// that it neither conflicts with nor overwrites concurrent changes to the
+// resource.
+func annotate(ctx context.Context, kube client.Client, mg resource.Managed, annotations map[string]any) error {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return kube.Patch(ctx, mg, client.RawPatch(types.MergePatchType, patch))
+}
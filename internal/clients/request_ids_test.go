@@ -0,0 +1,73 @@
+package clients
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource/fake"
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	kubefake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestRequestIDObserver(t *testing.T) {
+	type patch struct {
+		Type types.PatchType
+		Data string
+	}
+	cases := map[string]struct {
+		op   operation
+		rays []string
+		want []patch
+	}{
+		"UpdateIsAnnotated": {
+			op:   operationUpdate,
+			rays: []string{"ray-1", "", "ray-2"},
+			want: []patch{{Type: types.MergePatchType, Data: `{"metadata":{"annotations":{"cloudflare.upbound.io/last-request-ids":"ray-1,ray-2"}}}`}},
+		},
+		"NoRequestIDs": {
+			op:   operationCreate,
+			rays: []string{""},
+		},
+		"ReadIsNotAnnotated": {
+			op:   operationRead,
+			rays: []string{"ray-1"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []patch
+			kube := kubefake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(_ context.Context, _ client.WithWatch, obj client.Object, p client.Patch, _ ...client.PatchOption) error {
+					data, err := p.Data(obj)
+					if err != nil {
+						return err
+					}
+					got = append(got, patch{Type: p.Type(), Data: string(data)})
+					return nil
+				},
+				Update: func(context.Context, client.WithWatch, client.Object, ...client.UpdateOption) error {
+					t.Error("Update(...): the resource must be patched, not updated")
+					return nil
+				},
+			}).Build()
+			mg := &fake.Managed{}
+			mg.SetName("www")
+
+			calls := &apiCalls{}
+			for _, r := range tc.rays {
+				calls.add(apiResponse{StatusCode: 200, RayID: r})
+			}
+			o := requestIDObserver(kube, event.NewNopRecorder(), mg)
+			o(context.Background(), applyRequest{Operation: tc.op, TypeName: "cloudflare_dns_record"}, applyResult{Calls: calls, Diagnostics: &diag.Diagnostics{}})
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("requestIDObserver(...): patches: -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	ujresource "github.com/crossplane/upjet/v2/pkg/resource"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AnnotationKeyServiceTokenRotatedFor is the expiry an Access service token
+// was refreshed to when its client secret was last rotated, so that it is
+// not rotated again before that expiry is observed.
+const AnnotationKeyServiceTokenRotatedFor = "cloudflare.upbound.io/service-token-rotated-for"
+
+const (
+	// Connection detail keys of Access service tokens, which clients send
+	// in the CF-Access-Client-Id and CF-Access-Client-Secret headers.
+	connectionKeyClientID     = "client_id"
+	connectionKeyClientSecret = "client_secret"
+
+	errServiceTokenRotation = "cannot rotate the service token"
+)
+
+// rotateServiceToken rotates the client secret of an Access service token
+// with a rotateBeforeDays, and extends its expiry, once it expires within
+// that many days. The expiry is extended by refreshing the token, and the
+// client secret is rotated by Terraform when its clientSecretVersion is
+// incremented; the previous secret is accepted until the
+// previousClientSecretExpiresAt of the token. The refreshed expiry is
+// recorded with the incremented version, so that the token is not rotated
+// again until the expiry is observed. Tokens are only rotated when changes
+// are allowed.
+func rotateServiceToken(ctx context.Context, kube client.Client, mg resource.Managed, outside outsideApplier, changesAllowed bool) error {
+	tr, ok := mg.(ujresource.Terraformed)
+	if !ok || tr.GetTerraformResourceType() != "cloudflare_zero_trust_access_service_token" {
+		return nil
+	}
+	id := meta.GetExternalName(mg)
+	if id == "" || !changesAllowed || meta.WasDeleted(mg) {
+		return nil
+	}
+	paved, err := fieldpath.PaveObject(mg)
+	if err != nil {
+		return errors.Wrap(err, errServiceTokenRotation)
+	}
+	due, err := rotationDue(paved, mg.GetAnnotations()[AnnotationKeyServiceTokenRotatedFor], time.Now())
+	if err != nil || !due {
+		return errors.Wrap(err, errServiceTokenRotation)
+	}
+
+	api, err := newManagedAPIClient(ctx, kube, mg)
+	if err != nil {
+		return errors.Wrap(err, errServiceTokenRotation)
+	}
+	path := "/accounts/" + parameter(paved, "accountId")
+	if zoneID := parameter(paved, "zoneId"); zoneID != "" {
+		path = "/zones/" + zoneID
+	}
+	expiresAt, _ := paved.GetString("status.atProvider.expiresAt")
+	req := applyRequest{
+		Operation: operationUpdate,
+		TypeName:  tr.GetTerraformResourceType(),
+		Planned:   tftypes.NewValue(tftypes.String, expiresAt),
+	}
+	refreshed := struct {
+		ExpiresAt string `json:"expires_at"`
+	}{}
+	err = outside.apply(ctx, req, func(ctx context.Context) error {
+		return api.do(ctx, http.MethodPost, path+"/access/service_tokens/"+id+"/refresh", nil, nil, &refreshed)
+	})
+	if err != nil {
+		return errors.Wrap(err, errServiceTokenRotation)
+	}
+	if refreshed.ExpiresAt == "" {
+		return errors.New(errServiceTokenRotation + ": the refreshed token has no expiry")
+	}
+
+	version := 1.0
+	if v, err := number(paved, "status.atProvider.clientSecretVersion"); err == nil && v > 0 {
+		version = v
+	}
+	if v, err := number(paved, "spec.forProvider.clientSecretVersion"); err == nil && v > version {
+		version = v
+	}
+	if err := paved.SetValue("spec.forProvider.clientSecretVersion", version+1); err != nil {
+		return errors.Wrap(err, errServiceTokenRotation)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(paved.UnstructuredContent(), mg); err != nil {
+		return errors.Wrap(err, errServiceTokenRotation)
+	}
+	meta.AddAnnotations(mg, map[string]string{AnnotationKeyServiceTokenRotatedFor: refreshed.ExpiresAt})
+	return errors.Wrap(kube.Update(ctx, mg), errServiceTokenRotation)
+}
+
+// rotationDue reports whether an Access service token with a
+// rotateBeforeDays expires within that many days, unless it was rotated and
+// refreshed to the supplied expiry, which is later than the observed one.
+func rotationDue(paved *fieldpath.Paved, rotatedFor string, now time.Time) (bool, error) {
+	days, err := number(paved, "spec.forProvider.rotateBeforeDays")
+	if err != nil || days <= 0 {
+		return false, nil
+	}
+	expiresAt, err := paved.GetString("status.atProvider.expiresAt")
+	if err != nil || expiresAt == "" {
+		return false, nil
+	}
+	expires, err := parseCloudflareTime(expiresAt)
+	if err != nil {
+		return false, errors.Wrapf(err, "cannot parse expiresAt %q", expiresAt)
+	}
+	// The refreshed expiry is observed after Terraform reads the token.
+	if refreshed, err := parseCloudflareTime(rotatedFor); err == nil && refreshed.After(expires) {
+		return false, nil
+	}
+	return !now.Before(expires.Add(-time.Duration(days * float64(24*time.Hour)))), nil
+}
+
+// AccessServiceTokenConnectionDetails returns the client ID and secret of an
+// Access service token, which Cloudflare only returns when the token is
+// created or its secret rotated, as connection details.
+func AccessServiceTokenConnectionDetails(attr map[string]any) (map[string][]byte, error) {
+	details := map[string][]byte{}
+	for _, k := range []string{connectionKeyClientID, connectionKeyClientSecret} {
+		if v, _ := attr[k].(string); v != "" {
+			details[k] = []byte(v)
+		}
+	}
+	return details, nil
+}
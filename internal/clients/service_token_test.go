@@ -0,0 +1,75 @@
+package clients
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+)
+
+func TestRotationDue(t *testing.T) {
+	now := at("2026-06-01T00:00:00Z")
+	cases := map[string]struct {
+		forProvider map[string]any
+		atProvider  map[string]any
+		rotatedFor  string
+		want        bool
+		wantErr     bool
+	}{
+		"NoSchedule": {
+			atProvider: map[string]any{"expiresAt": "2026-06-02T00:00:00Z"},
+		},
+		"NotCreated": {
+			forProvider: map[string]any{"rotateBeforeDays": float64(30)},
+		},
+		"BeforeThreshold": {
+			forProvider: map[string]any{"rotateBeforeDays": float64(30)},
+			atProvider:  map[string]any{"expiresAt": "2026-07-01T00:00:01Z"},
+		},
+		"AtThreshold": {
+			forProvider: map[string]any{"rotateBeforeDays": float64(30)},
+			atProvider:  map[string]any{"expiresAt": "2026-07-01T00:00:00Z"},
+			want:        true,
+		},
+		"Expired": {
+			forProvider: map[string]any{"rotateBeforeDays": float64(30)},
+			atProvider:  map[string]any{"expiresAt": "2026-05-01T00:00:00Z"},
+			want:        true,
+		},
+		"RefreshNotObserved": {
+			forProvider: map[string]any{"rotateBeforeDays": float64(30)},
+			atProvider:  map[string]any{"expiresAt": "2026-06-05T00:00:00Z"},
+			rotatedFor:  "2027-06-01T00:00:00Z",
+		},
+		"RefreshObservedAndDueAgain": {
+			forProvider: map[string]any{"rotateBeforeDays": float64(30)},
+			atProvider:  map[string]any{"expiresAt": "2026-06-05T00:00:00Z"},
+			rotatedFor:  "2026-06-05T00:00:00Z",
+			want:        true,
+		},
+		"CloudflareTimeFormat": {
+			forProvider: map[string]any{"rotateBeforeDays": float64(1)},
+			atProvider:  map[string]any{"expiresAt": "2026-06-01 12:00:00 +0000 UTC"},
+			want:        true,
+		},
+		"InvalidExpiresAt": {
+			forProvider: map[string]any{"rotateBeforeDays": float64(30)},
+			atProvider:  map[string]any{"expiresAt": "June 5th"},
+			wantErr:     true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			paved := fieldpath.Pave(map[string]any{
+				"spec":   map[string]any{"forProvider": tc.forProvider},
+				"status": map[string]any{"atProvider": tc.atProvider},
+			})
+			got, err := rotationDue(paved, tc.rotatedFor, now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("rotationDue(...): want error %t, got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("rotationDue(...): want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
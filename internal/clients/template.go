@@ -7,13 +7,18 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrlLog "sigs.k8s.io/controller-runtime/pkg/log"
 
+	tjcontroller "github.com/crossplane/upjet/v2/pkg/controller"
 	"github.com/crossplane/upjet/v2/pkg/terraform"
 
 	clusterv1beta1 "github.com/prolixalias/provider-cloudflare/apis/cluster/v1beta1"
@@ -27,11 +32,69 @@ const (
 	errTrackUsage           = "cannot track ProviderConfig usage"
 	errExtractCredentials   = "cannot extract credentials"
 	errUnmarshalCredentials = "cannot unmarshal template credentials as JSON"
+	errParseDotenv          = "cannot parse credentials as dotenv"
+	errCredentialsShape     = "credentials must include api_token or both api_key and email"
+	errProviderConfigPaused = "ProviderConfig is paused, observe and apply are skipped until spec.paused is unset"
 )
 
+// A TerraformSetupOption configures the terraform.SetupFn returned by
+// TerraformSetupBuilder.
+type TerraformSetupOption func(*terraformSetupOptions)
+
+type terraformSetupOptions struct {
+	planOnly   bool
+	recorder   event.Recorder
+	trackers   trackerRemover
+	registries ociRegistries
+}
+
+// WithPlanOnly computes the plan of every managed resource but never applies
+// it, regardless of the PlanOnly setting of its ProviderConfig.
+func WithPlanOnly(planOnly bool) TerraformSetupOption {
+	return func(o *terraformSetupOptions) {
+		o.planOnly = planOnly
+	}
+}
+
+// WithEventRecorder sets the recorder used to emit events on managed
+// resources.
+func WithEventRecorder(r event.Recorder) TerraformSetupOption {
+	return func(o *terraformSetupOptions) {
+		o.recorder = r
+	}
+}
+
+// WithOperationTrackerStore sets the store of the Terraform state the
+// controllers keep in memory, which is cleared when a managed resource is
+// created again, such as an Origin CA certificate that is reissued.
+func WithOperationTrackerStore(s *tjcontroller.OperationTrackerStore) TerraformSetupOption {
+	return func(o *terraformSetupOptions) {
+		o.trackers = s
+	}
+}
+
+// WithOCIRegistries allows the contentFrom sources of Workers resources to
+// pull OCI artifacts from the supplied registries, and over plain HTTP from
+// the supplied insecure ones. No registry is allowed by default.
+func WithOCIRegistries(registries, insecure []string) TerraformSetupOption {
+	return func(o *terraformSetupOptions) {
+		o.registries = ociRegistries{}
+		for _, r := range registries {
+			o.registries[r] = false
+		}
+		for _, r := range insecure {
+			o.registries[r] = true
+		}
+	}
+}
+
 // TerraformSetupBuilder builds a terraform.SetupFn function which
 // returns Terraform provider setup configuration
-func TerraformSetupBuilder() terraform.SetupFn {
+func TerraformSetupBuilder(opts ...TerraformSetupOption) terraform.SetupFn {
+	o := &terraformSetupOptions{recorder: event.NewNopRecorder()}
+	for _, fn := range opts {
+		fn(o)
+	}
 	return func(ctx context.Context, client client.Client, mg resource.Managed) (terraform.Setup, error) {
 		ps := terraform.Setup{}
 		logger := ctrlLog.FromContext(ctx).WithValues(
@@ -46,16 +109,17 @@ func TerraformSetupBuilder() terraform.SetupFn {
 			logger.Error(err, "Terraform setup failed while resolving ProviderConfig")
 			return terraform.Setup{}, errors.Wrap(err, "cannot resolve provider config")
 		}
+		// The resources of a paused ProviderConfig are held until their
+		// crossplane.io/paused annotation lands, and when it is removed
+		// before the ProviderConfig is resumed.
+		if pcSpec.Paused {
+			return terraform.Setup{}, heldError{msg: errProviderConfigPaused}
+		}
 
-		data, err := resource.CommonCredentialExtractor(ctx, pcSpec.Credentials.Source, client, pcSpec.Credentials.CommonCredentialSelectors)
+		creds, err := providerCredentials(ctx, client, pcSpec.Credentials.Source, pcSpec.Credentials.CommonCredentialSelectors)
 		if err != nil {
 			logger.Error(err, "Terraform setup failed while extracting credentials", "credentialSource", pcSpec.Credentials.Source)
-			return ps, errors.Wrap(err, errExtractCredentials)
-		}
-		creds := map[string]string{}
-		if err := json.Unmarshal(data, &creds); err != nil {
-			logger.Error(err, "Terraform setup failed while unmarshalling credentials JSON", "credentialBytes", len(data))
-			return ps, errors.Wrap(err, errUnmarshalCredentials)
+			return ps, err
 		}
 		credKeys := sortedKeys(creds)
 
@@ -83,13 +147,81 @@ func TerraformSetupBuilder() terraform.SetupFn {
 			hasEmail = true
 		}
 
+		// An alternative API endpoint, such as the mock server of the
+		// internal/cfmock package in tests.
+		if v, ok := creds["base_url"]; ok && v != "" {
+			ps.Configuration["base_url"] = v
+		}
+
 		// Cloudflare auth requires api_token OR api_key+email.
 		if !(hasToken || (hasKey && hasEmail)) {
-			err := errors.New("credentials must include api_token or both api_key and email")
+			err := errors.New(errCredentialsShape)
 			logger.Error(err, "Terraform setup extracted credentials with unsupported shape", "credentialKeys", credKeys)
 			return ps, err
 		}
 
+		// Workers resources may read their content from ConfigMaps, Secrets
+		// and OCI artifacts instead.
+		if err := resolveContentSources(ctx, client, mg, o.registries); err != nil {
+			return ps, err
+		}
+		// cloudflared tunnels publish their token as a connection detail.
+		if err := fetchTunnelToken(ctx, client, mg); err != nil {
+			return ps, err
+		}
+		// Workers KV values may read their value from a ConfigMap.
+		if err := resolveKVValueSource(ctx, client, mg); err != nil {
+			return ps, err
+		}
+
+		windows, err := resourceChangeWindows(mg, pcSpec.ChangeWindows)
+		if err != nil {
+			return ps, errors.Wrap(err, errChangeWindows)
+		}
+
+		var holds []changeHold
+		if o.planOnly || pcSpec.PlanOnly {
+			holds = append(holds, &planOnlyHold{rec: o.recorder, mg: mg})
+		} else if mg.GetCondition(TypePendingChanges).Status == corev1.ConditionTrue {
+			// Plan-only mode was switched off, so changes are applied again.
+			mg.SetConditions(NoPendingChanges())
+		}
+		if len(windows) > 0 {
+			holds = append(holds, &windowHold{rec: o.recorder, mg: mg, windows: windows})
+		}
+		if windows.open(time.Now()) && mg.GetCondition(TypeWaitingForChangeWindow).Status == corev1.ConditionTrue {
+			mg.SetConditions(ChangeWindowOpen())
+		}
+		// Held creations and deletions are held back by the external
+		// client, held updates when they are planned.
+		setHeldChanges(ctx, holds)
+		guards := []applyGuard{apiBackoffGuard(mg)}
+		observers := []applyObserver{apiErrorObserver(mg), requestIDObserver(client, o.recorder, mg)}
+		outside := outsideApplier{guards: guards, observers: observers}
+		// Workers KV namespaces may sync their values from a ConfigMap,
+		// and Origin CA certificates may be reissued, which is held like
+		// the changes Terraform applies.
+		changesAllowed := !o.planOnly && !pcSpec.PlanOnly && windows.open(time.Now())
+		if changesAllowed {
+			if err := syncKVValues(ctx, client, mg, outside); err != nil {
+				return ps, err
+			}
+		}
+		if err := resolveOriginCertificate(ctx, client, o.trackers, mg, outside, changesAllowed); err != nil {
+			return ps, err
+		}
+		// Certificates may be read from Secrets, and uploaded again when
+		// they change.
+		if err := resolveCertificateSource(ctx, client, o.trackers, mg, outside, changesAllowed && updatesAllowed(mg)); err != nil {
+			return ps, err
+		}
+		// Access service tokens with a rotation schedule are rotated
+		// before they expire.
+		if err := rotateServiceToken(ctx, client, mg, outside, changesAllowed && updatesAllowed(mg)); err != nil {
+			return ps, err
+		}
+		ps.FrameworkProvider = newFrameworkProvider(ps.FrameworkProvider, holds, guards, observers)
+
 		// Emit extra runtime context for tunnel resources, where failures are currently opaque.
 		if isTunnelManaged(mg) {
 			tfPath := os.Getenv("TERRAFORM_NATIVE_PROVIDER_PATH")
@@ -105,6 +237,28 @@ func TerraformSetupBuilder() terraform.SetupFn {
 	}
 }
 
+// providerCredentials extracts the credentials JSON of a ProviderConfig.
+func providerCredentials(ctx context.Context, kube client.Client, source xpv1.CredentialsSource, selectors xpv1.CommonCredentialSelectors) (map[string]string, error) {
+	data, err := resource.CommonCredentialExtractor(ctx, source, kube, selectors)
+	if err != nil {
+		return nil, errors.Wrap(err, errExtractCredentials)
+	}
+	return parseCredentials(data)
+}
+
+// ValidateCredentials checks that ProviderConfig credentials are a JSON object
+// or dotenv file holding either an api_token or both an api_key and an email.
+func ValidateCredentials(data []byte) error {
+	creds, err := parseCredentials(data)
+	if err != nil {
+		return err
+	}
+	if creds["api_token"] == "" && (creds["api_key"] == "" || creds["email"] == "") {
+		return errors.New(errCredentialsShape)
+	}
+	return nil
+}
+
 func toSharedPCSpec(pc *clusterv1beta1.ProviderConfig) (*namespacedv1beta1.ProviderConfigSpec, error) {
 	if pc == nil {
 		return nil, nil
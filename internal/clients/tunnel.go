@@ -0,0 +1,179 @@
+package clients
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sync"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	ujresource "github.com/crossplane/upjet/v2/pkg/resource"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Connection detail keys of cloudflared tunnels.
+	connectionKeyTunnelToken       = "tunnel_token"
+	connectionKeyTunnelID          = "tunnel_id"
+	connectionKeyTunnelCredentials = "credentials.json"
+
+	errTunnelToken = "cannot get the token of the tunnel"
+)
+
+// tunnelTokens holds the tokens of the tunnels whose secret was generated by
+// Cloudflare, by tunnel ID. The secret of a tunnel does not change, so each
+// token is only fetched once. Tokens are forgotten when their managed
+// resource is deleted, or when it creates its tunnel again after it was
+// observed not to exist.
+var tunnelTokens sync.Map
+
+// tunnelIDs holds the ID of the tunnel of each managed resource, by UID.
+var tunnelIDs sync.Map
+
+// A tunnelToken is the content of the token cloudflared runs a tunnel with.
+type tunnelToken struct {
+	AccountTag string `json:"a"`
+	TunnelID   string `json:"t"`
+	Secret     string `json:"s"`
+}
+
+// isCloudflaredTunnel reports whether a managed resource is a cloudflared
+// tunnel, rather than one of the resources configuring it.
+func isCloudflaredTunnel(mg resource.Managed) bool {
+	tr, ok := mg.(ujresource.Terraformed)
+	return ok && tr.GetTerraformResourceType() == "cloudflare_zero_trust_tunnel_cloudflared"
+}
+
+// fetchTunnelToken fetches the token of an existing cloudflared tunnel whose
+// secret was generated by Cloudflare, for TunnelConnectionDetails to publish
+// it. Tunnels with a tunnelSecretSecretRef do not need it.
+func fetchTunnelToken(ctx context.Context, kube client.Client, mg resource.Managed) error {
+	id := meta.GetExternalName(mg)
+	if !isCloudflaredTunnel(mg) || id == "" {
+		return nil
+	}
+	if meta.WasDeleted(mg) {
+		tunnelTokens.Delete(id)
+		tunnelIDs.Delete(mg.GetUID())
+		return nil
+	}
+	if prev, ok := tunnelIDs.Swap(mg.GetUID(), id); ok && prev != id {
+		tunnelTokens.Delete(prev)
+	}
+	if _, ok := tunnelTokens.Load(id); ok {
+		return nil
+	}
+	paved, err := fieldpath.PaveObject(mg)
+	if err != nil {
+		return errors.Wrap(err, errTunnelToken)
+	}
+	if ref, err := paved.GetValue("spec.forProvider.tunnelSecretSecretRef"); err == nil && ref != nil {
+		return nil
+	}
+	account := parameter(paved, "accountId")
+	if account == "" {
+		return errors.New(errTunnelToken + ": spec.forProvider.accountId is required")
+	}
+	api, err := newManagedAPIClient(ctx, kube, mg)
+	if err != nil {
+		return errors.Wrap(err, errTunnelToken)
+	}
+	var token string
+	err = api.get(ctx, "/accounts/"+account+"/cfd_tunnel/"+id+"/token", nil, &token)
+	if isAPINotFound(err) {
+		// The tunnel does not exist (anymore), which it is observed to be.
+		// Its token is fetched again when the provider next connects.
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, errTunnelToken)
+	}
+	tunnelTokens.Store(id, token)
+	return nil
+}
+
+// TunnelConnectionDetails returns the connection details cloudflared runs a
+// tunnel with: its ID, its token and a credentials file. They are derived from
+// the tunnel secret in the state of the tunnel, or from the token fetched from
+// Cloudflare when the secret was generated by Cloudflare.
+func TunnelConnectionDetails(attr map[string]any) (map[string][]byte, error) {
+	t := tunnelToken{}
+	t.TunnelID, _ = attr["id"].(string)
+	t.AccountTag, _ = attr["account_tag"].(string)
+	t.Secret, _ = attr["tunnel_secret"].(string)
+	if t.TunnelID == "" {
+		return nil, nil
+	}
+	details := map[string][]byte{connectionKeyTunnelID: []byte(t.TunnelID)}
+
+	var token string
+	if t.Secret != "" {
+		b, err := json.Marshal(t)
+		if err != nil {
+			return nil, errors.Wrap(err, errTunnelToken)
+		}
+		token = base64.StdEncoding.EncodeToString(b)
+	} else {
+		v, ok := tunnelTokens.Load(t.TunnelID)
+		if !ok {
+			return details, nil
+		}
+		token = v.(string)
+		b, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot decode the token of the tunnel")
+		}
+		if err := json.Unmarshal(b, &t); err != nil {
+			return nil, errors.Wrap(err, "cannot decode the token of the tunnel")
+		}
+	}
+	creds, err := json.Marshal(struct {
+		AccountTag   string
+		TunnelSecret string
+		TunnelID     string
+	}{AccountTag: t.AccountTag, TunnelSecret: t.Secret, TunnelID: t.TunnelID})
+	if err != nil {
+		return nil, errors.Wrap(err, errTunnelToken)
+	}
+	details[connectionKeyTunnelToken] = []byte(token)
+	details[connectionKeyTunnelCredentials] = creds
+	return details, nil
+}
+
+// NewTunnelConfigInitializer returns an initializer that sets the external
+// name of a tunnel configuration to the ID of its tunnel once the tunnel
+// exists. A tunnel has exactly one configuration, identified by the ID of the
+// tunnel, so the configuration the tunnel was created with is observed and
+// late-initializes the spec instead of being overwritten.
+func NewTunnelConfigInitializer(kube client.Client) managed.Initializer {
+	return managed.InitializerFn(func(ctx context.Context, mg resource.Managed) error {
+		if meta.GetExternalName(mg) != "" {
+			return nil
+		}
+		// The tunnel is usually referenced. A tunnel that does not exist
+		// yet is reported by the managed reconciler when it resolves the
+		// reference.
+		err := managed.NewAPISimpleReferenceResolver(kube).ResolveReferences(ctx, mg)
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "cannot initialize the tunnel configuration")
+		}
+		paved, err := fieldpath.PaveObject(mg)
+		if err != nil {
+			return errors.Wrap(err, "cannot initialize the tunnel configuration")
+		}
+		id := parameter(paved, "tunnelId")
+		if id == "" {
+			return nil
+		}
+		meta.SetExternalName(mg, id)
+		return errors.Wrap(kube.Update(ctx, mg), "cannot initialize the tunnel configuration")
+	})
+}
@@ -0,0 +1,83 @@
+package clients
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	ujfake "github.com/crossplane/upjet/v2/pkg/resource/fake"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestFetchTunnelTokenForgets(t *testing.T) {
+	mg := &ujfake.Terraformed{MetadataProvider: ujfake.MetadataProvider{Type: "cloudflare_zero_trust_tunnel_cloudflared"}}
+	mg.SetUID("tunnel")
+	meta.SetExternalName(mg, "new")
+	tunnelIDs.Store(mg.GetUID(), "old")
+	tunnelTokens.Store("old", "old-token")
+	tunnelTokens.Store("new", "new-token")
+	t.Cleanup(func() {
+		tunnelIDs.Delete(mg.GetUID())
+		tunnelTokens.Delete("old")
+		tunnelTokens.Delete("new")
+	})
+
+	// The tunnel was created again after it was observed not to exist.
+	if err := fetchTunnelToken(context.Background(), nil, mg); err != nil {
+		t.Fatalf("fetchTunnelToken(...): unexpected error: %v", err)
+	}
+	if _, ok := tunnelTokens.Load("old"); ok {
+		t.Error("fetchTunnelToken(...): want the token of the previous tunnel forgotten")
+	}
+	if _, ok := tunnelTokens.Load("new"); !ok {
+		t.Error("fetchTunnelToken(...): want the token of the tunnel kept")
+	}
+
+	now := metav1.Now()
+	mg.SetDeletionTimestamp(&now)
+	if err := fetchTunnelToken(context.Background(), nil, mg); err != nil {
+		t.Fatalf("fetchTunnelToken(...): unexpected error: %v", err)
+	}
+	if _, ok := tunnelTokens.Load("new"); ok {
+		t.Error("fetchTunnelToken(...): want the token of a deleted tunnel forgotten")
+	}
+}
+
+// referencing is a managed resource whose references fail to resolve with
+// the supplied error.
+type referencing struct {
+	parameterized
+	err error
+}
+
+func (r *referencing) ResolveReferences(context.Context, client.Reader) error {
+	return r.err
+}
+
+func TestTunnelConfigInitializerReferences(t *testing.T) {
+	cases := map[string]struct {
+		err     error
+		wantErr bool
+	}{
+		"TunnelNotFound": {
+			err: errors.Wrap(kerrors.NewNotFound(schema.GroupResource{Resource: "tunnels"}, "tunnel"), "mg.Spec.ForProvider.TunnelID"),
+		},
+		"OtherError": {
+			err:     errors.New("boom"),
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mg := &referencing{err: tc.err}
+			err := NewTunnelConfigInitializer(nil).Initialize(context.Background(), mg)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Initialize(...): want error %t, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/prolixalias/provider-cloudflare/apis/namespaced/v1beta1"
+	"github.com/prolixalias/provider-cloudflare/internal/controller/paused"
 )
 
 // Setup adds a controller that reconciles ProviderConfigs by accounting for
@@ -26,9 +27,11 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 		WithOptions(o.ForControllerRuntime()).
 		For(&v1beta1.ProviderConfig{}).
 		Watches(&v1beta1.ProviderConfigUsage{}, &resource.EnqueueRequestForProviderConfig{}).
-		Complete(providerconfig.NewReconciler(mgr, of,
-			providerconfig.WithLogger(o.Logger.WithValues("controller", name)),
-			providerconfig.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name)))))
+		Complete(paused.NewReconciler(
+			providerconfig.NewReconciler(mgr, of,
+				providerconfig.WithLogger(o.Logger.WithValues("controller", name)),
+				providerconfig.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name)))),
+			mgr.GetClient(), of))
 }
 
 // SetupGated adds a controller that reconciles ProviderConfigs by accounting for
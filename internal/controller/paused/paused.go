@@ -0,0 +1,187 @@
+// Package paused pauses the managed resources of paused ProviderConfigs.
+package paused
+
+import (
+	"context"
+	"fmt"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// AnnotationKeyPausedByProviderConfig marks the managed resources that were
+// paused because their ProviderConfig is, so that only their pause is lifted
+// when the ProviderConfig is resumed.
+const AnnotationKeyPausedByProviderConfig = "cloudflare.upbound.io/paused-by-provider-config"
+
+// TypePaused indicates whether reconciliation of the managed resources that
+// use a ProviderConfig is paused.
+const TypePaused xpv1.ConditionType = "Paused"
+
+// Reasons a ProviderConfig is or is not paused.
+const (
+	ReasonPaused xpv1.ConditionReason = "Paused"
+	ReasonActive xpv1.ConditionReason = "Active"
+)
+
+const (
+	errGetProviderConfig     = "cannot get ProviderConfig"
+	errListUsages            = "cannot list ProviderConfigUsages"
+	errPauseManaged          = "cannot pause managed resource"
+	errUpdatePausedCondition = "cannot update Paused condition of ProviderConfig"
+)
+
+// ProviderConfigPaused returns a condition indicating that the supplied
+// number of managed resources are held because their ProviderConfig is
+// paused.
+func ProviderConfigPaused(held int64) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePaused,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonPaused,
+		Message:            fmt.Sprintf("Reconciliation of %d managed resources is paused", held),
+	}
+}
+
+// ProviderConfigActive returns a condition indicating that the managed
+// resources of a ProviderConfig are reconciled.
+func ProviderConfigActive() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePaused,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonActive,
+	}
+}
+
+// A Reconciler pauses the managed resources of a paused ProviderConfig after
+// the wrapped reconciler has accounted for their usage. Managed resources are
+// paused with the crossplane.io/paused annotation, so that the managed
+// reconciler reports them as paused like any other paused resource.
+type Reconciler struct {
+	reconcile.Reconciler
+
+	kube client.Client
+	of   resource.ProviderConfigKinds
+}
+
+// NewReconciler returns a Reconciler of the supplied ProviderConfig kinds
+// that wraps the supplied reconciler.
+func NewReconciler(r reconcile.Reconciler, kube client.Client, of resource.ProviderConfigKinds) *Reconciler {
+	return &Reconciler{Reconciler: r, kube: kube, of: of}
+}
+
+// Reconcile accounts for the usage of a ProviderConfig, pauses or resumes the
+// managed resources that use it and then sets its Paused condition, which
+// counts the managed resources that are held.
+func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	res, err := r.Reconciler.Reconcile(ctx, req)
+	if err != nil {
+		return res, err
+	}
+	obj, err := r.kube.Scheme().New(r.of.Config)
+	if err != nil {
+		return res, errors.Wrap(err, errGetProviderConfig)
+	}
+	pc, ok := obj.(resource.ProviderConfig)
+	if !ok {
+		return res, errors.Errorf("%s: %s is not a ProviderConfig", errGetProviderConfig, r.of.Config)
+	}
+	if err := r.kube.Get(ctx, req.NamespacedName, pc); err != nil {
+		return res, errors.Wrap(client.IgnoreNotFound(err), errGetProviderConfig)
+	}
+	paused := isPaused(pc)
+	if err := r.pauseManaged(ctx, pc, paused); err != nil {
+		return res, err
+	}
+	c := ProviderConfigActive()
+	if paused {
+		c = ProviderConfigPaused(pc.GetUsers())
+	}
+	if pc.GetCondition(TypePaused).Equal(c) {
+		return res, nil
+	}
+	pc.SetConditions(c)
+	return res, errors.Wrap(r.kube.Status().Update(ctx, pc), errUpdatePausedCondition)
+}
+
+// isPaused reports whether the spec.paused field of a ProviderConfig is set.
+func isPaused(pc resource.ProviderConfig) bool {
+	p, err := fieldpath.PaveObject(pc)
+	if err != nil {
+		return false
+	}
+	paused, err := p.GetBool("spec.paused")
+	return err == nil && paused
+}
+
+// pauseManaged pauses or resumes the managed resources that use the supplied
+// ProviderConfig, according to its usages. Resources that were paused by
+// other means are left alone.
+func (r *Reconciler) pauseManaged(ctx context.Context, pc resource.ProviderConfig, paused bool) error {
+	obj, err := r.kube.Scheme().New(r.of.UsageList)
+	if err != nil {
+		return errors.Wrap(err, errListUsages)
+	}
+	l, ok := obj.(resource.ProviderConfigUsageList)
+	if !ok {
+		return errors.Errorf("%s: %s is not a ProviderConfigUsage list", errListUsages, r.of.UsageList)
+	}
+	opts := []client.ListOption{client.MatchingLabels{xpv1.LabelKeyProviderName: pc.GetName()}}
+	if pc.GetNamespace() != "" {
+		// Namespaced managed resources use the ProviderConfigs of their own
+		// namespace.
+		opts = []client.ListOption{client.InNamespace(pc.GetNamespace()), client.MatchingLabels{
+			xpv1.LabelKeyProviderName: pc.GetName(),
+			xpv1.LabelKeyProviderKind: r.of.Config.Kind,
+		}}
+	}
+	if err := r.kube.List(ctx, l, opts...); err != nil {
+		return errors.Wrap(err, errListUsages)
+	}
+	for _, pcu := range l.GetItems() {
+		ref := pcu.GetResourceReference()
+		mg := &unstructured.Unstructured{}
+		mg.SetGroupVersionKind(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind))
+		if err := r.kube.Get(ctx, client.ObjectKey{Namespace: pcu.GetNamespace(), Name: ref.Name}, mg); err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+			return errors.Wrapf(err, "%s %s %s", errPauseManaged, ref.Kind, ref.Name)
+		}
+		if err := r.setPaused(ctx, mg, paused); err != nil {
+			return errors.Wrapf(err, "%s %s %s", errPauseManaged, ref.Kind, ref.Name)
+		}
+	}
+	return nil
+}
+
+// setPaused adds or removes the pause of a single managed resource, patching
+// only its annotations.
+func (r *Reconciler) setPaused(ctx context.Context, mg *unstructured.Unstructured, paused bool) error {
+	a := mg.GetAnnotations()
+	orig := mg.DeepCopy()
+	switch {
+	case paused && a[meta.AnnotationKeyReconciliationPaused] != "true":
+		meta.AddAnnotations(mg, map[string]string{
+			meta.AnnotationKeyReconciliationPaused: "true",
+			AnnotationKeyPausedByProviderConfig:    "true",
+		})
+	case !paused && a[AnnotationKeyPausedByProviderConfig] == "true":
+		meta.RemoveAnnotations(mg, meta.AnnotationKeyReconciliationPaused, AnnotationKeyPausedByProviderConfig)
+	default:
+		return nil
+	}
+	return r.kube.Patch(ctx, mg, client.MergeFrom(orig))
+}
@@ -0,0 +1,135 @@
+package paused
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	kmeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/prolixalias/provider-cloudflare/apis/namespaced/v1beta1"
+)
+
+var recordGVK = schema.GroupVersionKind{Group: "dns.cloudflare.m.upbound.io", Version: "v1alpha1", Kind: "Record"}
+
+var of = resource.ProviderConfigKinds{
+	Config:    v1beta1.ProviderConfigGroupVersionKind,
+	Usage:     v1beta1.ProviderConfigUsageGroupVersionKind,
+	UsageList: v1beta1.ProviderConfigUsageListGroupVersionKind,
+}
+
+func record(name string, annotations map[string]string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(recordGVK)
+	u.SetNamespace("team")
+	u.SetName(name)
+	u.SetAnnotations(annotations)
+	return u
+}
+
+func usage(name string) *v1beta1.ProviderConfigUsage {
+	return &v1beta1.ProviderConfigUsage{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "team",
+			Name:      name,
+			Labels: map[string]string{
+				xpv1.LabelKeyProviderName: "default",
+				xpv1.LabelKeyProviderKind: of.Config.Kind,
+			},
+		},
+		ProviderConfigUsage: xpv1.ProviderConfigUsage{
+			ProviderConfigReference: xpv1.Reference{Name: "default"},
+			ResourceReference:       xpv1.TypedReference{APIVersion: recordGVK.GroupVersion().String(), Kind: recordGVK.Kind, Name: name},
+		},
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	paused := map[string]string{meta.AnnotationKeyReconciliationPaused: "true", AnnotationKeyPausedByProviderConfig: "true"}
+	pausedByUser := map[string]string{meta.AnnotationKeyReconciliationPaused: "true"}
+	cases := map[string]struct {
+		paused          bool
+		annotations     map[string]string
+		wantAnnotations map[string]string
+		wantStatus      corev1.ConditionStatus
+	}{
+		"Pause": {
+			paused:          true,
+			wantAnnotations: paused,
+			wantStatus:      corev1.ConditionTrue,
+		},
+		"AlreadyPausedByUser": {
+			paused:          true,
+			annotations:     pausedByUser,
+			wantAnnotations: pausedByUser,
+			wantStatus:      corev1.ConditionTrue,
+		},
+		"Resume": {
+			annotations: paused,
+			wantStatus:  corev1.ConditionFalse,
+		},
+		"PausedByUserStaysPaused": {
+			annotations:     pausedByUser,
+			wantAnnotations: pausedByUser,
+			wantStatus:      corev1.ConditionFalse,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := runtime.NewScheme()
+			if err := v1beta1.SchemeBuilder.AddToScheme(s); err != nil {
+				t.Fatalf("cannot build scheme: %v", err)
+			}
+			mapper := kmeta.NewDefaultRESTMapper(nil)
+			mapper.Add(recordGVK, kmeta.RESTScopeNamespace)
+			mapper.Add(of.Config, kmeta.RESTScopeNamespace)
+			mapper.Add(of.Usage, kmeta.RESTScopeNamespace)
+			pc := &v1beta1.ProviderConfig{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "default"},
+				Spec:       v1beta1.ProviderConfigSpec{Paused: tc.paused},
+				Status:     v1beta1.ProviderConfigStatus{ProviderConfigStatus: xpv1.ProviderConfigStatus{Users: 1}},
+			}
+			other := usage("other")
+			other.Namespace = "elsewhere"
+			kube := fake.NewClientBuilder().WithScheme(s).WithRESTMapper(mapper).
+				WithStatusSubresource(pc).
+				WithObjects(pc, usage("www"), other, record("www", tc.annotations)).
+				Build()
+
+			r := NewReconciler(reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+				return reconcile.Result{}, nil
+			}), kube, of)
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "team", Name: "default"}}
+			if _, err := r.Reconcile(context.Background(), req); err != nil {
+				t.Fatalf("Reconcile(...): unexpected error: %v", err)
+			}
+
+			got := record("www", nil)
+			if err := kube.Get(context.Background(), client.ObjectKeyFromObject(got), got); err != nil {
+				t.Fatalf("cannot get record: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantAnnotations, got.GetAnnotations(), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Reconcile(...): annotations: -want, +got:\n%s", diff)
+			}
+			if err := kube.Get(context.Background(), req.NamespacedName, pc); err != nil {
+				t.Fatalf("cannot get ProviderConfig: %v", err)
+			}
+			if c := pc.GetCondition(TypePaused); c.Status != tc.wantStatus {
+				t.Errorf("Reconcile(...): want %s condition %s, got %s", TypePaused, tc.wantStatus, c.Status)
+			}
+		})
+	}
+}
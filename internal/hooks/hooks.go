@@ -0,0 +1,163 @@
+// Package hooks connects the resource configuration to the parts of the
+// provider that run when a managed resource is reconciled: initializers,
+// Terraform configuration injectors, connection details and external name
+// lookups. The configuration refers to the functions of this package, which
+// call the implementations the provider registers when it starts, so that it
+// does not depend on the provider runtime.
+package hooks
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/crossplane/upjet/v2/pkg/config"
+	"github.com/pkg/errors"
+)
+
+// Hooks are the implementations of the hooks.
+type Hooks struct {
+	// AccountIDInitializer defaults the account ID of account-level
+	// resources to the account of their ProviderConfig.
+	AccountIDInitializer config.NewInitializerFn
+
+	// DNSRecordAdoptionInitializer adopts existing DNS records.
+	DNSRecordAdoptionInitializer config.NewInitializerFn
+
+	// TunnelConfigInitializer sets the external name of tunnel
+	// configurations.
+	TunnelConfigInitializer config.NewInitializerFn
+
+	// ZoneIDByName looks up the ID of a zone by its domain name.
+	ZoneIDByName func(ctx context.Context, providerConfig map[string]any, name, accountID string) (string, error)
+
+	// InjectContentSources injects the content of Workers resources read
+	// from their contentFrom sources.
+	InjectContentSources config.ConfigurationInjector
+
+	// InjectKVValue injects the value of a Workers KV value read from its
+	// valueFrom source.
+	InjectKVValue config.ConfigurationInjector
+
+	// APITokenConnectionDetails publishes API tokens as credentials.
+	APITokenConnectionDetails config.AdditionalConnectionDetailsFn
+
+	// AccessServiceTokenConnectionDetails publishes the client ID and
+	// secret of Access service tokens.
+	AccessServiceTokenConnectionDetails config.AdditionalConnectionDetailsFn
+
+	// TunnelConnectionDetails publishes what cloudflared runs a tunnel
+	// with.
+	TunnelConnectionDetails config.AdditionalConnectionDetailsFn
+}
+
+var registered atomic.Pointer[Hooks]
+
+// Register registers the implementations of the hooks. It must be called
+// before the controllers are set up.
+func Register(h Hooks) {
+	registered.Store(&h)
+}
+
+// get returns the registered implementation of a hook, or an error if there
+// is none.
+func get[F any](name string, fn func(h *Hooks) F) (F, error) {
+	var zero F
+	h := registered.Load()
+	if h == nil {
+		return zero, errors.Errorf("hook %s is not registered", name)
+	}
+	f := fn(h)
+	if reflect.ValueOf(f).IsNil() {
+		return zero, errors.Errorf("hook %s is not registered", name)
+	}
+	return f, nil
+}
+
+// initializer returns a constructor of initializers that calls the
+// registered one. Initializers are constructed when the controllers are set
+// up, after the hooks have been registered.
+func initializer[F ~func(A) R, A, R any](name string, fn func(h *Hooks) F) F {
+	return func(a A) R {
+		f, err := get(name, fn)
+		if err != nil {
+			panic(err)
+		}
+		return f(a)
+	}
+}
+
+// AccountIDInitializer calls the registered AccountIDInitializer.
+var AccountIDInitializer = initializer("AccountIDInitializer", func(h *Hooks) config.NewInitializerFn {
+	return h.AccountIDInitializer
+})
+
+// DNSRecordAdoptionInitializer calls the registered
+// DNSRecordAdoptionInitializer.
+var DNSRecordAdoptionInitializer = initializer("DNSRecordAdoptionInitializer", func(h *Hooks) config.NewInitializerFn {
+	return h.DNSRecordAdoptionInitializer
+})
+
+// TunnelConfigInitializer calls the registered TunnelConfigInitializer.
+var TunnelConfigInitializer = initializer("TunnelConfigInitializer", func(h *Hooks) config.NewInitializerFn {
+	return h.TunnelConfigInitializer
+})
+
+// ZoneIDByName calls the registered ZoneIDByName.
+func ZoneIDByName(ctx context.Context, providerConfig map[string]any, name, accountID string) (string, error) {
+	f, err := get("ZoneIDByName", func(h *Hooks) func(context.Context, map[string]any, string, string) (string, error) {
+		return h.ZoneIDByName
+	})
+	if err != nil {
+		return "", err
+	}
+	return f(ctx, providerConfig, name, accountID)
+}
+
+// InjectContentSources calls the registered InjectContentSources.
+func InjectContentSources(jsonMap map[string]any, tfMap map[string]any) error {
+	f, err := get("InjectContentSources", func(h *Hooks) config.ConfigurationInjector { return h.InjectContentSources })
+	if err != nil {
+		return err
+	}
+	return f(jsonMap, tfMap)
+}
+
+// InjectKVValue calls the registered InjectKVValue.
+func InjectKVValue(jsonMap map[string]any, tfMap map[string]any) error {
+	f, err := get("InjectKVValue", func(h *Hooks) config.ConfigurationInjector { return h.InjectKVValue })
+	if err != nil {
+		return err
+	}
+	return f(jsonMap, tfMap)
+}
+
+// APITokenConnectionDetails calls the registered APITokenConnectionDetails.
+func APITokenConnectionDetails(attr map[string]any) (map[string][]byte, error) {
+	return connectionDetails("APITokenConnectionDetails", attr, func(h *Hooks) config.AdditionalConnectionDetailsFn {
+		return h.APITokenConnectionDetails
+	})
+}
+
+// AccessServiceTokenConnectionDetails calls the registered
+// AccessServiceTokenConnectionDetails.
+func AccessServiceTokenConnectionDetails(attr map[string]any) (map[string][]byte, error) {
+	return connectionDetails("AccessServiceTokenConnectionDetails", attr, func(h *Hooks) config.AdditionalConnectionDetailsFn {
+		return h.AccessServiceTokenConnectionDetails
+	})
+}
+
+// TunnelConnectionDetails calls the registered TunnelConnectionDetails.
+func TunnelConnectionDetails(attr map[string]any) (map[string][]byte, error) {
+	return connectionDetails("TunnelConnectionDetails", attr, func(h *Hooks) config.AdditionalConnectionDetailsFn {
+		return h.TunnelConnectionDetails
+	})
+}
+
+func connectionDetails(name string, attr map[string]any, fn func(h *Hooks) config.AdditionalConnectionDetailsFn) (map[string][]byte, error) {
+	f, err := get(name, fn)
+	if err != nil {
+		return nil, err
+	}
+	return f(attr)
+}
@@ -0,0 +1,29 @@
+package hooks
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHooks(t *testing.T) {
+	t.Cleanup(func() { registered.Store(nil) })
+
+	if _, err := TunnelConnectionDetails(nil); err == nil {
+		t.Error("TunnelConnectionDetails(...): want error before hooks are registered, got none")
+	}
+
+	Register(Hooks{TunnelConnectionDetails: func(attr map[string]any) (map[string][]byte, error) {
+		return map[string][]byte{"tunnel_id": []byte(attr["id"].(string))}, nil
+	}})
+	got, err := TunnelConnectionDetails(map[string]any{"id": "t"})
+	if err != nil {
+		t.Fatalf("TunnelConnectionDetails(...): unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(map[string][]byte{"tunnel_id": []byte("t")}, got); diff != "" {
+		t.Errorf("TunnelConnectionDetails(...): -want, +got:\n%s", diff)
+	}
+	if _, err := APITokenConnectionDetails(nil); err == nil {
+		t.Error("APITokenConnectionDetails(...): want error for a hook that is not registered, got none")
+	}
+}
@@ -0,0 +1,282 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// ttlAutomatic is the TTL value Cloudflare treats as automatic.
+	ttlAutomatic = 1
+	minTTL       = 30
+	maxTTL       = 86400
+
+	maxTXTContentLength = 2048
+	maxTXTStringLength  = 255
+)
+
+// proxiableTypes are the record types Cloudflare can proxy.
+var proxiableTypes = map[string]bool{"A": true, "AAAA": true, "CNAME": true}
+
+// caaTags are the property tags Cloudflare accepts on CAA records.
+var caaTags = map[string]bool{"issue": true, "issuewild": true, "iodef": true}
+
+// dnsRecordParameters are the Record parameters the validator checks. Field
+// names match the generated Record CRDs of both scopes.
+type dnsRecordParameters struct {
+	Type     *string        `json:"type,omitempty"`
+	Content  *string        `json:"content,omitempty"`
+	TTL      *float64       `json:"ttl,omitempty"`
+	Proxied  *bool          `json:"proxied,omitempty"`
+	Priority *float64       `json:"priority,omitempty"`
+	Data     *dnsRecordData `json:"data,omitempty"`
+}
+
+type dnsRecordData struct {
+	Flags    json.RawMessage `json:"flags,omitempty"`
+	Port     *float64        `json:"port,omitempty"`
+	Priority *float64        `json:"priority,omitempty"`
+	Tag      *string         `json:"tag,omitempty"`
+	Target   *string         `json:"target,omitempty"`
+	Value    *string         `json:"value,omitempty"`
+	Weight   *float64        `json:"weight,omitempty"`
+}
+
+type dnsRecord struct {
+	Spec struct {
+		ForProvider  dnsRecordParameters `json:"forProvider"`
+		InitProvider dnsRecordParameters `json:"initProvider"`
+	} `json:"spec"`
+}
+
+// parameters returns the effective parameters of the record. Like the
+// managed reconciler, it falls back to initProvider for every parameter that
+// is not set in forProvider.
+func (r *dnsRecord) parameters() dnsRecordParameters {
+	p := r.Spec.ForProvider
+	i := r.Spec.InitProvider
+	if p.Type == nil {
+		p.Type = i.Type
+	}
+	if p.Content == nil {
+		p.Content = i.Content
+	}
+	if p.TTL == nil {
+		p.TTL = i.TTL
+	}
+	if p.Proxied == nil {
+		p.Proxied = i.Proxied
+	}
+	if p.Priority == nil {
+		p.Priority = i.Priority
+	}
+	if p.Data == nil {
+		p.Data = i.Data
+	}
+	return p
+}
+
+// dnsRecordValidator rejects DNS Records whose content, TTL or proxy setting
+// Cloudflare would refuse, so that mistakes surface on apply rather than after
+// a reconcile round-trip.
+type dnsRecordValidator struct{}
+
+// Handle validates a cluster scoped or namespaced DNS Record.
+func (v *dnsRecordValidator) Handle(_ context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+	r := &dnsRecord{}
+	if err := json.Unmarshal(req.Object.Raw, r); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	p := r.parameters()
+	if req.Operation == admissionv1.Update {
+		// Do not block updates of records that were accepted before, for
+		// example when their finalizer is removed, unless the parameters
+		// themselves change.
+		old := &dnsRecord{}
+		if err := json.Unmarshal(req.OldObject.Raw, old); err == nil && reflect.DeepEqual(old.parameters(), p) {
+			return admission.Allowed("")
+		}
+	}
+	problems, warnings := validateDNSRecord(p)
+	if len(problems) > 0 {
+		return admission.Denied(strings.Join(problems, "; ")).WithWarnings(warnings...)
+	}
+	return admission.Allowed("").WithWarnings(warnings...)
+}
+
+// validateDNSRecord returns the problems that make Cloudflare reject the
+// supplied record, and warnings about settings it silently adjusts.
+func validateDNSRecord(p dnsRecordParameters) (problems, warnings []string) {
+	if p.Type == nil {
+		return nil, nil
+	}
+	t := strings.ToUpper(*p.Type)
+	proxied := p.Proxied != nil && *p.Proxied
+
+	if p.TTL != nil {
+		ttl := *p.TTL
+		switch {
+		case ttl != float64(int64(ttl)):
+			problems = append(problems, fmt.Sprintf("ttl %v must be a whole number of seconds", ttl))
+		case ttl != ttlAutomatic && (ttl < minTTL || ttl > maxTTL):
+			problems = append(problems, fmt.Sprintf("ttl %v must be %d (automatic) or between %d and %d", ttl, ttlAutomatic, minTTL, maxTTL))
+		case proxied && ttl != ttlAutomatic:
+			warnings = append(warnings, fmt.Sprintf("ttl %v is ignored for proxied records, Cloudflare uses automatic", ttl))
+		}
+	}
+	if proxied && !proxiableTypes[t] {
+		problems = append(problems, fmt.Sprintf("%s records cannot be proxied", t))
+	}
+
+	content := ""
+	if p.Content != nil {
+		content = *p.Content
+	}
+	switch t {
+	case "A":
+		if ip := net.ParseIP(content); ip == nil || ip.To4() == nil {
+			problems = append(problems, fmt.Sprintf("content %q of an A record must be an IPv4 address", content))
+		}
+	case "AAAA":
+		if ip := net.ParseIP(content); ip == nil || ip.To4() != nil {
+			problems = append(problems, fmt.Sprintf("content %q of an AAAA record must be an IPv6 address", content))
+		}
+	case "CNAME", "NS", "PTR":
+		problems = append(problems, validateHostname(t, "content", content)...)
+	case "MX":
+		problems = append(problems, validateHostname(t, "content", content)...)
+		if p.Priority == nil {
+			problems = append(problems, "MX records require a priority")
+		} else {
+			problems = append(problems, validateUint16(t, "priority", *p.Priority)...)
+		}
+	case "TXT":
+		problems = append(problems, validateTXT(content)...)
+	case "SRV":
+		problems = append(problems, validateSRV(p.Data)...)
+	case "CAA":
+		problems = append(problems, validateCAA(p.Data)...)
+	}
+	return problems, warnings
+}
+
+// validateHostname checks that s is a DNS hostname rather than an address or
+// a URL.
+func validateHostname(recordType, field, s string) []string {
+	switch {
+	case s == "":
+		return []string{fmt.Sprintf("%s records require %s", recordType, field)}
+	case strings.Contains(s, "://") || strings.ContainsAny(s, "/?#"):
+		return []string{fmt.Sprintf("%s %q of a %s record must be a hostname, not a URL", field, s, recordType)}
+	case net.ParseIP(s) != nil:
+		return []string{fmt.Sprintf("%s %q of a %s record must be a hostname, not an IP address", field, s, recordType)}
+	case strings.Contains(s, ":"):
+		return []string{fmt.Sprintf("%s %q of a %s record must be a hostname without a port", field, s, recordType)}
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(s, "."), ".") {
+		if label == "" || len(label) > 63 {
+			return []string{fmt.Sprintf("%s %q of a %s record is not a valid hostname", field, s, recordType)}
+		}
+	}
+	return nil
+}
+
+func validateUint16(recordType, field string, v float64) []string {
+	if v < 0 || v > 65535 || v != float64(int64(v)) {
+		return []string{fmt.Sprintf("%s %v of a %s record must be a whole number between 0 and 65535", field, v, recordType)}
+	}
+	return nil
+}
+
+// validateTXT checks the total length of TXT content and the length of each
+// of its quoted character strings.
+func validateTXT(content string) []string {
+	if len(content) > maxTXTContentLength {
+		return []string{fmt.Sprintf("content of a TXT record is %d characters long, the maximum is %d", len(content), maxTXTContentLength)}
+	}
+	if !strings.HasPrefix(content, `"`) {
+		return nil
+	}
+	var problems []string
+	for i, s := range strings.Split(strings.Trim(content, `"`), `" "`) {
+		if len(s) > maxTXTStringLength {
+			problems = append(problems, fmt.Sprintf("string %d of a TXT record is %d characters long, the maximum is %d", i+1, len(s), maxTXTStringLength))
+		}
+	}
+	return problems
+}
+
+func validateSRV(d *dnsRecordData) []string {
+	if d == nil {
+		return []string{"SRV records require data with priority, weight, port and target"}
+	}
+	var problems []string
+	for field, v := range map[string]*float64{"data.priority": d.Priority, "data.weight": d.Weight, "data.port": d.Port} {
+		if v == nil {
+			problems = append(problems, fmt.Sprintf("SRV records require %s", field))
+			continue
+		}
+		problems = append(problems, validateUint16("SRV", field, *v)...)
+	}
+	target := ""
+	if d.Target != nil {
+		target = *d.Target
+	}
+	// A target of "." explicitly states that the service is not available.
+	if target != "." {
+		problems = append(problems, validateHostname("SRV", "data.target", target)...)
+	}
+	// Report problems in a stable order.
+	sort.Strings(problems)
+	return problems
+}
+
+func validateCAA(d *dnsRecordData) []string {
+	if d == nil {
+		return []string{"CAA records require data with flags, tag and value"}
+	}
+	var problems []string
+	if flags, ok := numericFlags(d.Flags); !ok {
+		problems = append(problems, "CAA records require numeric data.flags")
+	} else if flags < 0 || flags > 255 || flags != float64(int64(flags)) {
+		problems = append(problems, fmt.Sprintf("data.flags %v of a CAA record must be a whole number between 0 and 255", flags))
+	}
+	if d.Tag == nil || !caaTags[*d.Tag] {
+		problems = append(problems, "data.tag of a CAA record must be one of issue, issuewild or iodef")
+	}
+	if d.Value == nil || *d.Value == "" {
+		problems = append(problems, "CAA records require data.value")
+	}
+	return problems
+}
+
+// numericFlags returns the value of the dynamically typed data.flags
+// attribute, which may hold a number or a numeric string.
+func numericFlags(raw json.RawMessage) (float64, bool) {
+	if len(raw) == 0 {
+		return 0, false
+	}
+	var n float64
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n, true
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	return n, err == nil
+}
@@ -0,0 +1,218 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestValidateDNSRecord(t *testing.T) {
+	cases := map[string]struct {
+		p            dnsRecordParameters
+		wantProblems []string
+		wantWarnings []string
+	}{
+		"NoType": {
+			p: dnsRecordParameters{Content: ptr("not an address")},
+		},
+		"A": {
+			p: dnsRecordParameters{Type: ptr("A"), Content: ptr("192.0.2.1"), TTL: ptr(300.0)},
+		},
+		"LowerCaseType": {
+			p: dnsRecordParameters{Type: ptr("a"), Content: ptr("192.0.2.1")},
+		},
+		"AWithIPv6Address": {
+			p:            dnsRecordParameters{Type: ptr("A"), Content: ptr("2001:db8::1")},
+			wantProblems: []string{`content "2001:db8::1" of an A record must be an IPv4 address`},
+		},
+		"AAAA": {
+			p: dnsRecordParameters{Type: ptr("AAAA"), Content: ptr("2001:db8::1")},
+		},
+		"AAAAWithIPv4Address": {
+			p:            dnsRecordParameters{Type: ptr("AAAA"), Content: ptr("192.0.2.1")},
+			wantProblems: []string{`content "192.0.2.1" of an AAAA record must be an IPv6 address`},
+		},
+		"AutomaticTTL": {
+			p: dnsRecordParameters{Type: ptr("A"), Content: ptr("192.0.2.1"), TTL: ptr(1.0)},
+		},
+		"TTLTooLow": {
+			p:            dnsRecordParameters{Type: ptr("A"), Content: ptr("192.0.2.1"), TTL: ptr(10.0)},
+			wantProblems: []string{"ttl 10 must be 1 (automatic) or between 30 and 86400"},
+		},
+		"TTLTooHigh": {
+			p:            dnsRecordParameters{Type: ptr("A"), Content: ptr("192.0.2.1"), TTL: ptr(86401.0)},
+			wantProblems: []string{"ttl 86401 must be 1 (automatic) or between 30 and 86400"},
+		},
+		"FractionalTTL": {
+			p:            dnsRecordParameters{Type: ptr("A"), Content: ptr("192.0.2.1"), TTL: ptr(60.5)},
+			wantProblems: []string{"ttl 60.5 must be a whole number of seconds"},
+		},
+		"ProxiedWithTTL": {
+			p:            dnsRecordParameters{Type: ptr("CNAME"), Content: ptr("origin.example.com"), TTL: ptr(300.0), Proxied: ptr(true)},
+			wantWarnings: []string{"ttl 300 is ignored for proxied records, Cloudflare uses automatic"},
+		},
+		"ProxiedTXT": {
+			p:            dnsRecordParameters{Type: ptr("TXT"), Content: ptr("v=spf1 -all"), Proxied: ptr(true)},
+			wantProblems: []string{"TXT records cannot be proxied"},
+		},
+		"CNAMEWithURL": {
+			p:            dnsRecordParameters{Type: ptr("CNAME"), Content: ptr("https://origin.example.com")},
+			wantProblems: []string{`content "https://origin.example.com" of a CNAME record must be a hostname, not a URL`},
+		},
+		"CNAMEWithIPAddress": {
+			p:            dnsRecordParameters{Type: ptr("CNAME"), Content: ptr("192.0.2.1")},
+			wantProblems: []string{`content "192.0.2.1" of a CNAME record must be a hostname, not an IP address`},
+		},
+		"CNAMEWithPort": {
+			p:            dnsRecordParameters{Type: ptr("CNAME"), Content: ptr("origin.example.com:8080")},
+			wantProblems: []string{`content "origin.example.com:8080" of a CNAME record must be a hostname without a port`},
+		},
+		"NSWithEmptyLabel": {
+			p:            dnsRecordParameters{Type: ptr("NS"), Content: ptr("ns1..example.com")},
+			wantProblems: []string{`content "ns1..example.com" of a NS record is not a valid hostname`},
+		},
+		"PTRWithoutContent": {
+			p:            dnsRecordParameters{Type: ptr("PTR")},
+			wantProblems: []string{"PTR records require content"},
+		},
+		"MX": {
+			p: dnsRecordParameters{Type: ptr("MX"), Content: ptr("mail.example.com."), Priority: ptr(10.0)},
+		},
+		"MXWithoutPriority": {
+			p:            dnsRecordParameters{Type: ptr("MX"), Content: ptr("mail.example.com")},
+			wantProblems: []string{"MX records require a priority"},
+		},
+		"MXWithPriorityOutOfRange": {
+			p:            dnsRecordParameters{Type: ptr("MX"), Content: ptr("mail.example.com"), Priority: ptr(65536.0)},
+			wantProblems: []string{"priority 65536 of a MX record must be a whole number between 0 and 65535"},
+		},
+		"TXTTooLong": {
+			p:            dnsRecordParameters{Type: ptr("TXT"), Content: ptr(strings.Repeat("a", 2049))},
+			wantProblems: []string{"content of a TXT record is 2049 characters long, the maximum is 2048"},
+		},
+		"TXTStrings": {
+			p: dnsRecordParameters{Type: ptr("TXT"), Content: ptr(`"` + strings.Repeat("a", 255) + `" "` + strings.Repeat("b", 255) + `"`)},
+		},
+		"TXTStringTooLong": {
+			p:            dnsRecordParameters{Type: ptr("TXT"), Content: ptr(`"a" "` + strings.Repeat("b", 256) + `"`)},
+			wantProblems: []string{"string 2 of a TXT record is 256 characters long, the maximum is 255"},
+		},
+		"SRV": {
+			p: dnsRecordParameters{Type: ptr("SRV"), Data: &dnsRecordData{Priority: ptr(10.0), Weight: ptr(5.0), Port: ptr(5060.0), Target: ptr("sip.example.com")}},
+		},
+		"SRVServiceNotAvailable": {
+			p: dnsRecordParameters{Type: ptr("SRV"), Data: &dnsRecordData{Priority: ptr(0.0), Weight: ptr(0.0), Port: ptr(0.0), Target: ptr(".")}},
+		},
+		"SRVWithoutData": {
+			p:            dnsRecordParameters{Type: ptr("SRV")},
+			wantProblems: []string{"SRV records require data with priority, weight, port and target"},
+		},
+		"SRVWithIncompleteData": {
+			p: dnsRecordParameters{Type: ptr("SRV"), Data: &dnsRecordData{Priority: ptr(10.0), Port: ptr(70000.0)}},
+			wantProblems: []string{
+				"SRV records require data.target",
+				"SRV records require data.weight",
+				"data.port 70000 of a SRV record must be a whole number between 0 and 65535",
+			},
+		},
+		"CAA": {
+			p: dnsRecordParameters{Type: ptr("CAA"), Data: &dnsRecordData{Flags: json.RawMessage(`0`), Tag: ptr("issue"), Value: ptr("letsencrypt.org")}},
+		},
+		"CAAWithStringFlags": {
+			p: dnsRecordParameters{Type: ptr("CAA"), Data: &dnsRecordData{Flags: json.RawMessage(`"128"`), Tag: ptr("iodef"), Value: ptr("mailto:security@example.com")}},
+		},
+		"CAAWithoutData": {
+			p:            dnsRecordParameters{Type: ptr("CAA")},
+			wantProblems: []string{"CAA records require data with flags, tag and value"},
+		},
+		"CAAWithInvalidData": {
+			p: dnsRecordParameters{Type: ptr("CAA"), Data: &dnsRecordData{Flags: json.RawMessage(`256`), Tag: ptr("issuer")}},
+			wantProblems: []string{
+				"data.flags 256 of a CAA record must be a whole number between 0 and 255",
+				"data.tag of a CAA record must be one of issue, issuewild or iodef",
+				"CAA records require data.value",
+			},
+		},
+		"CAAWithoutFlags": {
+			p:            dnsRecordParameters{Type: ptr("CAA"), Data: &dnsRecordData{Flags: json.RawMessage(`"critical"`), Tag: ptr("issue"), Value: ptr("letsencrypt.org")}},
+			wantProblems: []string{"CAA records require numeric data.flags"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			problems, warnings := validateDNSRecord(tc.p)
+			if diff := cmp.Diff(tc.wantProblems, problems); diff != "" {
+				t.Errorf("validateDNSRecord(...): problems: -want, +got:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantWarnings, warnings); diff != "" {
+				t.Errorf("validateDNSRecord(...): warnings: -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDNSRecordValidatorHandle(t *testing.T) {
+	record := func(forProvider, initProvider string) runtime.RawExtension {
+		return runtime.RawExtension{Raw: []byte(`{"spec":{"forProvider":` + forProvider + `,"initProvider":` + initProvider + `}}`)}
+	}
+	invalid := record(`{"type":"A","content":"www.example.com"}`, `{}`)
+	cases := map[string]struct {
+		op        admissionv1.Operation
+		object    runtime.RawExtension
+		oldObject runtime.RawExtension
+		want      bool
+	}{
+		"CreateValid": {
+			op:     admissionv1.Create,
+			object: record(`{"type":"A","content":"192.0.2.1"}`, `{}`),
+			want:   true,
+		},
+		"CreateInvalid": {
+			op:     admissionv1.Create,
+			object: invalid,
+		},
+		"CreateInvalidInitProvider": {
+			op:     admissionv1.Create,
+			object: record(`{"type":"A"}`, `{"content":"www.example.com"}`),
+		},
+		"ForProviderTakesPrecedence": {
+			op:     admissionv1.Create,
+			object: record(`{"type":"A","content":"192.0.2.1"}`, `{"content":"www.example.com"}`),
+			want:   true,
+		},
+		"UpdateUnchangedParameters": {
+			op:        admissionv1.Update,
+			object:    invalid,
+			oldObject: invalid,
+			want:      true,
+		},
+		"UpdateChangedParameters": {
+			op:        admissionv1.Update,
+			object:    invalid,
+			oldObject: record(`{"type":"A","content":"192.0.2.1"}`, `{}`),
+		},
+		"Delete": {
+			op:   admissionv1.Delete,
+			want: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: tc.op, Object: tc.object, OldObject: tc.oldObject}}
+			resp := (&dnsRecordValidator{}).Handle(context.Background(), req)
+			if resp.Allowed != tc.want {
+				t.Errorf("Handle(...): want allowed %t, got %t: %v", tc.want, resp.Allowed, resp.Result)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+// Package webhooks contains the admission webhooks of the provider.
+package webhooks
+
+import (
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// Paths the admission webhooks are served at. They must match the webhook
+// configurations in the provider package.
+const (
+	ValidateDNSRecordPath = "/validate-dns-cloudflare-upbound-io-v1alpha1-record"
+)
+
+// Setup registers the admission webhooks with the webhook server of the
+// supplied manager.
+func Setup(mgr ctrl.Manager) error {
+	srv := mgr.GetWebhookServer()
+	srv.Register(ValidateDNSRecordPath, &webhook.Admission{Handler: &dnsRecordValidator{}})
+	return nil
+}
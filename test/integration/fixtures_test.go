@@ -0,0 +1,236 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1beta1 "github.com/prolixalias/provider-cloudflare/apis/cluster/v1beta1"
+	namespacedv1beta1 "github.com/prolixalias/provider-cloudflare/apis/namespaced/v1beta1"
+	"github.com/prolixalias/provider-cloudflare/internal/cfmock"
+)
+
+const (
+	credentialsKey = "credentials"
+
+	waitTimeout  = 2 * time.Minute
+	waitInterval = 250 * time.Millisecond
+)
+
+// A scope is the cluster-scoped or the namespaced flavour of the managed
+// resources and their ProviderConfigs.
+type scope struct {
+	name string
+
+	// group is the root API group of the managed resources.
+	group string
+
+	// namespace holds the managed resources, if they are namespaced.
+	namespace string
+
+	// providerConfigRef is the providerConfigRef of the managed resources.
+	providerConfigRef map[string]any
+
+	// usages are the ProviderConfigUsages of the scope.
+	usages schema.GroupVersionKind
+}
+
+// scopes creates a ProviderConfig for each scope, with credentials for the
+// Cloudflare API stand-in.
+func scopes(t *testing.T) []scope {
+	t.Helper()
+	ns := createNamespace(t)
+
+	clusterName := "cluster-" + ns
+	createCredentials(t, ns, clusterName)
+	createObject(t, &clusterv1beta1.ProviderConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: clusterName},
+		Spec: clusterv1beta1.ProviderConfigSpec{
+			Credentials: clusterv1beta1.ProviderCredentials{
+				Source: xpv1.CredentialsSourceSecret,
+				CommonCredentialSelectors: xpv1.CommonCredentialSelectors{
+					SecretRef: &xpv1.SecretKeySelector{
+						SecretReference: xpv1.SecretReference{Name: clusterName, Namespace: ns},
+						Key:             credentialsKey,
+					},
+				},
+			},
+		},
+	})
+
+	// Namespaced managed resources read the credentials from their own
+	// namespace.
+	namespacedName := "namespaced-" + ns
+	createCredentials(t, ns, namespacedName)
+	createObject(t, &namespacedv1beta1.ProviderConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: namespacedName},
+		Spec: namespacedv1beta1.ProviderConfigSpec{
+			Credentials: namespacedv1beta1.ProviderCredentials{
+				Source: xpv1.CredentialsSourceSecret,
+				CommonCredentialSelectors: xpv1.CommonCredentialSelectors{
+					SecretRef: &xpv1.SecretKeySelector{
+						SecretReference: xpv1.SecretReference{Name: namespacedName},
+						Key:             credentialsKey,
+					},
+				},
+			},
+		},
+	})
+
+	return []scope{
+		{
+			name:              "Cluster",
+			group:             "cloudflare.upbound.io",
+			providerConfigRef: map[string]any{"name": clusterName},
+			usages:            clusterv1beta1.SchemeGroupVersion.WithKind("ProviderConfigUsageList"),
+		},
+		{
+			name:              "Namespaced",
+			group:             "cloudflare.m.upbound.io",
+			namespace:         ns,
+			providerConfigRef: map[string]any{"kind": namespacedv1beta1.ProviderConfigKind, "name": namespacedName},
+			usages:            namespacedv1beta1.SchemeGroupVersion.WithKind("ProviderConfigUsageList"),
+		},
+	}
+}
+
+// createNamespace creates a namespace that is deleted when the test ends.
+func createNamespace(t *testing.T) string {
+	t.Helper()
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "integration-"}}
+	createObject(t, ns)
+	return ns.GetName()
+}
+
+// createCredentials creates a Secret with credentials for the Cloudflare API
+// stand-in.
+func createCredentials(t *testing.T, namespace, name string) {
+	t.Helper()
+	createObject(t, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		StringData: map[string]string{
+			credentialsKey: fmt.Sprintf(`{"api_token":%q,"base_url":%q}`, apiToken, cf.BaseURL()),
+		},
+	})
+}
+
+// createObject creates an object that is deleted when the test ends.
+func createObject(t *testing.T, obj client.Object) {
+	t.Helper()
+	if err := kube.Create(context.Background(), obj); err != nil {
+		t.Fatalf("cannot create %T %s: %v", obj, obj.GetName(), err)
+	}
+	t.Cleanup(func() {
+		if err := kube.Delete(context.Background(), obj); err != nil && !kerrors.IsNotFound(err) {
+			t.Errorf("cannot delete %T %s: %v", obj, obj.GetName(), err)
+		}
+	})
+}
+
+// seedZone creates a zone in the Cloudflare API stand-in and returns its ID.
+func seedZone(t *testing.T, name string) string {
+	t.Helper()
+	zone, err := cf.Seed("/zones", cfmock.Object{"name": name, "account": map[string]any{"id": accountID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zone["id"].(string)
+}
+
+// newManaged returns a managed resource of the supplied short group, version
+// and kind in the supplied scope.
+func (s scope) newManaged(shortGroup, version, kind, name string, forProvider map[string]any) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]any{
+		"spec": map[string]any{
+			"forProvider":       forProvider,
+			"providerConfigRef": s.providerConfigRef,
+		},
+	}}
+	u.SetGroupVersionKind(schema.GroupVersionKind{Group: shortGroup + "." + s.group, Version: version, Kind: kind})
+	u.SetNamespace(s.namespace)
+	u.SetName(name)
+	return u
+}
+
+// waitFor polls until the supplied condition holds, failing the test if it
+// does not hold in time.
+func waitFor(t *testing.T, what string, cond func(ctx context.Context) (bool, error)) {
+	t.Helper()
+	if err := wait.PollUntilContextTimeout(context.Background(), waitInterval, waitTimeout, true, cond); err != nil {
+		t.Fatalf("timed out waiting for %s: %v", what, err)
+	}
+}
+
+// waitForCondition waits until the managed resource has the supplied
+// condition status and returns its latest version.
+func waitForCondition(t *testing.T, mg *unstructured.Unstructured, ct xpv1.ConditionType, status corev1.ConditionStatus) *unstructured.Unstructured {
+	t.Helper()
+	cur := mg.DeepCopy()
+	waitFor(t, fmt.Sprintf("%s %s to be %s", mg.GetKind(), ct, status), func(ctx context.Context) (bool, error) {
+		if err := kube.Get(ctx, client.ObjectKeyFromObject(mg), cur); err != nil {
+			return false, err
+		}
+		c, ok := condition(cur, ct)
+		return ok && c.Status == status, nil
+	})
+	return cur
+}
+
+// waitForDeletion waits until the managed resource is gone.
+func waitForDeletion(t *testing.T, mg *unstructured.Unstructured) {
+	t.Helper()
+	waitFor(t, fmt.Sprintf("%s %s to be deleted", mg.GetKind(), mg.GetName()), func(ctx context.Context) (bool, error) {
+		err := kube.Get(ctx, client.ObjectKeyFromObject(mg), mg.DeepCopy())
+		if kerrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+// condition returns the condition of the supplied type of a managed resource.
+func condition(mg *unstructured.Unstructured, ct xpv1.ConditionType) (xpv1.Condition, bool) {
+	cs, _, _ := unstructured.NestedSlice(mg.Object, "status", "conditions")
+	for _, c := range cs {
+		m, ok := c.(map[string]any)
+		if !ok || m["type"] != string(ct) {
+			continue
+		}
+		msg, _ := m["message"].(string)
+		reason, _ := m["reason"].(string)
+		status, _ := m["status"].(string)
+		return xpv1.Condition{Type: ct, Status: corev1.ConditionStatus(status), Reason: xpv1.ConditionReason(reason), Message: msg}, true
+	}
+	return xpv1.Condition{}, false
+}
+
+// usedBy reports whether a ProviderConfigUsage of the scope tracks the
+// supplied managed resource.
+func (s scope) usedBy(ctx context.Context, mg *unstructured.Unstructured) (bool, error) {
+	l := &unstructured.UnstructuredList{}
+	l.SetGroupVersionKind(s.usages)
+	if err := kube.List(ctx, l); err != nil {
+		return false, err
+	}
+	for _, u := range l.Items {
+		name, _, _ := unstructured.NestedString(u.Object, "resourceRef", "name")
+		kind, _, _ := unstructured.NestedString(u.Object, "resourceRef", "kind")
+		pc, _, _ := unstructured.NestedString(u.Object, "providerConfigRef", "name")
+		if name == mg.GetName() && kind == mg.GetKind() && u.GetNamespace() == mg.GetNamespace() && pc == s.providerConfigRef["name"] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
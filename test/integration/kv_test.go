@@ -0,0 +1,64 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestKVNamespaceValuesFrom(t *testing.T) {
+	for _, s := range scopes(t) {
+		t.Run(s.name, func(t *testing.T) {
+			ns := s.namespace
+			if ns == "" {
+				ns = createNamespace(t)
+			}
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "flags"},
+				Data:       map[string]string{"beta": "on", "theme": "dark"},
+			}
+			createObject(t, cm)
+
+			mg := s.newManaged("workers", "v1alpha1", "KvNamespace", "flags", map[string]any{
+				"accountId": accountID,
+				"title":     strings.ToLower(s.name) + "-flags",
+				"valuesFrom": map[string]any{
+					"configMapRef": map[string]any{"namespace": ns, "name": cm.Name},
+				},
+			})
+			createObject(t, mg)
+			cur := waitForCondition(t, mg, xpv1.TypeReady, corev1.ConditionTrue)
+
+			value := func(key string) (string, bool) {
+				v, ok := cf.KVValue(accountID, meta.GetExternalName(cur), key)
+				return string(v), ok
+			}
+			waitFor(t, "the ConfigMap values to be synced", func(ctx context.Context) (bool, error) {
+				if err := kube.Get(ctx, client.ObjectKeyFromObject(mg), cur); err != nil {
+					return false, err
+				}
+				beta, _ := value("beta")
+				theme, _ := value("theme")
+				return beta == "on" && theme == "dark", nil
+			})
+
+			cm.Data = map[string]string{"theme": "light"}
+			if err := kube.Update(context.Background(), cm); err != nil {
+				t.Fatal(err)
+			}
+			waitFor(t, "the changed ConfigMap to be synced", func(context.Context) (bool, error) {
+				_, beta := value("beta")
+				theme, _ := value("theme")
+				return !beta && theme == "light", nil
+			})
+		})
+	}
+}
@@ -0,0 +1,159 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/prolixalias/provider-cloudflare/internal/cfmock"
+	"github.com/prolixalias/provider-cloudflare/internal/clients"
+)
+
+func TestRecordLifecycle(t *testing.T) {
+	for _, s := range scopes(t) {
+		t.Run(s.name, func(t *testing.T) {
+			zoneID := seedZone(t, strings.ToLower(s.name)+".lifecycle.example.com")
+			records := "/zones/" + zoneID + "/dns_records"
+			mg := s.newManaged("dns", "v1alpha1", "Record", "www", map[string]any{
+				"zoneId":  zoneID,
+				"name":    "www",
+				"type":    "A",
+				"content": "192.0.2.10",
+				"ttl":     1,
+			})
+			if err := kube.Create(context.Background(), mg); err != nil {
+				t.Fatalf("cannot create record: %v", err)
+			}
+
+			// Create and observe.
+			cur := waitForCondition(t, mg, xpv1.TypeReady, corev1.ConditionTrue)
+			waitForCondition(t, mg, xpv1.TypeSynced, corev1.ConditionTrue)
+			objs := cf.Objects(records)
+			if len(objs) != 1 {
+				t.Fatalf("want 1 record in Cloudflare, got %d", len(objs))
+			}
+			if got := meta.GetExternalName(cur); got != objs[0]["id"] {
+				t.Errorf("external name: want %v, got %q", objs[0]["id"], got)
+			}
+			if got, _, _ := unstructured.NestedString(cur.Object, "status", "atProvider", "content"); got != "192.0.2.10" {
+				t.Errorf("status.atProvider.content: want 192.0.2.10, got %q", got)
+			}
+			waitFor(t, "ProviderConfigUsage of the record", func(ctx context.Context) (bool, error) {
+				return s.usedBy(ctx, mg)
+			})
+
+			// Update.
+			if err := unstructured.SetNestedField(cur.Object, "192.0.2.20", "spec", "forProvider", "content"); err != nil {
+				t.Fatal(err)
+			}
+			if err := kube.Update(context.Background(), cur); err != nil {
+				t.Fatalf("cannot update record: %v", err)
+			}
+			waitFor(t, "the record content to be updated in Cloudflare", func(context.Context) (bool, error) {
+				objs := cf.Objects(records)
+				return len(objs) == 1 && objs[0]["content"] == "192.0.2.20", nil
+			})
+
+			// Delete.
+			if err := kube.Delete(context.Background(), cur); err != nil {
+				t.Fatalf("cannot delete record: %v", err)
+			}
+			waitForDeletion(t, mg)
+			if objs := cf.Objects(records); len(objs) != 0 {
+				t.Errorf("want no records in Cloudflare after deletion, got %d", len(objs))
+			}
+		})
+	}
+}
+
+func TestRecordCreateRejected(t *testing.T) {
+	for _, s := range scopes(t) {
+		t.Run(s.name, func(t *testing.T) {
+			zoneID := seedZone(t, strings.ToLower(s.name)+".rejected.example.com")
+			records := "/zones/" + zoneID + "/dns_records"
+			cf.InjectFault(cfmock.Fault{
+				Method: http.MethodPost,
+				Path:   records,
+				Status: http.StatusBadRequest,
+				Errors: []cfmock.Error{{Code: 9005, Message: "Content for A record is invalid."}},
+			})
+			t.Cleanup(cf.ClearFaults)
+
+			mg := s.newManaged("dns", "v1alpha1", "Record", "rejected", map[string]any{
+				"zoneId":  zoneID,
+				"name":    "rejected",
+				"type":    "A",
+				"content": "192.0.2.30",
+				"ttl":     1,
+			})
+			if err := kube.Create(context.Background(), mg); err != nil {
+				t.Fatalf("cannot create record: %v", err)
+			}
+			t.Cleanup(func() {
+				_ = kube.Delete(context.Background(), mg)
+			})
+
+			cur := waitForCondition(t, mg, xpv1.TypeSynced, corev1.ConditionFalse)
+			waitFor(t, "the Cloudflare error to be reported", func(ctx context.Context) (bool, error) {
+				if err := kube.Get(ctx, client.ObjectKeyFromObject(mg), cur); err != nil {
+					return false, err
+				}
+				c, _ := condition(cur, xpv1.TypeSynced)
+				return strings.Contains(c.Message, "error 9005") && strings.Contains(c.Message, "terminal"), nil
+			})
+			if objs := cf.Objects(records); len(objs) != 0 {
+				t.Errorf("want no records in Cloudflare, got %d", len(objs))
+			}
+		})
+	}
+}
+
+func TestRecordAdoption(t *testing.T) {
+	for _, s := range scopes(t) {
+		t.Run(s.name, func(t *testing.T) {
+			zoneID := seedZone(t, strings.ToLower(s.name)+".adoption.example.com")
+			records := "/zones/" + zoneID + "/dns_records"
+			// Two records of a multi-value set, told apart by content.
+			var existing cfmock.Object
+			for _, content := range []string{"192.0.2.40", "192.0.2.41"} {
+				obj, err := cf.Seed(records, cfmock.Object{"name": "adopted", "type": "A", "content": content, "ttl": 1})
+				if err != nil {
+					t.Fatal(err)
+				}
+				existing = obj
+			}
+
+			mg := s.newManaged("dns", "v1alpha1", "Record", "adopted", map[string]any{
+				"zoneId":  zoneID,
+				"name":    "adopted",
+				"type":    "A",
+				"content": "192.0.2.41",
+				"ttl":     1,
+			})
+			mg.SetAnnotations(map[string]string{clients.AnnotationKeyAdopt: "true"})
+			if err := kube.Create(context.Background(), mg); err != nil {
+				t.Fatalf("cannot create record: %v", err)
+			}
+			t.Cleanup(func() {
+				_ = kube.Delete(context.Background(), mg)
+			})
+
+			cur := waitForCondition(t, mg, xpv1.TypeReady, corev1.ConditionTrue)
+			if got := meta.GetExternalName(cur); got != existing["id"] {
+				t.Errorf("external name: want the adopted record %v, got %q", existing["id"], got)
+			}
+			if objs := cf.Objects(records); len(objs) != 2 {
+				t.Errorf("want the 2 existing records in Cloudflare, got %d", len(objs))
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+//go:build integration
+
+// Package integration runs the provider's controllers against a Kubernetes
+// API server started by envtest and the Cloudflare API stand-in of the
+// internal/cfmock package. Run it with make test.integration, which fetches
+// the envtest binaries, points KUBEBUILDER_ASSETS at them and generates the
+// CRDs the suite reads from package/crds if they are missing.
+package integration
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	xpcontroller "github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/feature"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/ratelimiter"
+	ujconfig "github.com/crossplane/upjet/v2/pkg/config"
+	tjcontroller "github.com/crossplane/upjet/v2/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	apisCluster "github.com/prolixalias/provider-cloudflare/apis/cluster"
+	apisNamespaced "github.com/prolixalias/provider-cloudflare/apis/namespaced"
+	"github.com/prolixalias/provider-cloudflare/config"
+	"github.com/prolixalias/provider-cloudflare/internal/cfmock"
+	"github.com/prolixalias/provider-cloudflare/internal/clients"
+	controllerCluster "github.com/prolixalias/provider-cloudflare/internal/controller/cluster"
+	controllerNamespaced "github.com/prolixalias/provider-cloudflare/internal/controller/namespaced"
+	"github.com/prolixalias/provider-cloudflare/internal/hooks"
+)
+
+const (
+	apiToken  = "integration-test"
+	accountID = "0123456789abcdef0123456789abcdef"
+
+	// pollInterval is short so that drift and updates are picked up
+	// quickly.
+	pollInterval = 2 * time.Second
+)
+
+var (
+	// kube reads and writes directly through the API server, bypassing the
+	// manager's cache.
+	kube client.Client
+
+	// cf is the Cloudflare API the controllers talk to.
+	cf *cfmock.Server
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		fmt.Println("KUBEBUILDER_ASSETS is not set, skipping integration tests. Run them with make test.integration.")
+		return 0
+	}
+
+	crds := filepath.Join("..", "..", "package", "crds")
+	if m, _ := filepath.Glob(filepath.Join(crds, "*.yaml")); len(m) == 0 {
+		fmt.Printf("No CRDs in %s, skipping integration tests. Generate them with make generate, or run the tests with make test.integration.\n", crds)
+		return 0
+	}
+
+	cf = cfmock.NewServer(cfmock.WithAPIToken(apiToken))
+	defer cf.Close()
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths:     []string{crds},
+		ErrorIfCRDPathMissing: true,
+	}
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot start envtest: %v\n", err)
+		return 1
+	}
+	defer func() {
+		if err := env.Stop(); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot stop envtest: %v\n", err)
+		}
+	}()
+
+	mgr, err := newManager(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot set up controllers: %v\n", err)
+		return 1
+	}
+	kube, err = client.New(cfg, client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot create client: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- mgr.Start(ctx)
+	}()
+	code := m.Run()
+	cancel()
+	if err := <-done; err != nil {
+		fmt.Fprintf(os.Stderr, "controller manager failed: %v\n", err)
+		return 1
+	}
+	return code
+}
+
+// newManager returns a controller manager running the cluster-scoped and
+// namespaced controllers the way cmd/provider does.
+func newManager(cfg *rest.Config) (manager.Manager, error) {
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := apisCluster.AddToScheme(mgr.GetScheme()); err != nil {
+		return nil, err
+	}
+	if err := apisNamespaced.AddToScheme(mgr.GetScheme()); err != nil {
+		return nil, err
+	}
+	if err := corev1.AddToScheme(mgr.GetScheme()); err != nil {
+		return nil, err
+	}
+
+	log := logging.NewNopLogger()
+	if testing.Verbose() {
+		log = logging.NewLogrLogger(zap.New(zap.UseDevMode(true), zap.WriteTo(os.Stderr)))
+	}
+	trackers := tjcontroller.NewOperationStore(log)
+	hooks.Register(clients.Hooks())
+	setupFn := clients.TerraformSetupBuilder(clients.WithOperationTrackerStore(trackers))
+	options := func(p *ujconfig.Provider) tjcontroller.Options {
+		return tjcontroller.Options{
+			Options: xpcontroller.Options{
+				Logger:                  log,
+				GlobalRateLimiter:       ratelimiter.NewGlobal(100),
+				PollInterval:            pollInterval,
+				MaxConcurrentReconciles: 5,
+				Features:                &feature.Flags{},
+			},
+			Provider:              p,
+			OperationTrackerStore: trackers,
+			SetupFn:               setupFn,
+		}
+	}
+	if err := controllerCluster.Setup(mgr, options(config.GetProvider())); err != nil {
+		return nil, err
+	}
+	if err := controllerNamespaced.Setup(mgr, options(config.GetProviderNamespaced())); err != nil {
+		return nil, err
+	}
+	return mgr, nil
+}
@@ -0,0 +1,108 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestTunnelConnectionDetails(t *testing.T) {
+	for _, s := range scopes(t) {
+		t.Run(s.name, func(t *testing.T) {
+			ns := s.namespace
+			if ns == "" {
+				ns = createNamespace(t)
+			}
+			mg := s.newManaged("zero", "v1alpha1", "TrustTunnelCloudflared", "connected", map[string]any{
+				"accountId": accountID,
+				"name":      strings.ToLower(s.name) + "-connected",
+			})
+			ref := map[string]any{"name": "connected-tunnel"}
+			if s.namespace == "" {
+				ref["namespace"] = ns
+			}
+			mg.Object["spec"].(map[string]any)["writeConnectionSecretToRef"] = ref
+			createObject(t, mg)
+			cur := waitForCondition(t, mg, xpv1.TypeReady, corev1.ConditionTrue)
+
+			sec := &corev1.Secret{}
+			waitFor(t, "the tunnel token to be published", func(ctx context.Context) (bool, error) {
+				if err := kube.Get(ctx, client.ObjectKeyFromObject(mg), cur); err != nil {
+					return false, err
+				}
+				err := kube.Get(ctx, client.ObjectKey{Namespace: ns, Name: "connected-tunnel"}, sec)
+				return err == nil && len(sec.Data["tunnel_token"]) > 0, client.IgnoreNotFound(err)
+			})
+
+			id := meta.GetExternalName(cur)
+			if got := string(sec.Data["tunnel_id"]); got != id {
+				t.Errorf("tunnel_id: want %s, got %q", id, got)
+			}
+			b, err := base64.StdEncoding.DecodeString(string(sec.Data["tunnel_token"]))
+			if err != nil {
+				t.Fatalf("cannot decode tunnel_token: %v", err)
+			}
+			token := struct{ A, T, S string }{}
+			if err := json.Unmarshal(b, &token); err != nil {
+				t.Fatalf("cannot decode tunnel_token: %v", err)
+			}
+			creds := struct{ AccountTag, TunnelSecret, TunnelID string }{}
+			if err := json.Unmarshal(sec.Data["credentials.json"], &creds); err != nil {
+				t.Fatalf("cannot decode credentials.json: %v", err)
+			}
+			if token.A != accountID || token.T != id || token.S == "" {
+				t.Errorf("tunnel_token: want account %s, tunnel %s and a secret, got %+v", accountID, id, token)
+			}
+			if creds.AccountTag != token.A || creds.TunnelID != token.T || creds.TunnelSecret != token.S {
+				t.Errorf("credentials.json: want %+v, got %+v", token, creds)
+			}
+		})
+	}
+}
+
+func TestTunnelConfigReference(t *testing.T) {
+	for _, s := range scopes(t) {
+		t.Run(s.name, func(t *testing.T) {
+			tunnel := s.newManaged("zero", "v1alpha1", "TrustTunnelCloudflared", "configured", map[string]any{
+				"accountId": accountID,
+				"name":      strings.ToLower(s.name) + "-configured",
+				"configSrc": "cloudflare",
+			})
+			createObject(t, tunnel)
+
+			mg := s.newManaged("zero", "v1alpha1", "TrustTunnelCloudflaredConfig", "configured", map[string]any{
+				"accountId":   accountID,
+				"tunnelIdRef": map[string]any{"name": tunnel.GetName()},
+				"config": map[string]any{
+					"ingress": []any{
+						map[string]any{"hostname": "app.example.com", "service": "http://app.default.svc:8080"},
+						map[string]any{"service": "http_status:404"},
+					},
+				},
+			})
+			createObject(t, mg)
+
+			cur := waitForCondition(t, mg, xpv1.TypeReady, corev1.ConditionTrue)
+			tunnelID := meta.GetExternalName(waitForCondition(t, tunnel, xpv1.TypeReady, corev1.ConditionTrue))
+			if got, _, _ := unstructured.NestedString(cur.Object, "spec", "forProvider", "tunnelId"); got != tunnelID {
+				t.Errorf("spec.forProvider.tunnelId: want the referenced tunnel %s, got %q", tunnelID, got)
+			}
+			if got := meta.GetExternalName(cur); got != tunnelID {
+				t.Errorf("external name: want the tunnel ID %s, got %q", tunnelID, got)
+			}
+			if got, _, _ := unstructured.NestedString(cur.Object, "spec", "forProvider", "source"); got != "cloudflare" {
+				t.Errorf("spec.forProvider.source: want it late-initialized to cloudflare, got %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestZoneObserveByDomainName(t *testing.T) {
+	for _, s := range scopes(t) {
+		t.Run(s.name, func(t *testing.T) {
+			domain := strings.ToLower(s.name) + ".observed.example.com"
+			zoneID := seedZone(t, domain)
+
+			mg := s.newManaged("cloudflare", "v1alpha1", "Zone", "observed", map[string]any{})
+			meta.SetExternalName(mg, domain)
+			if err := unstructured.SetNestedStringSlice(mg.Object, []string{string(xpv1.ManagementActionObserve)}, "spec", "managementPolicies"); err != nil {
+				t.Fatal(err)
+			}
+			createObject(t, mg)
+
+			cur := waitForCondition(t, mg, xpv1.TypeReady, corev1.ConditionTrue)
+			waitFor(t, "the external name to be set to the zone ID", func(ctx context.Context) (bool, error) {
+				if err := kube.Get(ctx, client.ObjectKeyFromObject(mg), cur); err != nil {
+					return false, err
+				}
+				return meta.GetExternalName(cur) == zoneID, nil
+			})
+			if got, _, _ := unstructured.NestedString(cur.Object, "status", "atProvider", "name"); got != domain {
+				t.Errorf("status.atProvider.name: want %s, got %q", domain, got)
+			}
+			if _, ok := cf.Object("/zones/" + zoneID); !ok {
+				t.Error("want the observed zone to remain in Cloudflare")
+			}
+		})
+	}
+}